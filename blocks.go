@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/auth"
+	"github.com/CraigYanitski/mescli/internal/database"
+	"github.com/google/uuid"
+)
+
+type InitBlock struct {
+    UserID  uuid.UUID  `json:"user_id"`
+}
+type Block struct {
+    UserID     uuid.UUID  `json:"user_id"`
+    BlockedID  uuid.UUID  `json:"blocked_id"`
+    CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (cfg *apiConfig) handleCreateBlock(w http.ResponseWriter, r *http.Request) {
+    // check authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "unauthorised", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+
+    // unmarshal POST JSON
+    decoder := json.NewDecoder(r.Body)
+    b := &InitBlock{}
+    err = decoder.Decode(b)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error decoding request", err)
+        return
+    }
+    if b.UserID == uuid.Nil {
+        respondWithError(w, http.StatusBadRequest, "need user ID to block", nil)
+        return
+    } else if b.UserID == id {
+        respondWithError(w, http.StatusBadRequest, "cannot block yourself", nil)
+        return
+    }
+
+    block, err := cfg.dbQueries.CreateBlock(
+        r.Context(), 
+        database.CreateBlockParams{UserID: id, BlockedID: b.UserID},
+    )
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error adding to blocks database", err)
+        return
+    }
+
+    // drop anything already queued from the blocked user
+    err = cfg.dbQueries.DeleteMessagesFromSender(
+        r.Context(), 
+        database.DeleteMessagesFromSenderParams{UserID: id, SenderID: b.UserID},
+    )
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error deleting messages from blocked user", err)
+        return
+    }
+
+    respondWithJSON(w, http.StatusCreated, Block(block))
+}
+
+func (cfg *apiConfig) handleDeleteBlock(w http.ResponseWriter, r *http.Request) {
+    // check authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "unauthorised", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+
+    // get blocked user ID from request
+    userID, err := uuid.Parse(r.PathValue("userID"))
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "unable to parse user ID", err)
+        return
+    }
+
+    err = cfg.dbQueries.DeleteBlock(
+        r.Context(), 
+        database.DeleteBlockParams{UserID: id, BlockedID: userID},
+    )
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error deleting from blocks database", err)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handleGetBlocks(w http.ResponseWriter, r *http.Request) {
+    // check authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "unauthorised", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+
+    blocks, err := cfg.dbQueries.GetBlocks(r.Context(), id)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting blocks from database", err)
+        return
+    }
+
+    blockList := []Block{}
+    for _, block := range blocks {
+        blockList = append(blockList, Block(block))
+    }
+
+    respondWithJSON(w, http.StatusOK, blockList)
+}
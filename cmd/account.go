@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var name string
+var passwordStdin bool
+var yes bool
+
+var accountCmd = &cobra.Command{
+    Use:   "account [CMD]",
+    Short: "Manage your mescli account",
+    Long:  `Manage your mescli account.
+
+    This should be used with the commands "create", "update", "show" and "delete".
+    These are the non-interactive equivalents of the TUI account screens.`,
+}
+
+var accountCreateCmd = &cobra.Command{
+    Use:   "create",
+    Short: "Create a new account",
+    Long:  `Create a new account on the server.
+
+    Any details not given as flags are prompted for. Use --password-stdin
+    to read the password from stdin when scripting.
+    New identity keys are generated if none exist yet.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        var err error
+        if email == "" {
+            email, err = promptLine("Email: ")
+            if err != nil {
+                return err
+            }
+        }
+        if err = utils.ValidateEmail(email); err != nil {
+            return err
+        }
+        if err = readNewPassword(); err != nil {
+            return err
+        }
+        err = requests.CreateAccount(name, email, password)
+        if err != nil {
+            return fmt.Errorf("account creation failed: %s", err)
+        }
+        status(utils.SuccessStyle.Render("Created account " + email))
+        return nil
+    },
+}
+
+var accountUpdateCmd = &cobra.Command{
+    Use:   "update",
+    Short: "Update your account details",
+    Long:  `Update your account details on the server.
+
+    Only the details given as flags are changed. The server stores the
+    password again on every update, so it is prompted for if --password
+    is not given.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        var err error
+        if !cmd.Flags().Changed("name") {
+            name = viper.GetString("name")
+        }
+        if !cmd.Flags().Changed("email") {
+            email = viper.GetString("email")
+        }
+        if err = utils.ValidateEmail(email); err != nil {
+            return err
+        }
+        if err = readNewPassword(); err != nil {
+            return err
+        }
+        err = requests.UpdateAccount(name, email, password)
+        if err != nil {
+            return fmt.Errorf("update failed: %s", err)
+        }
+        status(utils.SuccessStyle.Render("Updated account " + email))
+        return nil
+    },
+}
+
+var accountShowCmd = &cobra.Command{
+    Use:   "show",
+    Short: "Show your account details",
+    Long:  `Show your account details.
+
+    This prints the details saved locally along with your UUID from the server,
+    in the format given by --output.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        email := viper.GetString("email")
+        if email == "" {
+            return errors.New("not logged in")
+        }
+        u, err := requests.GetAccount()
+        if err != nil {
+            return err
+        }
+        messages := 0
+        for _, msgs := range apiCfg.Messages {
+            messages += len(msgs)
+        }
+        record := UserRecord{
+            ID: u.ID.String(),
+            Email: email,
+            Name: viper.GetString("name"),
+            CreatedAt: &u.CreatedAt,
+            Messages: messages,
+            IdentityKeys: viper.GetString("identity_key") != "",
+        }
+        return render(record, func() {
+            keys := "no"
+            if record.IdentityKeys {
+                keys = "yes"
+            }
+            fmt.Printf("%s %s\n", utils.StatusStyle.Render("Name: "), record.Name)
+            fmt.Printf("%s %s\n", utils.StatusStyle.Render("Email:"), email)
+            fmt.Printf("%s %s\n", utils.StatusStyle.Render("UUID: "), u.ID)
+            fmt.Printf("%s %s\n", utils.StatusStyle.Render("Since:"), u.CreatedAt.Format("02-01-2006 15:04:05"))
+            fmt.Printf("%s %s\n", utils.StatusStyle.Render("Keys: "), keys)
+        })
+    },
+}
+
+var accountDeleteCmd = &cobra.Command{
+    Use:   "delete",
+    Short: "Delete your account",
+    Long:  `Delete your account from the server.
+
+    This removes your user, keys, sessions and any queued messages from
+    the server, then wipes your local keys and conversation history.
+    The password is required to confirm the deletion. Use --yes to skip
+    the prompt, which --password-stdin needs as both read stdin.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        email := viper.GetString("email")
+        if email == "" {
+            return errors.New("not logged in")
+        }
+        // the prompt would read the password given on stdin
+        if passwordStdin && !yes {
+            return errors.New("--password-stdin needs --yes to skip the confirmation")
+        }
+        if !yes {
+            ok, err := confirm(fmt.Sprintf("Permanently delete account %s and all local data?", email))
+            if err != nil {
+                return err
+            }
+            if !ok {
+                status("Account kept")
+                return nil
+            }
+        }
+        var err error
+        if passwordStdin {
+            password, err = promptLine("")
+        } else if password == "" {
+            password, err = promptPassword("Password: ")
+        }
+        if err != nil {
+            return err
+        }
+        err = requests.DeleteAccount(password)
+        if err != nil {
+            return fmt.Errorf("deletion failed: %s", err)
+        }
+        status(utils.SuccessStyle.Render("Deleted account " + email))
+        return nil
+    },
+}
+
+// read and validate a new password from the flags, stdin, or a prompt
+func readNewPassword() error {
+    var err error
+    if passwordStdin {
+        password, err = promptLine("")
+        if err != nil {
+            return err
+        }
+    } else if password == "" {
+        password, err = promptPassword("Password: ")
+        if err != nil {
+            return err
+        }
+        retyped, err := promptPassword("Retype password: ")
+        if err != nil {
+            return err
+        }
+        if retyped != password {
+            return errors.New("passwords do not match")
+        }
+    }
+    return utils.ValidatePassword(password)
+}
+
+func init() {
+    rootCmd.AddCommand(accountCmd)
+    accountCmd.AddCommand(accountCreateCmd)
+    accountCmd.AddCommand(accountUpdateCmd)
+    accountCmd.AddCommand(accountShowCmd)
+    accountCmd.AddCommand(accountDeleteCmd)
+
+    // Command flags
+    for _, c := range []*cobra.Command{accountCreateCmd, accountUpdateCmd} {
+        c.Flags().StringVarP(&name, "name", "n", "", "the account display name")
+        c.Flags().StringVarP(&email, "email", "e", "", "the account email")
+        c.Flags().StringVarP(&password, "password", "p", "", "the account password")
+        c.Flags().BoolVar(&passwordStdin, "password-stdin", false, "read the password from stdin")
+    }
+    accountDeleteCmd.Flags().StringVarP(&password, "password", "p", "", "the account password")
+    accountDeleteCmd.Flags().BoolVar(&passwordStdin, "password-stdin", false, "read the password from stdin")
+    accountDeleteCmd.Flags().BoolVarP(&yes, "yes", "y", false, "delete without asking for confirmation")
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestDeletePasswordStdin(t *testing.T) {
+    viper.Set("email", "me@example.com")
+    t.Cleanup(func() {
+        viper.Set("email", "")
+        passwordStdin, yes = false, false
+    })
+
+    // the confirmation would read the password, so it must be skipped
+    passwordStdin, yes = true, false
+    if err := accountDeleteCmd.RunE(accountDeleteCmd, nil); err == nil {
+        t.Fatal("expected --password-stdin without --yes to be refused")
+    }
+}
@@ -0,0 +1,358 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var nickname string
+var addressBook string
+var addDiscovered bool
+
+// loose pattern for email addresses in address-book exports
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+var contactsCmd = &cobra.Command{
+    Use:     "contacts [CMD]",
+    Aliases: []string{"contact"},
+    Short:   "Manage your local contact book",
+    Long:    `Manage your local contact book.
+
+    This should be used with the commands "add", "list", "rename", "trust",
+    "block", "unblock", "remove" and "discover".
+    Contacts are stored locally and may be referred to by uuid, email or
+    nickname wherever a user is expected.`,
+}
+
+var contactsAddCmd = &cobra.Command{
+    Use:   "add USER",
+    Short: "Add a user to your contact book",
+    Long:  `Add a user to your contact book.
+
+    The user is given by email or uuid. Their details and identity key
+    fingerprint are fetched from the server. Adding an existing contact
+    refreshes these details.`,
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        c, err := requests.SaveContact(apiCfg.Contacts, args[0])
+        if err != nil {
+            return err
+        }
+        if cmd.Flags().Changed("nickname") {
+            if err = setNickname(&c, nickname); err != nil {
+                return err
+            }
+        }
+        status(utils.SuccessStyle.Render("Added " + contactHeader(c)))
+        warnKeyChanged(c)
+        return nil
+    },
+}
+
+var contactsListCmd = &cobra.Command{
+    Use:   "list",
+    Short: "List your contacts",
+    Long:  `List the contacts in your contact book, including blocked contacts.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        records := newContactRecords(apiCfg.Contacts)
+        return render(records, func() {
+            if len(records) == 0 {
+                fmt.Println("No contacts (yet)")
+                return
+            }
+            for _, r := range records {
+                c := apiCfg.Contacts[r.ID]
+                header := contactHeader(c)
+                if r.Blocked {
+                    header += utils.ErrorStyle.Render(" (blocked)")
+                }
+                if r.NewFingerprint != "" {
+                    header += utils.ErrorStyle.Render(" (identity key changed)")
+                }
+                fmt.Println(utils.SuccessStyle.Bold(true).Render(header))
+                fmt.Printf("  %s %s\n", utils.StatusStyle.Render("UUID:       "), r.ID)
+                if r.Fingerprint != "" {
+                    fmt.Printf("  %s %s\n", utils.StatusStyle.Render("Fingerprint:"), r.Fingerprint)
+                }
+                if r.NewFingerprint != "" {
+                    fmt.Printf("  %s %s\n", utils.StatusStyle.Render("New key:    "), r.NewFingerprint)
+                }
+            }
+        })
+    },
+}
+
+var contactsRenameCmd = &cobra.Command{
+    Use:   "rename CONTACT NICKNAME",
+    Short: "Set the nickname of a contact",
+    Long:  `Set the local nickname of a contact.
+
+    The nickname is shown instead of the contact's name and may be used
+    to refer to them. An empty nickname removes it.`,
+    Args: cobra.ExactArgs(2),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        c, err := requests.ResolveContact(apiCfg.Contacts, args[0])
+        if err != nil {
+            return err
+        }
+        if err = setNickname(&c, args[1]); err != nil {
+            return err
+        }
+        status(utils.SuccessStyle.Render("Renamed " + contactHeader(c)))
+        return nil
+    },
+}
+
+var contactsTrustCmd = &cobra.Command{
+    Use:   "trust CONTACT",
+    Short: "Trust the changed identity key of a contact",
+    Long:  `Trust the changed identity key of a contact.
+
+    A contact's identity key changes when they reinstall mescli, or when
+    someone is impersonating them. Messages cannot be sent to the contact
+    until the new key is trusted. Compare the fingerprint with the contact
+    in person or over another channel before confirming.`,
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        c, ok := requests.FindContact(apiCfg.Contacts, args[0])
+        if !ok {
+            return fmt.Errorf("no contact %q", args[0])
+        }
+        if !c.KeyChanged() {
+            return errors.New("the identity key of " + contactHeader(c) + " has not changed")
+        }
+        warnKeyChanged(c)
+        ok, err := confirm("Trust the new identity key of " + contactHeader(c) + "?")
+        if err != nil {
+            return err
+        }
+        if !ok {
+            status("Key not trusted")
+            return nil
+        }
+        c.TrustKey()
+        apiCfg.Contacts[c.ID.String()] = c
+        if err = requests.WriteContacts(apiCfg.Contacts); err != nil {
+            return err
+        }
+        status(utils.SuccessStyle.Render("Trusted the new identity key of " + contactHeader(c)))
+        return nil
+    },
+}
+
+var contactsBlockCmd = &cobra.Command{
+    Use:   "block CONTACT",
+    Short: "Block a contact",
+    Long:  `Block a contact.
+
+    The server rejects messages and key requests from blocked contacts,
+    and any of their messages already queued are dropped. Messages cannot
+    be sent to blocked contacts and they are hidden in the TUI.`,
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        return setBlocked(args[0], true)
+    },
+}
+
+var contactsUnblockCmd = &cobra.Command{
+    Use:   "unblock CONTACT",
+    Short: "Unblock a contact",
+    Long:  `Unblock a previously blocked contact.`,
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        return setBlocked(args[0], false)
+    },
+}
+
+var contactsRemoveCmd = &cobra.Command{
+    Use:   "remove CONTACT",
+    Aliases: []string{"rm"},
+    Short: "Remove a contact from your contact book",
+    Long:  `Remove a contact from your contact book.
+
+    Any conversation history with the contact is kept.`,
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        c, ok := requests.FindContact(apiCfg.Contacts, args[0])
+        if !ok {
+            return fmt.Errorf("no contact %q", args[0])
+        }
+        delete(apiCfg.Contacts, c.ID.String())
+        if err := requests.WriteContacts(apiCfg.Contacts); err != nil {
+            return err
+        }
+        status(utils.SuccessStyle.Render("Removed " + contactHeader(c)))
+        return nil
+    },
+}
+
+var contactsDiscoverCmd = &cobra.Command{
+    Use:   "discover",
+    Short: "Find which of your contacts use mescli",
+    Long:  `Find which people in a local address book use mescli.
+
+    Email addresses are read from the file given with --file, which may
+    be a vCard, CSV or plain text export. Only truncated hashes of the
+    addresses are sent to the server, which returns the UUID and
+    identity key fingerprint of each match. Use --add to save the
+    matches in your contact book.
+    Lookups are rate limited by the server.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        if addressBook == "" {
+            return errors.New("must provide an address book with --file")
+        }
+        data, err := os.ReadFile(addressBook)
+        if err != nil {
+            return err
+        }
+        emails := parseEmails(string(data))
+        found, err := requests.DiscoverContacts(emails)
+        if err != nil && len(found) == 0 {
+            return err
+        } else if err != nil {
+            fmt.Fprintln(os.Stderr, utils.ErrorStyle.Render("discovery incomplete: "+err.Error()))
+        }
+        if addDiscovered {
+            for _, c := range found {
+                saved := apiCfg.Contacts[c.ID.String()]
+                saved.ID = c.ID
+                saved.Email = c.Email
+                saved.UpdateFingerprint(c.Fingerprint)
+                apiCfg.Contacts[c.ID.String()] = saved
+                warnKeyChanged(saved)
+            }
+            if err = requests.WriteContacts(apiCfg.Contacts); err != nil {
+                return err
+            }
+        }
+        records := []ContactRecord{}
+        for _, c := range found {
+            records = append(records, ContactRecord{ID: c.ID.String(), Email: c.Email, Fingerprint: c.Fingerprint})
+        }
+        return render(records, func() {
+            fmt.Printf("Found %d of %d addresses\n", len(found), len(emails))
+            for _, r := range records {
+                fmt.Println(utils.SuccessStyle.Bold(true).Render(r.Email))
+                fmt.Printf("  %s %s\n", utils.StatusStyle.Render("UUID:       "), r.ID)
+                fmt.Printf("  %s %s\n", utils.StatusStyle.Render("Fingerprint:"), r.Fingerprint)
+            }
+        })
+    },
+}
+
+// parseEmails returns the unique email addresses in an address book, other
+// than your own
+func parseEmails(data string) []string {
+    own := strings.ToLower(viper.GetString("email"))
+    seen := make(map[string]bool)
+    emails := []string{}
+    for _, email := range emailPattern.FindAllString(data, -1) {
+        email = strings.ToLower(email)
+        if seen[email] || email == own {
+            continue
+        }
+        seen[email] = true
+        emails = append(emails, email)
+    }
+    return emails
+}
+
+// set a unique nickname on a contact and save the contact book
+func setNickname(c *requests.Contact, nick string) error {
+    nick = strings.TrimSpace(nick)
+    if other, ok := requests.FindContact(apiCfg.Contacts, nick); nick != "" && ok && other.ID != c.ID {
+        return fmt.Errorf("%q already refers to %s", nick, contactHeader(other))
+    }
+    c.Nickname = nick
+    apiCfg.Contacts[c.ID.String()] = *c
+    return requests.WriteContacts(apiCfg.Contacts)
+}
+
+// warnKeyChanged warns that a contact's identity key is not the one trusted
+func warnKeyChanged(c requests.Contact) {
+    if !c.KeyChanged() {
+        return
+    }
+    status(utils.ErrorStyle.Render("The identity key of " + contactHeader(c) + " has changed"))
+    status("  " + utils.StatusStyle.Render("Trusted:") + " " + c.Fingerprint)
+    status("  " + utils.StatusStyle.Render("New:    ") + " " + c.NewFingerprint)
+}
+
+func setBlocked(user string, blocked bool) error {
+    c, err := requests.ResolveContact(apiCfg.Contacts, user)
+    if err != nil {
+        return err
+    }
+    if c.Blocked == blocked {
+        return errors.New(contactHeader(c) + " is already " + strings.ToLower(blockedState(blocked)))
+    }
+    // enforce the block on the server as well
+    if blocked {
+        err = requests.BlockUser(c.ID)
+    } else {
+        err = requests.UnblockUser(c.ID)
+    }
+    if err != nil {
+        return err
+    }
+    c.Blocked = blocked
+    apiCfg.Contacts[c.ID.String()] = c
+    if err = requests.WriteContacts(apiCfg.Contacts); err != nil {
+        return err
+    }
+    status(utils.SuccessStyle.Render(blockedState(blocked) + " " + contactHeader(c)))
+    return nil
+}
+
+func blockedState(blocked bool) string {
+    if blocked {
+        return "Blocked"
+    }
+    return "Unblocked"
+}
+
+func newContactRecords(contacts map[string]requests.Contact) []ContactRecord {
+    records := []ContactRecord{}
+    for id, c := range contacts {
+        records = append(records, ContactRecord{
+            ID: id,
+            Email: c.Email,
+            Name: c.Name,
+            Nickname: c.Nickname,
+            Fingerprint: c.Fingerprint,
+            NewFingerprint: c.NewFingerprint,
+            Blocked: c.Blocked,
+        })
+    }
+    sort.Slice(records, func(i, j int) bool {
+        return strings.ToLower(contacts[records[i].ID].DisplayName()) <
+            strings.ToLower(contacts[records[j].ID].DisplayName())
+    })
+    return records
+}
+
+func init() {
+    rootCmd.AddCommand(contactsCmd)
+    contactsCmd.AddCommand(contactsAddCmd)
+    contactsCmd.AddCommand(contactsListCmd)
+    contactsCmd.AddCommand(contactsRenameCmd)
+    contactsCmd.AddCommand(contactsTrustCmd)
+    contactsCmd.AddCommand(contactsBlockCmd)
+    contactsCmd.AddCommand(contactsUnblockCmd)
+    contactsCmd.AddCommand(contactsRemoveCmd)
+    contactsCmd.AddCommand(contactsDiscoverCmd)
+
+    // Command flags
+    contactsAddCmd.Flags().StringVar(&nickname, "nickname", "", "a local nickname for the contact")
+    contactsDiscoverCmd.Flags().StringVarP(&addressBook, "file", "f", "", "address book file to read emails from")
+    contactsDiscoverCmd.Flags().BoolVar(&addDiscovered, "add", false, "save matches in your contact book")
+}
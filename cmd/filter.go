@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/google/uuid"
+)
+
+// time layouts accepted by --since and --until
+var timeLayouts = []string{
+    time.RFC3339,
+    "2006-01-02 15:04:05",
+    "2006-01-02 15:04",
+    "2006-01-02",
+}
+
+type messageFilter struct {
+    contact        string
+    last           int
+    conversations  int
+    since          time.Time
+    until          time.Time
+    reverse        bool
+}
+
+type conversation struct {
+    contact   string
+    messages  []utils.RawMessage
+    latest    time.Time
+}
+
+// apply selects the most recent messages from the most recently active
+// conversations, with conversations ordered by latest activity
+func (f messageFilter) apply(messages map[string][]utils.RawMessage) []conversation {
+    convs := []conversation{}
+    for contact, msgs := range messages {
+        if f.contact != "" && contact != f.contact {
+            continue
+        }
+        c := conversation{contact: contact}
+        for _, m := range msgs {
+            if f.inRange(m.Time) {
+                c.messages = append(c.messages, m)
+            }
+        }
+        if len(c.messages) == 0 {
+            continue
+        }
+        sort.SliceStable(c.messages, func(i, j int) bool {
+            return c.messages[i].Time.Before(c.messages[j].Time)
+        })
+        c.latest = c.messages[len(c.messages)-1].Time
+        if f.last > 0 && len(c.messages) > f.last {
+            c.messages = c.messages[len(c.messages)-f.last:]
+        }
+        if f.reverse {
+            for i, j := 0, len(c.messages)-1; i < j; i, j = i+1, j-1 {
+                c.messages[i], c.messages[j] = c.messages[j], c.messages[i]
+            }
+        }
+        convs = append(convs, c)
+    }
+    sort.Slice(convs, func(i, j int) bool { return convs[i].latest.After(convs[j].latest) })
+    if f.conversations > 0 && len(convs) > f.conversations {
+        convs = convs[:f.conversations]
+    }
+    return convs
+}
+
+func (f messageFilter) inRange(t time.Time) bool {
+    if !f.since.IsZero() && t.Before(f.since) {
+        return false
+    }
+    if !f.until.IsZero() && t.After(f.until) {
+        return false
+    }
+    return true
+}
+
+// changedMessages returns the messages of a conversation that are new or
+// changed since it was last read, matching them by ID, or by position for
+// messages saved without one
+func changedMessages(before, after []utils.RawMessage) []utils.RawMessage {
+    key := func(i int, m utils.RawMessage) string {
+        if m.ID != "" {
+            return m.ID
+        }
+        return fmt.Sprintf("#%d", i)
+    }
+    previous := make(map[string]utils.RawMessage, len(before))
+    for i, m := range before {
+        previous[key(i, m)] = m
+    }
+    changed := []utils.RawMessage{}
+    for i, m := range after {
+        if p, ok := previous[key(i, m)]; !ok || !reflect.DeepEqual(p, m) {
+            changed = append(changed, m)
+        }
+    }
+    return changed
+}
+
+// parseTime reads an absolute time or a duration before now, such as "24h"
+func parseTime(s string) (time.Time, error) {
+    if s == "" {
+        return time.Time{}, nil
+    }
+    if d, err := time.ParseDuration(s); err == nil {
+        return time.Now().Add(-d), nil
+    }
+    for _, layout := range timeLayouts {
+        if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+            return t, nil
+        }
+    }
+    return time.Time{}, fmt.Errorf("cannot parse time %q, use RFC 3339, YYYY-MM-DD or a duration such as 24h", s)
+}
+
+// lookupContact resolves a conversation key to its contact details using the
+// local contacts cache, falling back to the bare key if it is not there. The
+// server is never asked, as messages are viewed from local disk.
+func lookupContact(contact string) requests.Contact {
+    if c, ok := requests.FindContact(apiCfg.Contacts, contact); ok {
+        return c
+    }
+    if id, err := uuid.Parse(contact); err == nil {
+        return requests.Contact{ID: id}
+    }
+    return requests.Contact{Email: contact}
+}
+
+// conversationKey returns the key of the stored conversation with a user given
+// by UUID or email
+func conversationKey(user string) string {
+    if _, ok := apiCfg.Messages[user]; ok {
+        return user
+    }
+    c := lookupContact(user)
+    if c.ID == uuid.Nil {
+        return user
+    }
+    return c.ID.String()
+}
+
+func contactHeader(c requests.Contact) string {
+    if c.Email != "" && c.DisplayName() != c.Email {
+        return fmt.Sprintf("%s <%s>", c.DisplayName(), c.Email)
+    }
+    return c.DisplayName()
+}
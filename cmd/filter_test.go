@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/tui"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+func TestParseTime(t *testing.T) {
+    type testCase struct {
+        name   string
+        input  string
+        want   time.Time
+        err    bool
+    }
+
+    tests := []testCase{
+        {"empty", "", time.Time{}, false},
+        {"rfc3339", "2024-03-01T12:30:00Z", time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), false},
+        {"seconds", "2024-03-01 12:30:15", time.Date(2024, 3, 1, 12, 30, 15, 0, time.Local), false},
+        {"minutes", "2024-03-01 12:30", time.Date(2024, 3, 1, 12, 30, 0, 0, time.Local), false},
+        {"date", "2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), false},
+        {"invalid", "yesterday", time.Time{}, true},
+        {"bad date", "2024-13-01", time.Time{}, true},
+    }
+
+    for _, test := range tests {
+        got, err := parseTime(test.input)
+        if (err != nil) != test.err {
+            t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+        } else if !got.Equal(test.want) {
+            t.Errorf("%s: expected %s, got %s", test.name, test.want, got)
+        }
+    }
+
+    // durations are before now
+    got, err := parseTime("90m")
+    if err != nil {
+        t.Fatalf("error parsing duration: %s", err)
+    }
+    if ago := time.Since(got); ago < 90*time.Minute || ago > 91*time.Minute {
+        t.Errorf("expected 90 minutes ago, got %s ago", ago)
+    }
+}
+
+func TestMessageFilter(t *testing.T) {
+    base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+    at := func(hours int, text string) utils.RawMessage {
+        return utils.RawMessage{Message: text, Time: base.Add(time.Duration(hours) * time.Hour)}
+    }
+    messages := map[string][]utils.RawMessage{
+        "alice": {at(0, "a0"), at(3, "a3"), at(1, "a1"), at(5, "a5")},
+        "bob":   {at(2, "b2"), at(4, "b4")},
+        "carol": {at(6, "c6")},
+    }
+
+    type testCase struct {
+        name    string
+        filter  messageFilter
+        want    string
+    }
+
+    tests := []testCase{
+        {"all", messageFilter{}, "carol: c6; alice: a0 a1 a3 a5; bob: b2 b4"},
+        {"sender", messageFilter{contact: "alice"}, "alice: a0 a1 a3 a5"},
+        {"unknown sender", messageFilter{contact: "dave"}, ""},
+        {"last", messageFilter{last: 1}, "carol: c6; alice: a5; bob: b4"},
+        {"conversations", messageFilter{conversations: 2}, "carol: c6; alice: a0 a1 a3 a5"},
+        {"since", messageFilter{since: base.Add(4 * time.Hour)}, "carol: c6; alice: a5; bob: b4"},
+        {"until", messageFilter{until: base.Add(2 * time.Hour)}, "bob: b2; alice: a0 a1"},
+        {"reverse", messageFilter{contact: "bob", reverse: true}, "bob: b4 b2"},
+        {
+            "sender in range",
+            messageFilter{contact: "alice", since: base.Add(time.Hour), until: base.Add(4 * time.Hour)},
+            "alice: a1 a3",
+        },
+        {
+            "combined",
+            messageFilter{since: base.Add(time.Hour), until: base.Add(5 * time.Hour), last: 1, conversations: 1, reverse: true},
+            "alice: a5",
+        },
+        {"empty range", messageFilter{contact: "carol", until: base}, ""},
+    }
+
+    for _, test := range tests {
+        convs := []string{}
+        for _, c := range test.filter.apply(messages) {
+            texts := []string{}
+            for _, m := range c.messages {
+                texts = append(texts, m.Message)
+            }
+            convs = append(convs, c.contact+": "+strings.Join(texts, " "))
+        }
+        if got := strings.Join(convs, "; "); got != test.want {
+            t.Errorf("%s: expected %q, got %q", test.name, test.want, got)
+        }
+    }
+}
+
+func TestLookupContact(t *testing.T) {
+    alice := requests.Contact{ID: uuid.New(), Email: "alice@example.com", Nickname: "al"}
+    saved := apiCfg
+    t.Cleanup(func() { apiCfg = saved })
+    apiCfg = &tui.ApiConfig{Contacts: map[string]requests.Contact{alice.ID.String(): alice}}
+
+    for _, user := range []string{alice.ID.String(), alice.Email, alice.Nickname} {
+        if c := lookupContact(user); c.ID != alice.ID {
+            t.Errorf("expected %q to find alice, got %+v", user, c)
+        }
+    }
+
+    // unknown senders are shown by UUID without asking the server
+    lookups := 0
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        lookups++
+        w.WriteHeader(http.StatusNotFound)
+    }))
+    t.Cleanup(server.Close)
+    url := viper.GetString("api_url")
+    t.Cleanup(func() { viper.Set("api_url", url) })
+    viper.Set("api_url", server.URL)
+    stranger := uuid.New()
+    if c := lookupContact(stranger.String()); c.ID != stranger || lookups != 0 {
+        t.Errorf("expected %s from the cache alone, got %+v after %d lookups", stranger, c, lookups)
+    }
+}
+
+func TestChangedMessages(t *testing.T) {
+    before := []utils.RawMessage{
+        {ID: "1", Message: "first"},
+        {ID: "2", Message: "second"},
+        {Message: "no id"},
+    }
+    edited := before[0]
+    edited.Message, edited.Edited = "first!", true
+    deleted := before[1]
+    deleted.Deleted = true
+    after := []utils.RawMessage{edited, deleted, before[2], {ID: "3", Message: "third"}}
+
+    got := changedMessages(before, after)
+    if len(got) != 3 || got[0].Message != "first!" || !got[1].Deleted || got[2].ID != "3" {
+        t.Fatalf("expected the edit, deletion and new message, got %+v", got)
+    }
+    if got := changedMessages(after, after); len(got) != 0 {
+        t.Fatalf("expected no changes, got %+v", got)
+    }
+}
@@ -14,7 +14,8 @@ var getCmd = &cobra.Command{
 	Long:  `Get information from the server.
 
 	This should be used with the commands "user" and "messages".
-	It should be run frequently if using mescli as CLI.`,
+	It should be run frequently if using mescli as CLI, or use 
+	"mescli watch" to keep fetching messages as they arrive.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("This command is not yet implemented...")
 	},
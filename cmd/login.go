@@ -3,18 +3,56 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/CraigYanitski/mescli/internal/client"
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+var forgetKeys bool
+
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Save credentials in config file",
 	Long:  `Login to the mescli service.
 
 	This can be completed in either the CLI or TUI.
-	It will need to be repeated if you logout or your JWT expires.`,
-	Run: func(cmd *cobra.Command, args []string){
-		fmt.Println("This command still needs to be implemented...")
+	Any credentials not given as flags are prompted for.
+	It will need to be repeated if you logout or your session expires.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+        var err error
+        // prompt for missing credentials
+        if email == "" {
+            email, err = promptLine("Email: ")
+            if err != nil {
+                return err
+            }
+        }
+        if password == "" {
+            password, err = promptPassword("Password: ")
+            if err != nil {
+                return err
+            }
+        }
+        err = requests.LoginWithPassword(email, password)
+        if err != nil {
+            return fmt.Errorf("login failed: %s", err)
+        }
+        // initialise keys if absent and publish them to the server
+        if viper.GetString("identity_key") == "" {
+            c := &client.Client{}
+            if err = c.Initialise(false); err != nil {
+                return err
+            }
+            err = requests.UpdateAccount(viper.GetString("name"), email, password)
+            if err != nil {
+                return fmt.Errorf("unable to publish new keys: %s", err)
+            }
+            status(utils.StatusStyle.Render("Generated new identity keys"))
+        }
+        status(utils.SuccessStyle.Render("Logged in as " + email))
+        return nil
 	},
 }
 
@@ -23,9 +61,30 @@ var logoutCmd = &cobra.Command{
 	Short: "Remove credentials from config file",
 	Long:  `Logout of the mescli service.
 
-	This can be completed in either the CLI or TUI.`,
-	Run: func(cmd *cobra.Command, args []string){
-		fmt.Println("This command still needs to be implemented...")
+	This can be completed in either the CLI or TUI.
+	Use --forget-keys to also wipe your identity keys and conversation
+	ratchets from this machine. Messages encrypted to the old keys can
+	no longer be read afterwards.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+        err := requests.Logout()
+        if err != nil {
+            status(utils.ErrorStyle.Render("Unable to revoke session on server: " + err.Error()))
+        }
+        if forgetKeys {
+            ok, err := confirm("Wipe all local key material?")
+            if err != nil {
+                return err
+            }
+            if !ok {
+                status("Keeping local keys")
+            } else if err = requests.ForgetKeys(); err != nil {
+                return err
+            } else {
+                status(utils.StatusStyle.Render("Local keys wiped"))
+            }
+        }
+        status(utils.SuccessStyle.Render("Logged out"))
+        return nil
 	},
 }
 
@@ -34,5 +93,5 @@ func init() {
 	loginCmd.Flags().StringVarP(&email, "email", "e", "", "the account email to login")
 	loginCmd.Flags().StringVarP(&password, "password", "p", "", "the account password to login")
 	rootCmd.AddCommand(logoutCmd)
+	logoutCmd.Flags().BoolVar(&forgetKeys, "forget-keys", false, "also wipe local key material")
 }
-
@@ -1,18 +1,35 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/CraigYanitski/mescli/internal/requests"
 	"github.com/CraigYanitski/mescli/internal/utils"
-	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
 
 
 
+var (
+    messageFile        string
+    editor             bool
+    split              bool
+    lastMessages       int
+    lastConversations  int
+    since              string
+    until              string
+    reverse            bool
+    follow             bool
+)
+
 var getMessagesCmd = &cobra.Command{
     Use:   "messages",
     Short: "Get messages from the server",
@@ -20,37 +37,38 @@ var getMessagesCmd = &cobra.Command{
 
     This requires the user to be logged in.
     Any messages sent to the user are stored on the database until 
-    retrieved. The messages will be displayed by user then by time.`,
+    retrieved. The messages will be displayed by user then by time.
+    With --output json or yaml this prints a list of message records.`,
     RunE: func(cmd *cobra.Command, args []string) error {
-        fmt.Println("This command is not yet implemented...")
-        messages, err := requests.GetMessages()
+        messages, err := requests.SyncMessages(apiCfg.Messages)
         if err != nil {
             return err
         }
-        var u string
+        records := []MessageRecord{}
         for _, m := range messages {
-            if m.SenderID.String() != u {
-                u = m.SenderID.String()
-                fmt.Printf("%s\n", utils.SuccessStyle.Bold(true).Render(u))
-            }
-            apiCfg.Messages[u] = append(
-                apiCfg.Messages[u], 
+            records = append(records, newMessageRecord(
+                m.SenderID.String(),
                 utils.RawMessage{
                     Sender: utils.ContactType,
                     Message: m.Message,
                     Time: m.CreatedAt,
                 },
-            )
-            fmt.Printf(
-                "  %s  %s\n", 
-                utils.StatusStyle.Render(m.CreatedAt.Format("02-01-2006 15:04:05")), 
-                m.Message,
-            )
-        }
-        if ok := requests.WriteMessages(apiCfg.Messages); !ok {
-            return errors.New("Unable to save messages locally.")
+            ))
         }
-        return nil
+        return render(records, func() {
+            var u string
+            for _, r := range records {
+                if r.Conversation != u {
+                    u = r.Conversation
+                    fmt.Printf("%s\n", utils.SuccessStyle.Bold(true).Render(u))
+                }
+                fmt.Printf(
+                    "  %s  %s\n", 
+                    utils.StatusStyle.Render(r.Timestamp.Format("02-01-2006 15:04:05")), 
+                    r.Text(),
+                )
+            }
+        })
     },
 }
 
@@ -60,67 +78,111 @@ var viewMessagesCmd = &cobra.Command{
     Long:  `View messages from local disk.
 
     The user email or UUID may be specified for a specific conversation.
-    This prints the five most recent messages in from the 3 most recent conversations.
-    It will soon be possible to alter these numbers.`,
-    Run: func(cmd *cobra.Command, args []string) {
-        fmt.Println("This command is not yet completed...")
-        if len(apiCfg.Messages) == 0 {
-            fmt.Println("There are no messages to display")
-            return
-        }
-        for contact, messages := range apiCfg.Messages {
-            fmt.Printf("%s\n", utils.SuccessStyle.Bold(true).Render(contact))
-            for _, m := range messages {
+    By default this prints the five most recent messages from the three 
+    most recently active conversations, which can be changed with --last 
+    and --conversations (0 for no limit).
+    The --since and --until options take an RFC 3339 time, a date such as 
+    2006-01-02, or a duration before now such as 24h.
+    With --follow, new messages saved by other mescli processes are printed 
+    as they arrive until interrupted, and messages are printed again when 
+    they are edited, deleted or reacted to.
+    With --output json or yaml this prints a list of message records.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        f, err := newMessageFilter()
+        if err != nil {
+            return err
+        }
+        convs := f.apply(apiCfg.Messages)
+        records := []MessageRecord{}
+        contacts := make(map[string]requests.Contact)
+        for _, c := range convs {
+            contacts[c.contact] = lookupContact(c.contact)
+            for _, m := range c.messages {
+                records = append(records, newContactMessageRecord(contacts[c.contact], c.contact, m))
+            }
+        }
+        err = render(records, func() {
+            if len(records) == 0 {
+                fmt.Println("There are no messages to display")
+                return
+            }
+            var u string
+            for _, r := range records {
+                if r.Conversation != u {
+                    u = r.Conversation
+                    fmt.Printf("%s\n", utils.SuccessStyle.Bold(true).Render(contactHeader(contacts[u])))
+                }
                 fmt.Printf(
                     "  %s  %s\n", 
-                    utils.StatusStyle.Render(m.Time.Format("02-01-2006 15:04:05")), 
-                    m.Message,
+                    utils.StatusStyle.Render(r.Timestamp.Format("02-01-2006 15:04:05")), 
+                    r.Text(),
                 )
             }
+        })
+        if err != nil || !follow {
+            return err
         }
+        return followMessages(f)
     },
 }
 
 var sendMessageCmd = &cobra.Command{
-    Use:   "message [MSG]",
+    Use:   "message [MSG | -]",
     Short: "Send message to contact",
     Long:  `Send a message to a contact.
 
-    The user email or uuid must be specified in order to send a message.`,
+    The user email, uuid or nickname must be specified in order to send a
+    message. Messages cannot be sent to blocked contacts.
+    The Markdown body is given as an argument, read from stdin when the
+    argument is "-", read from a file with --file, or written in $EDITOR
+    with --editor.
+    Messages are limited to 1024 characters. Longer messages are rejected
+    unless --split is given, in which case they are sent as several
+    messages broken between paragraphs, then lines, then words. Code
+    blocks are never broken, so one over the limit is sent whole.`,
     RunE: func(cmd *cobra.Command, args []string) error {
-        if len(args) != 1 {
-            return fmt.Errorf("Require 1 argument, got %d", len(args))
+        if len(args) > 1 {
+            return fmt.Errorf("Require at most 1 argument, got %d", len(args))
         }
-        msg := args[0]
-        var uid *uuid.UUID
         if user == "" {
-			// Throw error if user not specified
             return errors.New("must provide a user in order to send a message")
-        } else {
-			// Get user information and save uuid and email
-			u, err := requests.GetUser(user)
-            if err != nil {
-                return err
-            }
-			uid = &u.ID
-			user = u.Email
         }
-        packet, err := requests.AddContact(user)
+        msg, err := readMessageBody(args)
         if err != nil {
             return err
         }
-        err = requests.SendEncryptedMessage(*uid, packet, msg)
+        chunks := utils.SplitMessage(msg, utils.MaxMessageLength)
+        if len(chunks) == 0 {
+            return errors.New("cannot send an empty message")
+        } else if len(chunks) > 1 && !split {
+            return fmt.Errorf(
+                "message is %d characters, over the %d character limit (use --split to send it in parts)",
+                utf8.RuneCountInString(msg),
+                utils.MaxMessageLength,
+            )
+        }
+        // Get user information and save uuid and email
+        u, err := requests.ResolveContact(apiCfg.Contacts, user)
         if err != nil {
             return err
         }
-        apiCfg.Messages[uid.String()] = append(
-            apiCfg.Messages[uid.String()], 
-            utils.RawMessage{
-                Sender: utils.SelfType,
-                Message: msg,
-                Time: time.Now(),
-            },
-        )
+        if u.Blocked {
+            return fmt.Errorf("%s: %w", u.DisplayName(), requests.ErrBlocked)
+        }
+        uid := &u.ID
+        for _, chunk := range chunks {
+            p := utils.NewPayload(utils.TextPayload, chunk)
+            err = requests.SendPayload(uid.String(), p)
+            if err != nil {
+                return err
+            }
+            apiCfg.Messages[uid.String()] = utils.ApplyPayload(
+                apiCfg.Messages[uid.String()], 
+                utils.SelfType,
+                p,
+                p.SentAt.Local(),
+            )
+        }
         if ok := requests.WriteMessages(apiCfg.Messages); !ok {
             return errors.New("Unable to save messages locally.")
         }
@@ -128,6 +190,118 @@ var sendMessageCmd = &cobra.Command{
     },
 }
 
+// read the message body from exactly one of the argument, stdin, a file or the editor
+func readMessageBody(args []string) (string, error) {
+    sources := 0
+    if len(args) == 1 {
+        sources++
+    }
+    if messageFile != "" {
+        sources++
+    }
+    if editor {
+        sources++
+    }
+    if sources != 1 {
+        return "", errors.New("give the message as exactly one of an argument, \"-\" for stdin, --file or --editor")
+    }
+    switch {
+    case messageFile != "":
+        body, err := os.ReadFile(messageFile)
+        return string(body), err
+    case editor:
+        return editMessage()
+    case args[0] == "-":
+        body, err := io.ReadAll(stdin)
+        return string(body), err
+    default:
+        return args[0], nil
+    }
+}
+
+// open $EDITOR on a temporary Markdown file and return its contents
+func editMessage() (string, error) {
+    f, err := os.CreateTemp("", "mescli-*.md")
+    if err != nil {
+        return "", err
+    }
+    defer os.Remove(f.Name())
+    f.Close()
+    editorCmd := utils.EditorCommand(f.Name())
+    editorCmd.Stdin = os.Stdin
+    editorCmd.Stdout = os.Stdout
+    editorCmd.Stderr = os.Stderr
+    if err = editorCmd.Run(); err != nil {
+        return "", fmt.Errorf("error running editor: %s", err)
+    }
+    body, err := os.ReadFile(f.Name())
+    return string(body), err
+}
+
+func newMessageFilter() (messageFilter, error) {
+    f := messageFilter{
+        last: lastMessages,
+        conversations: lastConversations,
+        reverse: reverse,
+    }
+    var err error
+    if user != "" {
+        f.contact = conversationKey(user)
+    }
+    if f.since, err = parseTime(since); err != nil {
+        return f, err
+    }
+    if f.until, err = parseTime(until); err != nil {
+        return f, err
+    }
+    return f, nil
+}
+
+func newContactMessageRecord(c requests.Contact, contact string, m utils.RawMessage) MessageRecord {
+    r := newMessageRecord(contact, m)
+    r.Name = c.Name
+    r.Email = c.Email
+    return r
+}
+
+// followMessages prints messages saved locally until interrupted, as they
+// arrive or are edited, deleted or reacted to
+func followMessages(f messageFilter) error {
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    seen := apiCfg.Messages
+    ticker := time.NewTicker(time.Second)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return nil
+        case <-ticker.C:
+        }
+        messages, err := requests.ReadMessages()
+        if err != nil {
+            // the file may be mid-write, so try again on the next tick
+            continue
+        }
+        for contact, msgs := range messages {
+            if f.contact != "" && contact != f.contact {
+                continue
+            }
+            for _, m := range changedMessages(seen[contact], msgs) {
+                if !f.inRange(m.Time) {
+                    continue
+                }
+                err = renderLine(newContactMessageRecord(lookupContact(contact), contact, m))
+                if err != nil {
+                    return err
+                }
+            }
+        }
+        seen = messages
+        apiCfg.Messages = messages
+    }
+}
+
 func init() {
     rootCmd.AddCommand(sendMessageCmd)
     getCmd.AddCommand(getMessagesCmd)
@@ -135,5 +309,14 @@ func init() {
 
     // Command flags
     rootCmd.PersistentFlags().StringVarP(&user, "user", "u", "", "user UUID or email")
+    sendMessageCmd.Flags().StringVar(&messageFile, "file", "", "read the message from a Markdown file")
+    sendMessageCmd.Flags().BoolVar(&editor, "editor", false, "write the message in $EDITOR")
+    sendMessageCmd.Flags().BoolVar(&split, "split", false, "send long messages in several parts")
+    viewMessagesCmd.Flags().IntVarP(&lastMessages, "last", "n", 5, "number of recent messages per conversation")
+    viewMessagesCmd.Flags().IntVarP(&lastConversations, "conversations", "c", 3, "number of recent conversations")
+    viewMessagesCmd.Flags().StringVar(&since, "since", "", "only show messages after this time")
+    viewMessagesCmd.Flags().StringVar(&until, "until", "", "only show messages before this time")
+    viewMessagesCmd.Flags().BoolVarP(&reverse, "reverse", "r", false, "show the newest messages first")
+    viewMessagesCmd.Flags().BoolVarP(&follow, "follow", "f", false, "print new messages as they arrive")
 }
 
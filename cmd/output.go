@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"go.yaml.in/yaml/v3"
+)
+
+// output formats for the --output flag
+const (
+    outputTable = "table"
+    outputJSON  = "json"
+    outputYAML  = "yaml"
+)
+
+// message directions in MessageRecord
+const (
+    directionSent      = "sent"
+    directionReceived  = "received"
+)
+
+var output string
+
+// UserRecord is the stable schema for users in JSON and YAML output.
+type UserRecord struct {
+    ID            string      `json:"id" yaml:"id"`
+    Email         string      `json:"email,omitempty" yaml:"email,omitempty"`
+    Name          string      `json:"name,omitempty" yaml:"name,omitempty"`
+    CreatedAt     *time.Time  `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+    Messages      int         `json:"messages" yaml:"messages"`
+    LastActive    *time.Time  `json:"last_active,omitempty" yaml:"last_active,omitempty"`
+    // whether identity keys are saved, only shown for your own account
+    IdentityKeys  bool        `json:"identity_keys,omitempty" yaml:"identity_keys,omitempty"`
+}
+
+// ContactRecord is the stable schema for contacts in JSON and YAML output.
+type ContactRecord struct {
+    ID              string  `json:"id" yaml:"id"`
+    Email           string  `json:"email,omitempty" yaml:"email,omitempty"`
+    Name            string  `json:"name,omitempty" yaml:"name,omitempty"`
+    Nickname        string  `json:"nickname,omitempty" yaml:"nickname,omitempty"`
+    Fingerprint     string  `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+    // fingerprint of a changed identity key, not yet trusted
+    NewFingerprint  string  `json:"new_fingerprint,omitempty" yaml:"new_fingerprint,omitempty"`
+    Blocked         bool    `json:"blocked" yaml:"blocked"`
+}
+
+// RequestRecord is the stable schema for message requests in JSON and YAML output.
+type RequestRecord struct {
+    ID           string     `json:"id" yaml:"id"`
+    Email        string     `json:"email,omitempty" yaml:"email,omitempty"`
+    Name         string     `json:"name,omitempty" yaml:"name,omitempty"`
+    Messages     int        `json:"messages" yaml:"messages"`
+    FirstSentAt  time.Time  `json:"first_sent_at" yaml:"first_sent_at"`
+}
+
+// MessageRecord is the stable schema for messages in JSON and YAML output.
+type MessageRecord struct {
+    ID            string     `json:"id,omitempty" yaml:"id,omitempty"`
+    Conversation  string     `json:"conversation" yaml:"conversation"`
+    Name          string     `json:"name,omitempty" yaml:"name,omitempty"`
+    Email         string     `json:"email,omitempty" yaml:"email,omitempty"`
+    Sender        string     `json:"sender" yaml:"sender"`
+    Direction     string     `json:"direction" yaml:"direction"`
+    Timestamp     time.Time  `json:"timestamp" yaml:"timestamp"`
+    Body          string     `json:"body" yaml:"body"`
+    ReplyTo       string     `json:"reply_to,omitempty" yaml:"reply_to,omitempty"`
+    Edited        bool       `json:"edited,omitempty" yaml:"edited,omitempty"`
+    Deleted       bool       `json:"deleted,omitempty" yaml:"deleted,omitempty"`
+}
+
+func validateOutput() error {
+    switch output {
+    case outputTable, outputJSON, outputYAML:
+        return nil
+    default:
+        return fmt.Errorf("unknown output format %q, expected json, yaml or table", output)
+    }
+}
+
+// render writes v in the selected format, falling back to the table function
+func render(v any, table func()) error {
+    switch output {
+    case outputJSON:
+        enc := json.NewEncoder(os.Stdout)
+        enc.SetIndent("", "  ")
+        return enc.Encode(v)
+    case outputYAML:
+        enc := yaml.NewEncoder(os.Stdout)
+        defer enc.Close()
+        return enc.Encode(v)
+    default:
+        table()
+        return nil
+    }
+}
+
+// status tells the user what a command did. It is written to stderr like the
+// prompts, so only the records are written to stdout.
+func status(msg string) {
+    fmt.Fprintln(os.Stderr, msg)
+}
+
+// renderLine writes a single message as it arrives, as one JSON object per
+// line, one YAML document, or one line of text
+func renderLine(r MessageRecord) error {
+    switch output {
+    case outputJSON:
+        return json.NewEncoder(os.Stdout).Encode(r)
+    case outputYAML:
+        fmt.Println("---")
+        enc := yaml.NewEncoder(os.Stdout)
+        defer enc.Close()
+        return enc.Encode(r)
+    default:
+        sender := r.Name
+        if sender == "" {
+            sender = r.Conversation
+        }
+        if r.Direction == directionSent {
+            sender = "You"
+        }
+        fmt.Printf(
+            "%s  %s %s\n",
+            utils.StatusStyle.Render(r.Timestamp.Format("02-01-2006 15:04:05")),
+            utils.SuccessStyle.Bold(true).Render(sender+":"),
+            r.Text(),
+        )
+        return nil
+    }
+}
+
+// Text is the body shown in text output, marking edits and deletions.
+func (r MessageRecord) Text() string {
+    if r.Deleted {
+        return "(message deleted)"
+    }
+    if r.Edited {
+        return r.Body + " (edited)"
+    }
+    return r.Body
+}
+
+func newMessageRecord(conversation string, m utils.RawMessage) MessageRecord {
+    r := MessageRecord{
+        ID: m.ID,
+        Conversation: conversation,
+        Sender: conversation,
+        Direction: directionReceived,
+        Timestamp: m.Time,
+        Body: m.Message,
+        ReplyTo: m.ReplyTo,
+        Edited: m.Edited,
+        Deleted: m.Deleted,
+    }
+    if m.Sender == utils.SelfType {
+        r.Sender = "self"
+        r.Direction = directionSent
+    }
+    return r
+}
+
+func newUserRecords(messages map[string][]utils.RawMessage) []UserRecord {
+    records := []UserRecord{}
+    for contact, msgs := range messages {
+        r := UserRecord{ID: contact, Messages: len(msgs)}
+        if len(msgs) > 0 {
+            last := msgs[len(msgs)-1].Time
+            r.LastActive = &last
+        }
+        records = append(records, r)
+    }
+    sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+    return records
+}
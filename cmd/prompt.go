@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// read a single line from stdin
+func promptLine(prompt string) (string, error) {
+    fmt.Fprint(os.Stderr, prompt)
+    line, err := stdin.ReadString('\n')
+    if err != nil && line == "" {
+        return "", err
+    }
+    return strings.TrimSpace(line), nil
+}
+
+// read a password from stdin without echoing it to the terminal
+func promptPassword(prompt string) (string, error) {
+    fd := int(os.Stdin.Fd())
+    if !term.IsTerminal(fd) {
+        return promptLine(prompt)
+    }
+    fmt.Fprint(os.Stderr, prompt)
+    password, err := term.ReadPassword(fd)
+    fmt.Fprintln(os.Stderr)
+    if err != nil {
+        return "", err
+    }
+    return string(password), nil
+}
+
+// ask the user to confirm an action, defaulting to no
+func confirm(prompt string) (bool, error) {
+    answer, err := promptLine(prompt + " [y/N]: ")
+    if err != nil {
+        return false, err
+    }
+    answer = strings.ToLower(answer)
+    return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var declineBlock bool
+
+var requestsCmd = &cobra.Command{
+    Use:   "requests [CMD]",
+    Short: "Manage message requests from unknown users",
+    Long:  `Manage message requests from unknown users.
+
+    This should be used with the commands "list", "accept", "decline",
+    "enable" and "disable".
+    When message requests are enabled, the first messages from users you
+    have not messaged or accepted are held by the server until you accept
+    or decline them.`,
+}
+
+var requestsListCmd = &cobra.Command{
+    Use:   "list",
+    Short: "List pending message requests",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        pending, err := requests.GetMessageRequests()
+        if err != nil {
+            return err
+        }
+        records := newRequestRecords(pending)
+        return render(records, func() {
+            if len(records) == 0 {
+                fmt.Println("No message requests")
+                return
+            }
+            for _, r := range records {
+                c := requests.Contact{Email: r.Email, Name: r.Name}
+                fmt.Printf(
+                    "%s  %s  %d message(s)\n",
+                    utils.StatusStyle.Render(r.FirstSentAt.Format("02-01-2006 15:04:05")),
+                    utils.SuccessStyle.Bold(true).Render(contactHeader(c)),
+                    r.Messages,
+                )
+            }
+        })
+    },
+}
+
+var requestsAcceptCmd = &cobra.Command{
+    Use:   "accept USER",
+    Short: "Accept a message request",
+    Long:  `Accept a message request, adding the user to your contact book.
+
+    Their held messages are delivered the next time messages are fetched.`,
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        c, err := requests.ResolveContact(apiCfg.Contacts, args[0])
+        if err != nil {
+            return err
+        }
+        if err = requests.AcceptMessageRequest(c.ID); err != nil {
+            return err
+        }
+        status(utils.SuccessStyle.Render("Accepted " + contactHeader(c)))
+        return nil
+    },
+}
+
+var requestsDeclineCmd = &cobra.Command{
+    Use:   "decline USER",
+    Short: "Decline a message request",
+    Long:  `Decline a message request, deleting the held messages.
+
+    Use --block to also block the user.`,
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        id, err := uuid.Parse(args[0])
+        if err != nil {
+            u, err := requests.GetUser(args[0])
+            if err != nil {
+                return err
+            }
+            id = u.ID
+        }
+        if err = requests.DeclineMessageRequest(id); err != nil {
+            return err
+        }
+        if declineBlock {
+            return setBlocked(id.String(), true)
+        }
+        status(utils.SuccessStyle.Render("Declined " + args[0]))
+        return nil
+    },
+}
+
+var requestsEnableCmd = &cobra.Command{
+    Use:   "enable",
+    Short: "Hold first contact from unknown users",
+    Long:  `Hold first contact from unknown users until you accept it.
+
+    Everyone in your contact book who is not blocked is accepted, so
+    existing conversations are not interrupted.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        for _, c := range apiCfg.Contacts {
+            if c.Blocked {
+                continue
+            }
+            if err := requests.AcceptMessageRequest(c.ID); err != nil {
+                return err
+            }
+        }
+        return setMessageRequests(true)
+    },
+}
+
+var requestsDisableCmd = &cobra.Command{
+    Use:   "disable",
+    Short: "Receive messages from anyone",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        return setMessageRequests(false)
+    },
+}
+
+func setMessageRequests(enabled bool) error {
+    s, err := requests.UpdateSettings(requests.Settings{MessageRequests: enabled})
+    if err != nil {
+        return err
+    }
+    state := "disabled"
+    if s.MessageRequests {
+        state = "enabled"
+    }
+    status(utils.SuccessStyle.Render("Message requests " + state))
+    return nil
+}
+
+func newRequestRecords(pending []requests.PendingRequest) []RequestRecord {
+    records := []RequestRecord{}
+    for _, p := range pending {
+        records = append(records, RequestRecord{
+            ID: p.SenderID.String(),
+            Email: p.Email,
+            Name: p.Name,
+            Messages: p.Messages,
+            FirstSentAt: p.FirstSentAt,
+        })
+    }
+    return records
+}
+
+func init() {
+    rootCmd.AddCommand(requestsCmd)
+    requestsCmd.AddCommand(requestsListCmd)
+    requestsCmd.AddCommand(requestsAcceptCmd)
+    requestsCmd.AddCommand(requestsDeclineCmd)
+    requestsCmd.AddCommand(requestsEnableCmd)
+    requestsCmd.AddCommand(requestsDisableCmd)
+
+    // Command flags
+    requestsDeclineCmd.Flags().BoolVar(&declineBlock, "block", false, "also block the user")
+}
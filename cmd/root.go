@@ -1,12 +1,13 @@
 package cmd
 
 import (
-    "encoding/json"
     "errors"
     "fmt"
     "log"
     "os"
+    "path/filepath"
 
+    "github.com/CraigYanitski/mescli/internal/requests"
     "github.com/CraigYanitski/mescli/internal/tui"
     "github.com/CraigYanitski/mescli/internal/utils"
     "github.com/spf13/cobra"
@@ -31,6 +32,15 @@ var rootCmd = &cobra.Command{
 
     The primary feature of mescli is the asynchronous encryption that is 
     achieved using the Signal encryption protocol.`,
+    PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+        if err := loadTheme(); err != nil {
+            return err
+        }
+        if err := loadKeymap(); err != nil {
+            return err
+        }
+        return validateOutput()
+    },
     Run: func(cmd *cobra.Command, args []string) {
         // bubble tea interface
         p := tui.NewProgram(apiCfg)
@@ -40,6 +50,9 @@ var rootCmd = &cobra.Command{
         if err != nil {
             log.Fatal(err)
         }
+        // contacts see the user offline straight away, not after the
+        // server's timeout
+        tui.Offline()
 
         // run tests if selected
         if m, ok := m.(tui.Model); ok && m.Chosen == 3 {
@@ -51,6 +64,44 @@ var rootCmd = &cobra.Command{
     },
 }
 
+// loadTheme styles the output with the theme named in the config, reading
+// theme files relative to the config directory
+func loadTheme() error {
+    name := viper.GetString("theme")
+    path := filepath.Join(filepath.Dir(viper.ConfigFileUsed()), name)
+    if name != "" && !filepath.IsAbs(name) && fileExists(path) {
+        name = path
+    }
+    t, err := tui.LoadTheme(name)
+    if err != nil {
+        return err
+    }
+    tui.SetTheme(t)
+    return nil
+}
+
+// loadKeymap sets the TUI key bindings from the keymap config, a preset and
+// the actions remapped in each group
+func loadKeymap() error {
+    groups := make(map[string]map[string][]string)
+    for group := range viper.GetStringMap("keymap") {
+        if group != "preset" {
+            groups[group] = viper.GetStringMapStringSlice("keymap." + group)
+        }
+    }
+    k, err := tui.LoadKeymap(viper.GetString("keymap.preset"), groups)
+    if err != nil {
+        return fmt.Errorf("error in keymap config: %w", err)
+    }
+    tui.SetKeymap(k)
+    return nil
+}
+
+func fileExists(path string) bool {
+    _, err := os.Stat(path)
+    return err == nil
+}
+
 func Execute() error {
     err := rootCmd.Execute()
     if err != nil {
@@ -75,6 +126,13 @@ func init() {
     viper.SetDefault("identity_key", "")
     viper.SetDefault("signed_prekey", "")
     viper.SetDefault("signed_key", "")
+    viper.SetDefault("notifications", "none")
+    viper.SetDefault("typing_indicators", true)
+    viper.SetDefault("presence", false)
+    viper.SetDefault("presence_shared", false)
+    viper.SetDefault("editor_send", false)
+    viper.SetDefault("maths", true)
+    viper.SetDefault("theme", "auto")
     //viper.SetDefault("root_ratchet", nil)
     //viper.SetDefault("send_ratchets", nil)
     //viper.SetDefault("recv_ratchets", nil)
@@ -96,19 +154,16 @@ func init() {
         Email: viper.GetString("email"),
     }
 
-    messages := make(map[string][]utils.RawMessage)
-    if _, err = os.Stat(".messages"); err == nil {
-        msgBytes, err := os.ReadFile(".messages")
-        if err != nil {
-            log.Fatalln(err)
-        }
-        if err = json.Unmarshal(msgBytes, &messages); err != nil {
-            log.Fatalln(err)
-        }
-        //fmt.Println(messages)
+    messages, err := requests.ReadMessages()
+    if err != nil {
+        log.Fatalln(err)
     }
-    
     apiCfg.Messages = messages
+    contacts, err := requests.ReadContacts()
+    if err != nil {
+        log.Fatalln(err)
+    }
+    apiCfg.Contacts = contacts
 
     // check if client is initialised
     //c := client.Client{
@@ -121,5 +176,6 @@ func init() {
 
     // Command flags
     rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+    rootCmd.PersistentFlags().StringVarP(&output, "output", "o", outputTable, "output format: json, yaml or table")
 }
 
@@ -17,9 +17,8 @@ var getUserCmd = &cobra.Command{
     Long:  `Get user information from the server.
 
     The user email must be specified.
-    This prints the user's UUID.`,
+    This prints the user's UUID, or a user record with --output json or yaml.`,
     RunE: func(cmd *cobra.Command, args []string) error {
-        fmt.Println("This command is not yet completed...")
         var email string
         if len(args) !=1 {
             return errors.New("A user email must be specified to get their UUID")
@@ -30,8 +29,16 @@ var getUserCmd = &cobra.Command{
         if err != nil {
             return err
         }
-        fmt.Printf("User %s (%s) \n", u.ID.String(), email)
-        return nil
+        record := UserRecord{
+            ID: u.ID.String(),
+            Email: email,
+            Name: u.Name,
+            CreatedAt: &u.CreatedAt,
+            Messages: len(apiCfg.Messages[u.ID.String()]),
+        }
+        return render(record, func() {
+            fmt.Printf("User %s (%s) \n", u.ID.String(), email)
+        })
     },
 }
 
@@ -41,16 +48,19 @@ var viewUsersCmd = &cobra.Command{
     Long:  `View user information from local disk.
 
     The user email may be specified for specific information.
-    This prints each user's UUID, message statistics, and most recent message.`,
-    Run: func(cmd *cobra.Command, args []string) {
-        fmt.Println("This command is not yet completed...")
-        if len(apiCfg.Messages) == 0 {
-            fmt.Println("There are no messages to display")
-            return
-        }
-        for contact := range apiCfg.Messages {
-            fmt.Printf("%s\n", utils.SuccessStyle.Bold(true).Render(contact))
-        }
+    This prints each user's UUID, message statistics, and most recent message.
+    With --output json or yaml this prints a list of user records.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        records := newUserRecords(apiCfg.Messages)
+        return render(records, func() {
+            if len(records) == 0 {
+                fmt.Println("There are no messages to display")
+                return
+            }
+            for _, r := range records {
+                fmt.Printf("%s\n", utils.SuccessStyle.Bold(true).Render(r.ID))
+            }
+        })
     },
 }
 
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var interval time.Duration
+var hook string
+
+var watchCmd = &cobra.Command{
+    Use:     "watch",
+    Aliases: []string{"daemon"},
+    Short:   "Continuously fetch and print incoming messages",
+    Long:    `Continuously fetch and print incoming messages.
+
+    This polls the server for new messages, decrypts them and saves them
+    locally, printing each one as it arrives. Use --output json for one
+    JSON message record per line.
+    A hook command may be given with --hook or the "watch.hook" config key.
+    It is run through the shell for every message with the environment
+    variables MESCLI_SENDER, MESCLI_SENDER_ID, MESCLI_SENDER_EMAIL,
+    MESCLI_TIME and MESCLI_BODY set.
+    The watcher shuts down cleanly on SIGINT or SIGTERM.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        if interval <= 0 {
+            return fmt.Errorf("invalid interval %s", interval)
+        }
+        if !cmd.Flags().Changed("hook") {
+            hook = viper.GetString("watch.hook")
+        }
+        ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+        defer stop()
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            err := syncAndPrint(ctx)
+            if errors.Is(err, requests.ErrSessionExpired) {
+                return err
+            } else if err != nil {
+                fmt.Fprintln(os.Stderr, utils.ErrorStyle.Render("error fetching messages: "+err.Error()))
+            }
+            select {
+            case <-ctx.Done():
+                return nil
+            case <-ticker.C:
+            }
+        }
+    },
+}
+
+// fetch new messages, print them and run the hook for each one
+func syncAndPrint(ctx context.Context) error {
+    messages, err := requests.SyncMessages(apiCfg.Messages)
+    if err != nil {
+        return err
+    }
+    for _, m := range messages {
+        contact := m.SenderID.String()
+        c := lookupContact(contact)
+        record := newContactMessageRecord(
+            c,
+            contact,
+            utils.RawMessage{
+                Sender: utils.ContactType,
+                Message: m.Message,
+                Time: m.CreatedAt,
+            },
+        )
+        if err = renderLine(record); err != nil {
+            return err
+        }
+        if hook != "" {
+            if err = runHook(ctx, c, record); err != nil {
+                fmt.Fprintln(os.Stderr, utils.ErrorStyle.Render("error running hook: "+err.Error()))
+            }
+        }
+    }
+    return nil
+}
+
+func runHook(ctx context.Context, c requests.Contact, r MessageRecord) error {
+    hookCmd := exec.CommandContext(ctx, "sh", "-c", hook)
+    hookCmd.Env = append(
+        os.Environ(),
+        "MESCLI_SENDER="+c.DisplayName(),
+        "MESCLI_SENDER_ID="+r.Conversation,
+        "MESCLI_SENDER_EMAIL="+c.Email,
+        "MESCLI_TIME="+r.Timestamp.Format(time.RFC3339),
+        "MESCLI_BODY="+r.Body,
+    )
+    hookCmd.Stdout = os.Stderr
+    hookCmd.Stderr = os.Stderr
+    return hookCmd.Run()
+}
+
+func init() {
+    rootCmd.AddCommand(watchCmd)
+
+    // Command flags
+    watchCmd.Flags().DurationVar(&interval, "interval", 10*time.Second, "time between checks for new messages")
+    watchCmd.Flags().StringVar(&hook, "hook", "", "shell command to run for every message")
+}
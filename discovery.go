@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/CraigYanitski/mescli/internal/auth"
+	crypt "github.com/CraigYanitski/mescli/internal/cryptography"
+	"github.com/CraigYanitski/mescli/internal/database"
+	"github.com/google/uuid"
+)
+
+// maximum number of identifiers in one discovery request
+const discoveryBatchSize = 500
+
+type DiscoveryRequest struct {
+    Identifiers  []string  `json:"identifiers"`
+}
+type DiscoveredUser struct {
+    Identifier   string     `json:"identifier"`
+    ID           uuid.UUID  `json:"id"`
+    Fingerprint  string     `json:"fingerprint"`
+}
+
+func (cfg *apiConfig) handleDiscoverUsers(w http.ResponseWriter, r *http.Request) {
+    // check authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "unauthorised", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+
+    // unmarshal POST JSON
+    decoder := json.NewDecoder(r.Body)
+    d := &DiscoveryRequest{}
+    err = decoder.Decode(d)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "error decoding request", err)
+        return
+    }
+    if len(d.Identifiers) == 0 {
+        respondWithJSON(w, http.StatusOK, []DiscoveredUser{})
+        return
+    } else if len(d.Identifiers) > discoveryBatchSize {
+        respondWithError(
+            w, 
+            http.StatusRequestEntityTooLarge, 
+            fmt.Sprintf("at most %d identifiers per request", discoveryBatchSize), 
+            nil,
+        )
+        return
+    }
+    for _, identifier := range d.Identifiers {
+        if len(identifier) != crypt.DiscoveryPrefixLength {
+            respondWithError(
+                w, 
+                http.StatusBadRequest, 
+                fmt.Sprintf("identifiers must be %d character hash prefixes", crypt.DiscoveryPrefixLength), 
+                nil,
+            )
+            return
+        }
+    }
+
+    // every identifier counts against the account limit
+    if !cfg.allowLookups(w, id, len(d.Identifiers)) {
+        return
+    }
+
+    users, err := cfg.dbQueries.DiscoverUsers(
+        r.Context(), 
+        database.DiscoverUsersParams{Prefixes: d.Identifiers, RequesterID: id},
+    )
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error searching users database", err)
+        return
+    }
+
+    // only reveal the UUID and identity key fingerprint of matches
+    discovered := []DiscoveredUser{}
+    for _, user := range users {
+        identityKey := crypt.DecodeECDSAPublicKey(user.IdentityKey)
+        if user.ID == id || identityKey == nil {
+            continue
+        }
+        discovered = append(discovered, DiscoveredUser{
+            Identifier: user.HashPrefix,
+            ID: user.ID,
+            Fingerprint: crypt.Fingerprint(identityKey),
+        })
+    }
+
+    respondWithJSON(w, http.StatusOK, discovered)
+}
+
+// allowLookups applies the per-account lookup limit, responding with 429 if
+// it is exceeded
+func (cfg *apiConfig) allowLookups(w http.ResponseWriter, id uuid.UUID, n int) bool {
+    ok, wait := cfg.lookups.allow(id, n)
+    if !ok {
+        w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+        respondWithError(w, http.StatusTooManyRequests, "lookup limit reached", nil)
+    }
+    return ok
+}
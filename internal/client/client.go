@@ -7,8 +7,8 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
 	"slices"
 
 	crypt "github.com/CraigYanitski/mescli/internal/cryptography"
@@ -17,6 +17,10 @@ import (
 	"golang.org/x/crypto/hkdf"
 )
 
+// MaxSkip is the number of lost messages a received message may follow and
+// still be decrypted
+const MaxSkip = 100
+
 type Client struct {
     Name           string
     password       string
@@ -132,24 +136,26 @@ func (c *Client) EphemeralKey() (*ecdh.PublicKey) {
     return c.ephemeralKey.PublicKey()
 }
 
-func (c *Client) InitiateX3DH(contact *PrekeyPacketJSON, contactID uuid.UUID, test bool) *MessagePacketJSON {
+func (c *Client) InitiateX3DH(contact *PrekeyPacketJSON, contactID uuid.UUID, test bool) (*MessagePacketJSON, error) {
     // get recipient identity public keys
-    rIKdsa, rSPK, rSK, rOK := ParsePrekeyPacket(contact)
+    rIKdsa, rSPK, rSK, rOK, err := ParsePrekeyPacket(contact)
+    if err != nil {
+        return nil, err
+    }
     rIK, err := rIKdsa.ECDH()
     if err != nil {
-        log.Fatal(err)
+        return nil, err
     }
-    log.Println("parsed prekey packet")
 
     // verify signed prekey
     if !ecdsa.VerifyASN1(rIKdsa, encodeKey(rSPK), rSK) {
-        log.Fatal("error verifying signed key during X3DH")
+        return nil, errors.New("error verifying signed key during X3DH")
     }
 
     // generate ephemeral key
     ek, err := generateECDH()
     if err != nil {
-        log.Fatal(err)
+        return nil, err
     }
     c.ephemeralKey = ek
 
@@ -159,19 +165,19 @@ func (c *Client) InitiateX3DH(contact *PrekeyPacketJSON, contactID uuid.UUID, te
     // calculate four DH secrets
     dh1, err := iK.ECDH(rSPK)
     if err != nil {
-        log.Fatal(err)
+        return nil, err
     }
     dh2, err := c.ephemeralKey.ECDH(rIK)
     if err != nil {
-        log.Fatal(err)
+        return nil, err
     }
     dh3, err := c.ephemeralKey.ECDH(rSPK)
     if err != nil {
-        log.Fatal(err)
+        return nil, err
     }
     dh4, err := c.ephemeralKey.ECDH(rOK)
     if err != nil {
-        log.Fatal(err)
+        return nil, err
     }
 
     // calculate secret key
@@ -179,73 +185,56 @@ func (c *Client) InitiateX3DH(contact *PrekeyPacketJSON, contactID uuid.UUID, te
     secret := make([]byte, 32)
     _, err = hkdf.New(sha256.New, concat, nil, nil).Read(secret)
     if err != nil {
-        log.Fatal(err)
+        return nil, err
     }
 
     // save secret
     c.secret = secret
 
-    // check if ratchets are in config
-    if rr := viper.GetString("root_ratchet"); rr == "" || test {
-        // initialise root ratchet
-        c.rootRatchet = &crypt.Ratchet{}
-        c.rootRatchet.NewKDF(secret, nil, nil)
+    // a new key exchange always starts a new session
+    c.rootRatchet = &crypt.Ratchet{}
+    c.rootRatchet.NewKDF(secret, nil, nil)
 
-        // initialise sending ratchet
-        sendSecret, _, err := c.rootRatchet.Extract(nil, nil, nil)
-        if err != nil {
-            log.Fatal(err)
-        }
-        c.sendRatchet = &crypt.Ratchet{}
-        c.sendRatchet.NewKDF(sendSecret, nil, nil)
+    // initialise sending ratchet
+    sendSecret, _, err := c.rootRatchet.Extract(nil, nil, nil)
+    if err != nil {
+        return nil, err
+    }
+    c.sendRatchet = &crypt.Ratchet{}
+    c.sendRatchet.NewKDF(sendSecret, nil, nil)
 
-        // initialise receiving ratchet
-        recvSecret, _, err := c.rootRatchet.Extract(nil, nil, nil)
-        if err != nil {
-            log.Fatal(err)
-        }
-        c.recvRatchet = &crypt.Ratchet{}
-        c.recvRatchet.NewKDF(recvSecret, nil, nil)
-    } else {
-        // Make salt
-        salt := make([]byte, len(secret))
-        for i := 0; i < len(secret); i++ {
-            salt[i] = secret[i] ^ 0xff
-        }
-        // get ratchets from config
-        c.rootRatchet = crypt.DecodeRatchet(viper.GetString("contacts."+contactID.String()+".root_ratchet"), nil, nil)
-        c.sendRatchet = crypt.DecodeRatchet(viper.GetString("contacts."+contactID.String()+".send_ratchet"), salt, nil)
-        c.recvRatchet = crypt.DecodeRatchet(viper.GetString("contacts."+contactID.String()+".recv_ratchet"), salt, nil)
+    // initialise receiving ratchet
+    recvSecret, _, err := c.rootRatchet.Extract(nil, nil, nil)
+    if err != nil {
+        return nil, err
     }
+    c.recvRatchet = &crypt.Ratchet{}
+    c.recvRatchet.NewKDF(recvSecret, nil, nil)
 
     packet, err := c.SendMessagePacketJSON()
     if err != nil {
-        log.Fatal(err)
+        return nil, err
     }
     
-    // save ratchets in config
+    // save session in config
     if !test {
-        // save secret 
-        viper.Set("contacts."+contactID.String()+".secret", c.secret)
-        // save ratchets
-        viper.Set("contacts."+contactID.String()+".root_ratchet", c.rootRatchet.EncodeRatchet())
-        viper.Set("contacts."+contactID.String()+".send_ratchets", c.sendRatchet.EncodeRatchet())
-        viper.Set("contacts."+contactID.String()+".recv_ratchets", c.recvRatchet.EncodeRatchet())
-        // write config
-        err = viper.WriteConfig()
+        err = c.saveSession(contactID)
         if err != nil {
-            log.Fatal(err)
+            return nil, err
         }
     }
-    return packet
+    return packet, nil
 }
 
 func (c *Client) CompleteX3DH(contact *MessagePacketJSON, contactID uuid.UUID, test bool) error {
     // get sender public keys
-    sIKdsa, sEK := ParseMessagePacket(contact)
+    sIKdsa, sEK, err := ParseMessagePacket(contact)
+    if err != nil {
+        return err
+    }
     sIK, err := sIKdsa.ECDH()
     if err != nil {
-        log.Fatal(err)
+        return err
     }
 
     // get private ECDH key
@@ -277,60 +266,67 @@ func (c *Client) CompleteX3DH(contact *MessagePacketJSON, contactID uuid.UUID, t
         return err
     }
 
-    // save secret and return
+    // save secret
     c.secret = secret
-    viper.Set("contacts."+contactID.String()+".secret", secret)
 
-    // check if ratchets are in config
-    if rr := viper.GetString("root_ratchet"); rr == "" || test {
-        // initialise root ratchet
-        c.rootRatchet = &crypt.Ratchet{}
-        c.rootRatchet.NewKDF(secret, nil, nil)
+    // a new key exchange always starts a new session
+    c.rootRatchet = &crypt.Ratchet{}
+    c.rootRatchet.NewKDF(secret, nil, nil)
 
-        // initialise receiving ratchet
-        recvSecret, _, err := c.rootRatchet.Extract(nil, nil, nil)
-        if err != nil {
-            return err
-        }
-        c.recvRatchet = &crypt.Ratchet{}
-        c.recvRatchet.NewKDF(recvSecret, nil, nil)
+    // initialise receiving ratchet
+    recvSecret, _, err := c.rootRatchet.Extract(nil, nil, nil)
+    if err != nil {
+        return err
+    }
+    c.recvRatchet = &crypt.Ratchet{}
+    c.recvRatchet.NewKDF(recvSecret, nil, nil)
 
-        // initialise sending ratchet
-        sendSecret, _, err := c.rootRatchet.Extract(nil, nil, nil)
-        if err != nil {
-            return err
-        }
-        c.sendRatchet = &crypt.Ratchet{}
-        c.sendRatchet.NewKDF(sendSecret, nil, nil)
-    } else {
-        // Make salt
-        salt := make([]byte, len(secret))
-        for i := 0; i < len(secret); i++ {
-            salt[i] = secret[i] ^ 0xff
-        }
-        // get ratchets from config
-        c.rootRatchet = crypt.DecodeRatchet(viper.GetString("root_ratchet"), nil, nil)
-        c.sendRatchet = crypt.DecodeRatchet(viper.GetString("send_ratchet"), salt, nil)
-        c.recvRatchet = crypt.DecodeRatchet(viper.GetString("recv_ratchet"), salt, nil)
+    // initialise sending ratchet
+    sendSecret, _, err := c.rootRatchet.Extract(nil, nil, nil)
+    if err != nil {
+        return err
     }
-    
-    // save ratchets in config
+    c.sendRatchet = &crypt.Ratchet{}
+    c.sendRatchet.NewKDF(sendSecret, nil, nil)
+
+    // save session in config
     if !test {
-        viper.Set("contacts."+contactID.String()+".secret", c.secret)
-        // save ratchets
-        viper.Set("contacts."+contactID.String()+".root_ratchet" , c.rootRatchet.EncodeRatchet())
-        viper.Set("contacts."+contactID.String()+".recv_ratchets", c.recvRatchet.EncodeRatchet())
-        viper.Set("contacts."+contactID.String()+".send_ratchets", c.sendRatchet.EncodeRatchet())
-        // write config
-        err = viper.WriteConfig()
-        if err != nil {
-            log.Fatal(err)
-        }
+        return c.saveSession(contactID)
     }
 
     return nil
 }
 
+// LoadSession restores the ratchets of an established session with a contact,
+// returning false if there is none.
+func (c *Client) LoadSession(contactID uuid.UUID) bool {
+    key := "contacts."+contactID.String()
+    rr := viper.GetString(key+".root_ratchet")
+    sr := viper.GetString(key+".send_ratchet")
+    vr := viper.GetString(key+".recv_ratchet")
+    if rr == "" || sr == "" || vr == "" {
+        return false
+    }
+    c.rootRatchet = crypt.DecodeRatchet(rr, nil, nil)
+    c.sendRatchet = crypt.DecodeRatchet(sr, nil, nil)
+    c.recvRatchet = crypt.DecodeRatchet(vr, nil, nil)
+    return c.rootRatchet != nil && c.sendRatchet != nil && c.recvRatchet != nil
+}
+
+// HasSession reports whether a session with the contact is saved in config.
+func HasSession(contactID uuid.UUID) bool {
+    c := Client{}
+    return c.LoadSession(contactID)
+}
+
+func (c *Client) saveSession(contactID uuid.UUID) error {
+    key := "contacts."+contactID.String()
+    viper.Set(key+".root_ratchet", c.rootRatchet.EncodeRatchet())
+    viper.Set(key+".send_ratchet", c.sendRatchet.EncodeRatchet())
+    viper.Set(key+".recv_ratchet", c.recvRatchet.EncodeRatchet())
+    return viper.WriteConfig()
+}
+
 func (c *Client) CheckSecretEqual(contact *Client) bool {
     return bytes.Equal(c.secret, contact.secret)
 }
@@ -385,6 +381,9 @@ func (c *Client) SendMessage(plaintext string, pubkey *ecdsa.PublicKey, contactI
     }
 
     // Generate key and iv
+    if c.sendRatchet == nil {
+        return "", fmt.Errorf("no session with contact %s", contactID)
+    }
     sendKey, iv, err := c.sendRatchet.Extract(secret, salt, nil)
     if err != nil {
         return "", err
@@ -397,17 +396,17 @@ func (c *Client) SendMessage(plaintext string, pubkey *ecdsa.PublicKey, contactI
         return "", err
     }
 
-    // save sending key if not test
-    if !test {
-        // save ratchet
-        viper.Set("send_ratchet."+contactID.String()+".key", c.sendRatchet.EncodeRatchet())
-        // write config
-        viper.WriteConfig()
-    }
-
+    // the ratchet is saved with SaveSendRatchet once the message is delivered,
+    // so a failed send leaves both sides in step
     return hex.EncodeToString(ciphertext), nil
 }
 
+// SaveSendRatchet saves the sending ratchet after a message has been delivered.
+func (c *Client) SaveSendRatchet(contactID uuid.UUID) error {
+    viper.Set("contacts."+contactID.String()+".send_ratchet", c.sendRatchet.EncodeRatchet())
+    return viper.WriteConfig()
+}
+
 func (c *Client) ReceiveMessage(ciphertext string, pubkey *ecdsa.PublicKey, contactID uuid.UUID, test bool) (string, error) {
     // Renew Diffie-Hellman key
     key := c.identityECDH()
@@ -429,26 +428,37 @@ func (c *Client) ReceiveMessage(ciphertext string, pubkey *ecdsa.PublicKey, cont
     }
 
     // Generate key and iv
-    recvKey, iv, err := c.recvRatchet.Extract(secret, salt, nil)
-    if err != nil {
-        return "", err
+    if c.recvRatchet == nil {
+        return "", fmt.Errorf("no session with contact %s", contactID)
     }
-
-    // Decrypt message
     ciphertextBytes, err := hex.DecodeString(ciphertext)
     if err != nil {
         return "", err
     }
-    plaintext, err := crypt.DecryptMessage(recvKey, ciphertextBytes, iv)
-    if err != nil {
-        err = fmt.Errorf("error decrypting message: %v", err)
-        return "", err
+
+    // Decrypt message, skipping ahead over messages that never arrived, such
+    // as those in a declined message request
+    ratchet := crypt.DecodeRatchet(c.recvRatchet.EncodeRatchet(), nil, nil)
+    var plaintext []byte
+    for skip := 0; ; skip++ {
+        recvKey, iv, err := ratchet.Extract(secret, salt, nil)
+        if err != nil {
+            return "", err
+        }
+        plaintext, err = crypt.DecryptMessage(recvKey, ciphertextBytes, iv)
+        if err == nil {
+            break
+        }
+        if skip == MaxSkip {
+            return "", fmt.Errorf("error decrypting message: %v", err)
+        }
     }
+    c.recvRatchet = ratchet
 
-    // save sending key if not test
+    // save receiving key if not test
     if !test {
         // save ratchet
-        viper.Set("recv_ratchet."+contactID.String()+".key", c.recvRatchet.EncodeRatchet())
+        viper.Set("contacts."+contactID.String()+".recv_ratchet", c.recvRatchet.EncodeRatchet())
         // write config
         viper.WriteConfig()
     }
@@ -4,9 +4,7 @@ import (
 	"crypto/ecdh"
 	"crypto/ecdsa"
 	"encoding/hex"
-
-	//"fmt"
-	"log"
+	"errors"
 
 	crypt "github.com/CraigYanitski/mescli/internal/cryptography"
 )
@@ -98,20 +96,26 @@ func (c Client) SendMessagePacketJSON() (*MessagePacketJSON, error) {
     }, nil
 }
 
-func ParsePrekeyPacket(packet *PrekeyPacketJSON) (*ecdsa.PublicKey, *ecdh.PublicKey, []byte, *ecdh.PublicKey) {
+func ParsePrekeyPacket(packet *PrekeyPacketJSON) (*ecdsa.PublicKey, *ecdh.PublicKey, []byte, *ecdh.PublicKey, error) {
     rIKdsa := crypt.DecodeECDSAPublicKey(packet.IdentityKey)
     rSPK := crypt.DecodeECDHPublicKey(packet.SignedPrekey)
     rOK := crypt.DecodeECDHPublicKey(packet.OnetimePrekey)
+    if rIKdsa == nil || rSPK == nil || rOK == nil {
+        return nil, nil, nil, nil, errors.New("error decoding prekey packet")
+    }
     rSK, err := hex.DecodeString(packet.SignedKey)
     if err != nil {
-        log.Fatal(err)
+        return nil, nil, nil, nil, err
     }
-    return rIKdsa, rSPK, rSK, rOK
+    return rIKdsa, rSPK, rSK, rOK, nil
 }
 
-func ParseMessagePacket(packet *MessagePacketJSON) (*ecdsa.PublicKey, *ecdh.PublicKey) {
+func ParseMessagePacket(packet *MessagePacketJSON) (*ecdsa.PublicKey, *ecdh.PublicKey, error) {
     rIKdsa := crypt.DecodeECDSAPublicKey(packet.IdentityKey)
     rEPK := crypt.DecodeECDHPublicKey(packet.EphemeralKey)
-    return rIKdsa, rEPK
+    if rIKdsa == nil || rEPK == nil {
+        return nil, nil, errors.New("error decoding message packet")
+    }
+    return rIKdsa, rEPK, nil
 }
 
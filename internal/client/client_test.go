@@ -183,7 +183,7 @@ func TestX3DH(t *testing.T) {
             t.Errorf("error sending client 1 message packet: %v", err)
         }
 
-        clientOnePacket := clientOne.InitiateX3DH(clientTwoPacket, uuid.UUID{}, true)
+        clientOnePacket, err := clientOne.InitiateX3DH(clientTwoPacket, uuid.UUID{}, true)
         if err != nil {
             t.Errorf("error for %v initiating X3DH with %v: %v", clientOne.Name, clientTwo.Name, err)
         }
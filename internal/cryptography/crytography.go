@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"strings"
 
 	//"log"
 
@@ -23,6 +24,9 @@ import (
 
 const (
     NonceSize = 15
+    // long enough that a prefix matches a single address, too long to walk
+    // every prefix, must match the index in sql/schema
+    DiscoveryPrefixLength = 16
 )
 
 func HashPassword(password string) (string, error) {
@@ -274,4 +278,34 @@ func DecodeECDHPrivateKey(code string) *ecdh.PrivateKey {
     return key
 }
 
+// Fingerprint returns a short human-comparable digest of an identity key.
+func Fingerprint(key *ecdsa.PublicKey) string {
+    keyBytes, err := x509.MarshalPKIXPublicKey(key)
+    if err != nil {
+        log.Println(err)
+        return ""
+    }
+    digest := sha256.Sum256(keyBytes)
+    code := strings.ToUpper(hex.EncodeToString(digest[:20]))
+    groups := []string{}
+    for i := 0; i < len(code); i += 4 {
+        groups = append(groups, code[i:i+4])
+    }
+    return strings.Join(groups, " ")
+}
+
+// HashIdentifier hashes a normalised email address for contact discovery, so
+// the server can match contacts without being sent the address itself.
+func HashIdentifier(email string) string {
+    digest := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+    return hex.EncodeToString(digest[:])
+}
 
+// TruncateIdentifier shortens an identifier hash to the prefix sent for
+// discovery, so the full hash never leaves the client.
+func TruncateIdentifier(hash string) string {
+    if len(hash) < DiscoveryPrefixLength {
+        return hash
+    }
+    return hash[:DiscoveryPrefixLength]
+}
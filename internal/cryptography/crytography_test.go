@@ -217,3 +217,38 @@ Actual:    %v
     //fmt.Printf("%d passed, %d failed\n", passCount, failCount)
 }
 
+func TestFingerprint(t *testing.T) {
+    key, err := cryptography.GenerateECDSA()
+    if err != nil {
+        t.Fatalf("error generating key: %v", err)
+    }
+    other, err := cryptography.GenerateECDSA()
+    if err != nil {
+        t.Fatalf("error generating key: %v", err)
+    }
+
+    fingerprint := cryptography.Fingerprint(&key.PublicKey)
+    if len(fingerprint) != 49 {
+        t.Errorf("expected 10 groups of 4 characters, got %q", fingerprint)
+    }
+    if fingerprint != cryptography.Fingerprint(&key.PublicKey) {
+        t.Error("fingerprint of the same key differs")
+    }
+    if fingerprint == cryptography.Fingerprint(&other.PublicKey) {
+        t.Error("fingerprints of different keys match")
+    }
+}
+
+func TestHashIdentifier(t *testing.T) {
+    hash := cryptography.HashIdentifier("Alice@Example.com ")
+    if hash != cryptography.HashIdentifier("alice@example.com") {
+        t.Error("identifier hash depends on case or whitespace")
+    }
+    if hash == cryptography.HashIdentifier("bob@example.com") {
+        t.Error("hashes of different identifiers match")
+    }
+    prefix := cryptography.TruncateIdentifier(hash)
+    if len(prefix) != cryptography.DiscoveryPrefixLength || hash[:len(prefix)] != prefix {
+        t.Errorf("expected %d character prefix of %q, got %q", cryptography.DiscoveryPrefixLength, hash, prefix)
+    }
+}
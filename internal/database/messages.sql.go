@@ -21,7 +21,8 @@ INSERT INTO messages (
     sender_id,
     sender_identity_key,
     sender_ephemeral_key,
-    message
+    message,
+    pending
 ) VALUES(
     gen_random_uuid(),
     NOW(),
@@ -30,8 +31,9 @@ INSERT INTO messages (
     $2,
     $4,
     $5,
-    $3
-) RETURNING id, created_at, updated_at, user_id, sender_id, sender_identity_key, sender_ephemeral_key, message
+    $3,
+    $6
+) RETURNING id, created_at, updated_at, user_id, sender_id, sender_identity_key, sender_ephemeral_key, message, pending
 `
 
 type CreateMessageParams struct {
@@ -40,6 +42,7 @@ type CreateMessageParams struct {
 	Message            string
 	SenderIdentityKey  sql.NullString
 	SenderEphemeralKey sql.NullString
+	Pending            bool
 }
 
 func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
@@ -49,6 +52,7 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		arg.Message,
 		arg.SenderIdentityKey,
 		arg.SenderEphemeralKey,
+		arg.Pending,
 	)
 	var i Message
 	err := row.Scan(
@@ -60,6 +64,7 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		&i.SenderIdentityKey,
 		&i.SenderEphemeralKey,
 		&i.Message,
+		&i.Pending,
 	)
 	return i, err
 }
@@ -67,7 +72,7 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 const deleteMessage = `-- name: DeleteMessage :one
 DELETE FROM messages 
 WHERE id = $1 
-RETURNING id, created_at, updated_at, user_id, sender_id, sender_identity_key, sender_ephemeral_key, message
+RETURNING id, created_at, updated_at, user_id, sender_id, sender_identity_key, sender_ephemeral_key, message, pending
 `
 
 func (q *Queries) DeleteMessage(ctx context.Context, id uuid.UUID) (Message, error) {
@@ -82,13 +87,44 @@ func (q *Queries) DeleteMessage(ctx context.Context, id uuid.UUID) (Message, err
 		&i.SenderIdentityKey,
 		&i.SenderEphemeralKey,
 		&i.Message,
+		&i.Pending,
 	)
 	return i, err
 }
 
+const deleteMessagesFromSender = `-- name: DeleteMessagesFromSender :exec
+DELETE FROM messages 
+WHERE user_id = $1 AND sender_id = $2
+`
+
+type DeleteMessagesFromSenderParams struct {
+	UserID   uuid.UUID
+	SenderID uuid.UUID
+}
+
+func (q *Queries) DeleteMessagesFromSender(ctx context.Context, arg DeleteMessagesFromSenderParams) error {
+	_, err := q.db.ExecContext(ctx, deleteMessagesFromSender, arg.UserID, arg.SenderID)
+	return err
+}
+
+const deletePendingMessages = `-- name: DeletePendingMessages :exec
+DELETE FROM messages 
+WHERE user_id = $1 AND sender_id = $2 AND pending
+`
+
+type DeletePendingMessagesParams struct {
+	UserID   uuid.UUID
+	SenderID uuid.UUID
+}
+
+func (q *Queries) DeletePendingMessages(ctx context.Context, arg DeletePendingMessagesParams) error {
+	_, err := q.db.ExecContext(ctx, deletePendingMessages, arg.UserID, arg.SenderID)
+	return err
+}
+
 const getMessages = `-- name: GetMessages :many
-SELECT id, created_at, updated_at, user_id, sender_id, sender_identity_key, sender_ephemeral_key, message FROM messages 
-WHERE user_id = $1 
+SELECT id, created_at, updated_at, user_id, sender_id, sender_identity_key, sender_ephemeral_key, message, pending FROM messages 
+WHERE user_id = $1 AND NOT pending 
 ORDER BY created_at
 `
 
@@ -110,6 +146,7 @@ func (q *Queries) GetMessages(ctx context.Context, userID uuid.UUID) ([]Message,
 			&i.SenderIdentityKey,
 			&i.SenderEphemeralKey,
 			&i.Message,
+			&i.Pending,
 		); err != nil {
 			return nil, err
 		}
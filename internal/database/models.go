@@ -11,6 +11,18 @@ import (
 	"github.com/google/uuid"
 )
 
+type AcceptedContact struct {
+	UserID    uuid.UUID
+	ContactID uuid.UUID
+	CreatedAt time.Time
+}
+
+type Block struct {
+	UserID    uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
 type CryptoKey struct {
 	IdentityKey   string
 	CreatedAt     time.Time
@@ -30,6 +42,7 @@ type Message struct {
 	SenderIdentityKey  sql.NullString
 	SenderEphemeralKey sql.NullString
 	Message            string
+	Pending            bool
 }
 
 type RefreshToken struct {
@@ -42,11 +55,13 @@ type RefreshToken struct {
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	Name           string
-	HashedPassword string
-	Initialised    bool
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	Name            string
+	HashedPassword  string
+	Initialised     bool
+	MessageRequests bool
+	EmailHash       string
 }
@@ -9,6 +9,7 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -19,7 +20,8 @@ INSERT INTO users (
     email, 
     name,
     hashed_password, 
-    initialised
+    initialised,
+    email_hash
 ) VALUES (
     gen_random_uuid(), 
     NOW(), 
@@ -27,18 +29,25 @@ INSERT INTO users (
     $1,
     $2,
     $3,
-    false
-) RETURNING id, created_at, updated_at, email, name, hashed_password, initialised
+    false,
+    $4
+) RETURNING id, created_at, updated_at, email, name, hashed_password, initialised, message_requests, email_hash
 `
 
 type CreateUserParams struct {
 	Email          string
 	Name           string
 	HashedPassword string
+	EmailHash      string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.Name, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser,
+		arg.Email,
+		arg.Name,
+		arg.HashedPassword,
+		arg.EmailHash,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -48,12 +57,72 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Name,
 		&i.HashedPassword,
 		&i.Initialised,
+		&i.MessageRequests,
+		&i.EmailHash,
 	)
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users 
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const discoverUsers = `-- name: DiscoverUsers :many
+SELECT 
+    LEFT(users.email_hash, 16)::text AS hash_prefix, 
+    users.id, 
+    crypto_keys.identity_key
+FROM users 
+JOIN crypto_keys ON crypto_keys.user_id = users.id 
+WHERE LEFT(users.email_hash, 16) = ANY($1::text[]) 
+AND NOT EXISTS (
+    SELECT 1 FROM blocks 
+    WHERE blocks.user_id = users.id AND blocks.blocked_id = $2
+)
+`
+
+type DiscoverUsersParams struct {
+	Prefixes    []string
+	RequesterID uuid.UUID
+}
+
+type DiscoverUsersRow struct {
+	HashPrefix  string
+	ID          uuid.UUID
+	IdentityKey string
+}
+
+func (q *Queries) DiscoverUsers(ctx context.Context, arg DiscoverUsersParams) ([]DiscoverUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, discoverUsers, pq.Array(arg.Prefixes), arg.RequesterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DiscoverUsersRow
+	for rows.Next() {
+		var i DiscoverUsersRow
+		if err := rows.Scan(&i.HashPrefix, &i.ID, &i.IdentityKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, email, name, hashed_password, initialised FROM users 
+SELECT id, created_at, updated_at, email, name, hashed_password, initialised, message_requests, email_hash FROM users 
 WHERE id = $1
 `
 
@@ -68,12 +137,14 @@ func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Name,
 		&i.HashedPassword,
 		&i.Initialised,
+		&i.MessageRequests,
+		&i.EmailHash,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, name, hashed_password, initialised FROM users 
+SELECT id, created_at, updated_at, email, name, hashed_password, initialised, message_requests, email_hash FROM users 
 WHERE email = $1
 `
 
@@ -88,6 +159,38 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Name,
 		&i.HashedPassword,
 		&i.Initialised,
+		&i.MessageRequests,
+		&i.EmailHash,
+	)
+	return i, err
+}
+
+const updateMessageRequests = `-- name: UpdateMessageRequests :one
+UPDATE users 
+SET updated_at = NOW(),
+    message_requests = $2
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, name, hashed_password, initialised, message_requests, email_hash
+`
+
+type UpdateMessageRequestsParams struct {
+	ID              uuid.UUID
+	MessageRequests bool
+}
+
+func (q *Queries) UpdateMessageRequests(ctx context.Context, arg UpdateMessageRequestsParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateMessageRequests, arg.ID, arg.MessageRequests)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Name,
+		&i.HashedPassword,
+		&i.Initialised,
+		&i.MessageRequests,
+		&i.EmailHash,
 	)
 	return i, err
 }
@@ -98,9 +201,10 @@ SET updated_at = NOW(),
     email = $2,
     name = $3,
     hashed_password = $4,
-    initialised = $5
+    initialised = $5,
+    email_hash = $6
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, name, hashed_password, initialised
+RETURNING id, created_at, updated_at, email, name, hashed_password, initialised, message_requests, email_hash
 `
 
 type UpdateUserParams struct {
@@ -109,6 +213,7 @@ type UpdateUserParams struct {
 	Name           string
 	HashedPassword string
 	Initialised    bool
+	EmailHash      string
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
@@ -118,6 +223,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		arg.Name,
 		arg.HashedPassword,
 		arg.Initialised,
+		arg.EmailHash,
 	)
 	var i User
 	err := row.Scan(
@@ -128,6 +234,8 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Name,
 		&i.HashedPassword,
 		&i.Initialised,
+		&i.MessageRequests,
+		&i.EmailHash,
 	)
 	return i, err
 }
@@ -0,0 +1,284 @@
+package maths
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+    KindInlineMath  = ast.NewNodeKind("InlineMath")
+    KindMathBlock   = ast.NewNodeKind("MathBlock")
+)
+
+// InlineMath is maths within text, between $ or $$.
+type InlineMath struct {
+    ast.BaseInline
+    // offsets of the maths in the source, with its dollars
+    Start, Stop  int
+    Display      bool
+}
+
+func (n *InlineMath) Kind() ast.NodeKind {
+    return KindInlineMath
+}
+
+func (n *InlineMath) Dump(source []byte, level int) {
+    ast.DumpHelper(n, source, level, map[string]string{"Tex": n.Tex(source)}, nil)
+}
+
+// Tex is the LaTeX between the dollars.
+func (n *InlineMath) Tex(source []byte) string {
+    dollars := 1
+    if n.Display {
+        dollars = 2
+    }
+    return string(source[n.Start+dollars : n.Stop-dollars])
+}
+
+// MathBlock is display maths starting a line with $$, ending with $$ on the
+// same or a later line.
+type MathBlock struct {
+    ast.BaseBlock
+    // offsets of the maths in the source, with its dollars
+    Start, Stop  int
+    closed       bool
+}
+
+func (n *MathBlock) Kind() ast.NodeKind {
+    return KindMathBlock
+}
+
+func (n *MathBlock) IsRaw() bool {
+    return true
+}
+
+func (n *MathBlock) Dump(source []byte, level int) {
+    ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// Tex is the LaTeX between the dollars.
+func (n *MathBlock) Tex(source []byte) string {
+    var b strings.Builder
+    for i := 0; i < n.Lines().Len(); i++ {
+        line := n.Lines().At(i)
+        b.Write(line.Value(source))
+    }
+    return b.String()
+}
+
+type inlineParser struct{}
+
+func (p inlineParser) Trigger() []byte {
+    return []byte{'$'}
+}
+
+// Parse reads maths as pandoc does: the opening $ must not be followed by a
+// space, and the closing $ must not follow a space or be followed by a digit,
+// so prices are left alone
+func (p inlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+    line, segment := block.PeekLine()
+    if bytes.HasPrefix(line, []byte("$$")) {
+        end := bytes.Index(line[2:], []byte("$$"))
+        if end <= 0 {
+            return nil
+        }
+        block.Advance(end + 4)
+        return &InlineMath{Start: segment.Start, Stop: segment.Start + end + 4, Display: true}
+    }
+    if len(line) < 2 || util.IsSpace(line[1]) {
+        return nil
+    }
+    for i := 1; i < len(line); i++ {
+        switch {
+        case line[i] == '\\':
+            i++
+        case line[i] == '$' && i > 1 && !util.IsSpace(line[i-1]) && (i+1 == len(line) || !util.IsNumeric(line[i+1])):
+            block.Advance(i + 1)
+            return &InlineMath{Start: segment.Start, Stop: segment.Start + i + 1}
+        }
+    }
+    return nil
+}
+
+type blockParser struct{}
+
+func (p blockParser) Trigger() []byte {
+    return []byte{'$'}
+}
+
+// closing finds the first $$ on a line of display maths, and whether the
+// line ends with it
+func closing(line []byte) (int, bool) {
+    end := bytes.Index(line, []byte("$$"))
+    if end < 0 {
+        return -1, false
+    }
+    return end, util.IsBlank(line[end+2:])
+}
+
+func (p blockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+    line, segment := reader.PeekLine()
+    pos := pc.BlockOffset()
+    if pos < 0 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+        return nil, parser.NoChildren
+    }
+    start := segment.Start - segment.Padding + pos
+    node := &MathBlock{Start: start}
+    end, last := closing(line[pos+2:])
+    switch {
+    case end == 0:
+        return nil, parser.NoChildren
+    case end > 0 && !last:
+        // maths with text after it is inline
+        return nil, parser.NoChildren
+    case end > 0:
+        node.Lines().Append(text.NewSegment(start+2, start+2+end))
+        node.Stop = start + end + 4
+        node.closed = true
+    default:
+        node.Lines().Append(text.NewSegment(start+2, segment.Stop))
+    }
+    reader.Advance(segment.Len() - 1)
+    return node, parser.NoChildren
+}
+
+func (p blockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+    n := node.(*MathBlock)
+    if n.closed {
+        return parser.Close
+    }
+    line, segment := reader.PeekLine()
+    if line == nil {
+        return parser.Close
+    }
+    if end, last := closing(line); end >= 0 {
+        // display maths with text after it is left as written, like maths
+        // that is never closed
+        if last {
+            n.Lines().Append(text.NewSegment(segment.Start, segment.Start+end))
+            n.Stop = segment.Start + end + 2
+            n.closed = true
+        }
+        reader.Advance(segment.Len() - 1)
+        return parser.Close
+    }
+    n.Lines().Append(segment)
+    reader.Advance(segment.Len() - 1)
+    return parser.Continue | parser.NoChildren
+}
+
+func (p blockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p blockParser) CanInterruptParagraph() bool {
+    return true
+}
+
+func (p blockParser) CanAcceptIndentedLine() bool {
+    return false
+}
+
+type htmlRenderer struct{}
+
+func (r htmlRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+    reg.Register(KindInlineMath, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+        if entering {
+            w.WriteString(`<span class="math">`)
+            w.Write(util.EscapeHTML([]byte(Inline(n.(*InlineMath).Tex(source)))))
+            w.WriteString("</span>")
+        }
+        return ast.WalkContinue, nil
+    })
+    reg.Register(KindMathBlock, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+        if entering {
+            w.WriteString(`<pre class="math">`)
+            w.Write(util.EscapeHTML([]byte(Display(n.(*MathBlock).Tex(source)))))
+            w.WriteString("</pre>\n")
+        }
+        return ast.WalkSkipChildren, nil
+    })
+}
+
+type mathsExtension struct{}
+
+// Extension parses $ and $$ maths in goldmark, rendering it to HTML as
+// Unicode.
+var Extension goldmark.Extender = mathsExtension{}
+
+func (e mathsExtension) Extend(m goldmark.Markdown) {
+    m.Parser().AddOptions(
+        parser.WithInlineParsers(util.Prioritized(inlineParser{}, 500)),
+        parser.WithBlockParsers(util.Prioritized(blockParser{}, 650)),
+    )
+    m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(htmlRenderer{}, 500)))
+}
+
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM, Extension))
+
+// Typeset replaces the maths in a Markdown message with Unicode, leaving the
+// rest of the message for glamour to render. Display maths becomes a code
+// block so its lines stay aligned.
+func Typeset(message string) string {
+    source := []byte(message)
+    type span struct {
+        start, stop  int
+        text         string
+    }
+    var spans []span
+    ast.Walk(markdown.Parser().Parse(text.NewReader(source)), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+        if !entering {
+            return ast.WalkContinue, nil
+        }
+        switch n := n.(type) {
+        case *InlineMath:
+            spans = append(spans, span{n.Start, n.Stop, escape(Inline(n.Tex(source)))})
+        case *MathBlock:
+            // unclosed maths is left as text
+            if n.closed {
+                spans = append(spans, span{n.Start, n.Stop, fence(source, n.Start, Display(n.Tex(source)))})
+            }
+            return ast.WalkSkipChildren, nil
+        }
+        return ast.WalkContinue, nil
+    })
+    // replace from the end so the offsets before stay put
+    for i := len(spans) - 1; i >= 0; i-- {
+        s := spans[i]
+        source = append(source[:s.start:s.start], append([]byte(s.text), source[s.stop:]...)...)
+    }
+    return string(source)
+}
+
+// escape keeps Markdown from reading the punctuation in maths
+func escape(s string) string {
+    var b strings.Builder
+    for _, c := range s {
+        if c < 128 && util.IsPunct(byte(c)) {
+            b.WriteByte('\\')
+        }
+        b.WriteRune(c)
+    }
+    return b.String()
+}
+
+// fence puts display maths in a code block, indented to stay in the list item
+// or block quote it started in
+func fence(source []byte, start int, maths string) string {
+    lineStart := bytes.LastIndexByte(source[:start], '\n') + 1
+    prefix := []byte(string(source[lineStart:start]))
+    for i, c := range prefix {
+        if c != '>' && c != '\t' {
+            prefix[i] = ' '
+        }
+    }
+    lines := append([]string{"```"}, strings.Split(maths, "\n")...)
+    lines = append(lines, "```")
+    return strings.Join(lines, "\n"+string(prefix))
+}
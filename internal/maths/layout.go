@@ -0,0 +1,422 @@
+package maths
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// box is rendered maths, lines of equal width with the line that sits on the
+// baseline of the text around it
+type box struct {
+    lines     []string
+    baseline  int
+}
+
+func textBox(s string) box {
+    return box{lines: []string{s}}
+}
+
+func (b box) width() int {
+    if len(b.lines) == 0 {
+        return 0
+    }
+    return runewidth.StringWidth(b.lines[0])
+}
+
+func (b box) height() int {
+    return len(b.lines)
+}
+
+// single is the box as one line of text
+func (b box) single() (string, bool) {
+    if b.height() != 1 {
+        return "", false
+    }
+    return b.lines[0], true
+}
+
+func padRight(s string, width int) string {
+    return s + strings.Repeat(" ", max(width-runewidth.StringWidth(s), 0))
+}
+
+func centre(s string, width int) string {
+    space := max(width-runewidth.StringWidth(s), 0)
+    return strings.Repeat(" ", space/2) + s + strings.Repeat(" ", space-space/2)
+}
+
+// hcat joins boxes side by side on their baselines
+func hcat(boxes ...box) box {
+    above, below := 0, 0
+    for _, b := range boxes {
+        above = max(above, b.baseline)
+        below = max(below, b.height()-b.baseline-1)
+    }
+    lines := make([]string, above+below+1)
+    for _, b := range boxes {
+        top := above - b.baseline
+        blank := strings.Repeat(" ", b.width())
+        for i := range lines {
+            if i >= top && i < top+b.height() {
+                lines[i] += b.lines[i-top]
+            } else {
+                lines[i] += blank
+            }
+        }
+    }
+    return box{lines: lines, baseline: above}
+}
+
+// vcat stacks boxes, centred or aligned left, with the baseline on the given
+// line
+func vcat(left bool, baseline int, boxes ...box) box {
+    width := 0
+    for _, b := range boxes {
+        width = max(width, b.width())
+    }
+    var lines []string
+    for _, b := range boxes {
+        for _, line := range b.lines {
+            if left {
+                lines = append(lines, padRight(line, width))
+            } else {
+                lines = append(lines, centre(line, width))
+            }
+        }
+    }
+    return box{lines: lines, baseline: baseline}
+}
+
+// script converts text to superscript or subscript characters, if they all
+// exist, closing up the spaces between them
+func script(s string, table map[rune]rune) (string, bool) {
+    var b strings.Builder
+    for _, c := range s {
+        if c == ' ' {
+            continue
+        }
+        r, ok := table[c]
+        if !ok {
+            return "", false
+        }
+        b.WriteRune(r)
+    }
+    return b.String(), true
+}
+
+// delimiter stretches a delimiter over a box's height
+func delimiter(d string, height, baseline int) box {
+    if d == "" {
+        return box{lines: make([]string, height), baseline: baseline}
+    }
+    pieces, ok := tallDelimiters[d]
+    if (d == "{" || d == "}") && height == 2 {
+        pieces = [4]string{"⎰", "", "⎱"}
+        if d == "}" {
+            pieces = [4]string{"⎱", "", "⎰"}
+        }
+    }
+    if height == 1 || !ok {
+        lines := make([]string, height)
+        for i := range lines {
+            lines[i] = strings.Repeat(" ", runewidth.StringWidth(d))
+        }
+        lines[baseline] = d
+        return box{lines: lines, baseline: baseline}
+    }
+    lines := make([]string, height)
+    for i := range lines {
+        lines[i] = pieces[1]
+    }
+    lines[0] = pieces[0]
+    lines[height-1] = pieces[2]
+    if height > 2 {
+        lines[height/2] = pieces[3]
+    }
+    return box{lines: lines, baseline: baseline}
+}
+
+// layout lays out parsed maths, on one line when inline
+type layout struct {
+    inline  bool
+}
+
+func (r layout) render(n node) box {
+    switch n := n.(type) {
+    case symbol:
+        return textBox(n.text)
+    case row:
+        return r.row(n)
+    case frac:
+        return r.frac(n)
+    case root:
+        return r.root(n)
+    case scripts:
+        return r.scripts(n)
+    case matrix:
+        return r.matrix(n)
+    case delimited:
+        body := r.render(n.body)
+        return hcat(delimiter(n.open, body.height(), body.baseline), body,
+            delimiter(n.close, body.height(), body.baseline))
+    case accent:
+        return r.accent(n)
+    }
+    return textBox("")
+}
+
+// classOf is the spacing class of a node
+func classOf(n node) class {
+    switch n := n.(type) {
+    case symbol:
+        return n.class
+    case scripts:
+        return classOf(n.base)
+    }
+    return ord
+}
+
+// spaced reports whether a space separates nodes of these classes
+func spaced(prev, next class) bool {
+    switch {
+    case prev == bin || next == bin || prev == rel || next == rel:
+        return true
+    case prev == punct:
+        return true
+    case prev == fun || prev == op:
+        return next != open && next != punct && next != close
+    case next == fun || next == op:
+        return prev != open
+    }
+    return false
+}
+
+func (r layout) row(nodes row) box {
+    boxes := []box{}
+    prev := class(-1)
+    for _, n := range nodes {
+        c := classOf(n)
+        // a sign at the start or after an operator is unary
+        if c == bin && (prev == -1 || prev == bin || prev == rel || prev == open || prev == punct || prev == op) {
+            c = ord
+        }
+        if prev != -1 && spaced(prev, c) {
+            boxes = append(boxes, textBox(" "))
+        }
+        boxes = append(boxes, r.render(n))
+        prev = c
+    }
+    if len(boxes) == 0 {
+        return textBox("")
+    }
+    return hcat(boxes...)
+}
+
+// atomic reports whether a node reads as one piece inline, without brackets
+func atomic(n node) bool {
+    switch n := n.(type) {
+    case row:
+        return len(n) == 1 && atomic(n[0])
+    case symbol, scripts, delimited, matrix, accent, root:
+        return true
+    }
+    return false
+}
+
+// bracket renders a node inline, in brackets unless it is one piece
+func (r layout) bracket(n node) box {
+    b := r.render(n)
+    if atomic(n) {
+        return b
+    }
+    return hcat(textBox("("), b, textBox(")"))
+}
+
+func (r layout) frac(f frac) box {
+    if r.inline {
+        num, den := r.bracket(f.num), r.bracket(f.den)
+        if s, ok := vulgarFractions[num.lines[0]+"/"+den.lines[0]]; ok {
+            return textBox(s)
+        }
+        return hcat(num, textBox("/"), den)
+    }
+    num, den := r.render(f.num), r.render(f.den)
+    bar := textBox(strings.Repeat("─", max(num.width(), den.width())+2))
+    return vcat(false, num.height(), num, bar, den)
+}
+
+func (r layout) root(n root) box {
+    sign := "√"
+    if n.index != nil {
+        index := layout{inline: true}.render(n.index).lines[0]
+        if s, ok := script(index, superscripts); ok {
+            sign = s + sign
+        } else {
+            sign = index + sign
+        }
+    }
+    if r.inline {
+        return hcat(textBox(sign), r.bracket(n.arg))
+    }
+    arg := r.render(n.arg)
+    // a bar over the argument
+    lines := []string{strings.Repeat(" ", runewidth.StringWidth(sign)) + strings.Repeat("_", arg.width())}
+    for i, line := range arg.lines {
+        prefix := strings.Repeat(" ", runewidth.StringWidth(sign)-1) + "│"
+        if i == arg.height()-1 {
+            prefix = sign
+        }
+        lines = append(lines, prefix+line)
+    }
+    return box{lines: lines, baseline: arg.baseline + 1}
+}
+
+func (r layout) scripts(s scripts) box {
+    base := r.render(s.base)
+    flat := layout{inline: true}
+    var sub, sup string
+    if s.sub != nil {
+        sub = flat.render(s.sub).lines[0]
+    }
+    if s.sup != nil {
+        sup = flat.render(s.sup).lines[0]
+    }
+    // big operators have their limits above and below in display maths
+    if sym, ok := s.base.(symbol); ok && sym.limits && !r.inline {
+        parts := []box{}
+        if s.sup != nil {
+            parts = append(parts, r.render(s.sup))
+        }
+        parts = append(parts, base)
+        if s.sub != nil {
+            parts = append(parts, r.render(s.sub))
+        }
+        above := 0
+        if s.sup != nil {
+            above = parts[0].height()
+        }
+        return vcat(false, above+base.baseline, parts...)
+    }
+    subText, subOK := script(sub, subscripts)
+    supText, supOK := script(sup, superscripts)
+    if subOK && supOK && base.height() > 1 {
+        // scripts of tall nodes sit at their top and bottom
+        column := make([]string, base.height())
+        column[0] = supText
+        column[base.height()-1] = subText
+        return hcat(base, vcat(true, base.baseline, box{lines: column}))
+    }
+    if subOK && supOK || r.inline {
+        b := base
+        if subOK {
+            b = hcat(b, textBox(subText))
+        } else {
+            b = hcat(b, textBox("_"), r.bracket(s.sub))
+        }
+        if supOK {
+            b = hcat(b, textBox(supText))
+        } else {
+            b = hcat(b, textBox("^"), r.bracket(s.sup))
+        }
+        return b
+    }
+    // scripts without Unicode characters are raised and lowered
+    var supBox, subBox box
+    if s.sup != nil {
+        supBox = r.render(s.sup)
+    }
+    if s.sub != nil {
+        subBox = r.render(s.sub)
+    }
+    blank := func(height int) box {
+        return box{lines: make([]string, height)}
+    }
+    left := vcat(true, 0, blank(supBox.height()), base, blank(subBox.height()))
+    right := vcat(true, 0, supBox, blank(base.height()), subBox)
+    b := hcat(left, right)
+    b.baseline = supBox.height() + base.baseline
+    return b
+}
+
+func (r layout) matrix(m matrix) box {
+    if r.inline {
+        cellSep, rowSep := ", ", "; "
+        if m.left {
+            cellSep = " "
+        }
+        var rows []string
+        for _, cells := range m.rows {
+            var texts []string
+            for _, cell := range cells {
+                texts = append(texts, r.render(cell).lines[0])
+            }
+            rows = append(rows, strings.TrimSpace(strings.Join(texts, cellSep)))
+        }
+        return textBox(m.open + strings.Join(rows, rowSep) + m.close)
+    }
+    // column widths
+    rendered := make([][]box, len(m.rows))
+    widths := []int{}
+    for i, cells := range m.rows {
+        for j, cell := range cells {
+            b := r.render(cell)
+            rendered[i] = append(rendered[i], b)
+            if j == len(widths) {
+                widths = append(widths, 0)
+            }
+            widths[j] = max(widths[j], b.width())
+        }
+    }
+    sep := "  "
+    if m.left {
+        sep = " "
+    }
+    var rows []box
+    for _, cells := range rendered {
+        parts := []box{}
+        for j, width := range widths {
+            cell := textBox("")
+            if j < len(cells) {
+                cell = cells[j]
+            }
+            for k, line := range cell.lines {
+                if m.left {
+                    cell.lines[k] = padRight(line, width)
+                } else {
+                    cell.lines[k] = centre(line, width)
+                }
+            }
+            if j > 0 {
+                parts = append(parts, textBox(sep))
+            }
+            parts = append(parts, cell)
+        }
+        rows = append(rows, hcat(parts...))
+    }
+    body := vcat(true, 0, rows...)
+    body.baseline = body.height() / 2
+    if m.open == "" && m.close == "" {
+        return body
+    }
+    open := delimiter(m.open, body.height(), body.baseline)
+    close := delimiter(m.close, body.height(), body.baseline)
+    return hcat(open, textBox(" "), body, textBox(" "), close)
+}
+
+func (r layout) accent(a accent) box {
+    arg := r.render(a.arg)
+    text, ok := arg.single()
+    if !ok || text == "" {
+        return arg
+    }
+    runes := []rune(text)
+    if !a.wide {
+        return textBox(text + a.mark)
+    }
+    var b strings.Builder
+    for _, c := range runes {
+        b.WriteRune(c)
+        b.WriteString(a.mark)
+    }
+    return textBox(b.String())
+}
@@ -0,0 +1,337 @@
+// Package maths renders LaTeX maths as Unicode text for the terminal, and
+// extends goldmark to typeset the maths in Markdown messages.
+package maths
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Inline renders LaTeX maths on a single line, to sit within text.
+func Inline(tex string) string {
+    return strings.TrimRight(layout{inline: true}.render(parse(tex)).lines[0], " ")
+}
+
+// Display renders LaTeX maths over as many lines as it needs, stacking
+// fractions, limits and matrices.
+func Display(tex string) string {
+    lines := layout{}.render(parse(tex)).lines
+    for i, line := range lines {
+        lines[i] = strings.TrimRight(line, " ")
+    }
+    return strings.Join(lines, "\n")
+}
+
+// parsed maths
+type (
+    node     interface{}
+    // a sequence of nodes, such as a group in braces
+    row      []node
+    frac     struct {
+        num, den  node
+    }
+    root     struct {
+        index, arg  node
+    }
+    scripts  struct {
+        base, sub, sup  node
+    }
+    matrix   struct {
+        rows         [][]node
+        open, close  string
+        left         bool
+    }
+    delimited  struct {
+        open, close  string
+        body         node
+    }
+    accent   struct {
+        mark  string
+        wide  bool
+        arg   node
+    }
+)
+
+// texParser reads LaTeX maths, ignoring what it does not understand rather
+// than failing, since messages are written by hand
+type texParser struct {
+    src  []rune
+    pos  int
+}
+
+func parse(tex string) node {
+    p := &texParser{src: []rune(tex)}
+    rows := p.cells()
+    // rows and columns outside an environment are aligned
+    if len(rows) == 1 && len(rows[0]) == 1 {
+        return rows[0][0]
+    }
+    return matrix{rows: rows, left: true}
+}
+
+func (p *texParser) eof() bool {
+    return p.pos >= len(p.src)
+}
+
+func (p *texParser) skipSpace() {
+    for !p.eof() && unicode.IsSpace(p.src[p.pos]) {
+        p.pos++
+    }
+}
+
+// cells reads the rows and columns of a matrix, up to \end
+func (p *texParser) cells() [][]node {
+    var rows [][]node
+    var cells []node
+    for {
+        cell, end := p.sequence()
+        cells = append(cells, cell)
+        switch end {
+        case "&":
+            continue
+        case `\\`:
+            rows = append(rows, cells)
+            cells = nil
+            continue
+        }
+        // a line break may end the last row
+        if len(rows) == 0 || len(cells) > 1 || len(cell) > 0 {
+            rows = append(rows, cells)
+        }
+        return rows
+    }
+}
+
+// sequence reads nodes up to the end of a group, cell or row, returning what
+// ended it
+func (p *texParser) sequence() (row, string) {
+    r := row{}
+    for {
+        p.skipSpace()
+        if p.eof() {
+            return r, ""
+        }
+        c := p.src[p.pos]
+        p.pos++
+        switch c {
+        case '}', '&':
+            return r, string(c)
+        case '{':
+            group, _ := p.sequence()
+            r = append(r, group)
+        case '^', '_':
+            r = attach(r, c == '_', p.argument())
+        case '\'':
+            r = attach(r, false, symbol{"′", ord, false})
+        case '\\':
+            name := p.command()
+            switch name {
+            case `\`, "cr":
+                return r, `\\`
+            case "end":
+                p.raw()
+                return r, "end"
+            case "right":
+                return r, "right"
+            }
+            if n := p.commandNode(name); n != nil {
+                r = append(r, n)
+            }
+        default:
+            r = append(r, charSymbol(c))
+        }
+    }
+}
+
+// attach adds a subscript or superscript to the last node
+func attach(r row, sub bool, arg node) row {
+    s := scripts{base: row{}}
+    if n := len(r); n > 0 {
+        s.base = r[n-1]
+        // the other script of a node already scripted
+        if prev, ok := r[n-1].(scripts); ok && (sub && prev.sub == nil || !sub && prev.sup == nil) {
+            s = prev
+        }
+        r = r[:n-1]
+    }
+    if sub {
+        s.sub = arg
+    } else {
+        s.sup = arg
+    }
+    return append(r, s)
+}
+
+// command reads the name of a command after its backslash
+func (p *texParser) command() string {
+    if p.eof() {
+        return ""
+    }
+    start := p.pos
+    for !p.eof() && unicode.IsLetter(p.src[p.pos]) {
+        p.pos++
+    }
+    if p.pos == start {
+        p.pos++
+    } else if !p.eof() && p.src[p.pos] == '*' {
+        p.pos++
+    }
+    return string(p.src[start:p.pos])
+}
+
+// argument reads the argument of a command or script, a group or a single
+// symbol
+func (p *texParser) argument() node {
+    p.skipSpace()
+    if p.eof() {
+        return row{}
+    }
+    c := p.src[p.pos]
+    p.pos++
+    switch c {
+    case '{':
+        group, _ := p.sequence()
+        return group
+    case '\\':
+        if n := p.commandNode(p.command()); n != nil {
+            return n
+        }
+        return row{}
+    }
+    return charSymbol(c)
+}
+
+// raw reads the text of a group without parsing it
+func (p *texParser) raw() string {
+    p.skipSpace()
+    if p.eof() || p.src[p.pos] != '{' {
+        return ""
+    }
+    depth := 0
+    start := p.pos + 1
+    for ; !p.eof(); p.pos++ {
+        switch p.src[p.pos] {
+        case '{':
+            depth++
+        case '}':
+            depth--
+            if depth == 0 {
+                p.pos++
+                return string(p.src[start : p.pos-1])
+            }
+        }
+    }
+    return string(p.src[start:])
+}
+
+// optional reads an optional argument in square brackets
+func (p *texParser) optional() node {
+    p.skipSpace()
+    if p.eof() || p.src[p.pos] != '[' {
+        return nil
+    }
+    end := p.pos + 1
+    for end < len(p.src) && p.src[end] != ']' {
+        end++
+    }
+    arg := parse(string(p.src[p.pos+1 : end]))
+    p.pos = min(end+1, len(p.src))
+    return arg
+}
+
+// delimiter reads the delimiter after \left or \right
+func (p *texParser) delimiter() string {
+    p.skipSpace()
+    if p.eof() {
+        return ""
+    }
+    c := p.src[p.pos]
+    p.pos++
+    switch c {
+    case '.':
+        return ""
+    case '\\':
+        if s, ok := symbols[p.command()]; ok {
+            return s.text
+        }
+        return ""
+    }
+    return string(c)
+}
+
+func (p *texParser) commandNode(name string) node {
+    if s, ok := symbols[name]; ok {
+        return s
+    }
+    if limits, ok := functions[name]; ok {
+        return symbol{name, fun, limits}
+    }
+    if mark, ok := accents[name]; ok {
+        return accent{mark: mark, wide: wideAccents[name], arg: p.argument()}
+    }
+    switch name {
+    case "frac", "dfrac", "tfrac", "cfrac":
+        return frac{num: p.argument(), den: p.argument()}
+    case "binom", "dbinom", "tbinom":
+        top, bottom := p.argument(), p.argument()
+        return matrix{rows: [][]node{{top}, {bottom}}, open: "(", close: ")"}
+    case "sqrt":
+        index := p.optional()
+        return root{index: index, arg: p.argument()}
+    case "text", "textrm", "textit", "textbf", "mbox", "mathrm", "mathit", "mathbf", "mathsf",
+        "mathtt", "mathcal", "boldsymbol":
+        if strings.HasPrefix(name, "math") || name == "boldsymbol" {
+            return p.argument()
+        }
+        return symbol{p.raw(), ord, false}
+    case "operatorname":
+        return symbol{p.raw(), fun, false}
+    case "mathbb":
+        var b strings.Builder
+        for _, c := range p.raw() {
+            if s, ok := doubleStruck[c]; ok {
+                b.WriteString(s)
+            } else {
+                b.WriteRune(c)
+            }
+        }
+        return symbol{b.String(), ord, false}
+    case "left":
+        open := p.delimiter()
+        body, _ := p.sequence()
+        return delimited{open: open, close: p.delimiter(), body: body}
+    case "begin":
+        env := p.raw()
+        if env == "array" {
+            // column specification
+            p.raw()
+        }
+        e := environments[env]
+        return matrix{rows: p.cells(), open: e.open, close: e.close, left: e.left}
+    case "big", "Big", "bigg", "Bigg", "bigl", "bigr", "Bigl", "Bigr", "biggl", "biggr", "Biggl",
+        "Biggr", "displaystyle", "textstyle", "scriptstyle", "limits", "nolimits":
+        return nil
+    }
+    return symbol{`\` + name, ord, false}
+}
+
+// charSymbol is a character typed in maths
+func charSymbol(c rune) symbol {
+    switch c {
+    case '+':
+        return symbol{"+", bin, false}
+    case '-':
+        return symbol{"−", bin, false}
+    case '*':
+        return symbol{"∗", bin, false}
+    case '=', '<', '>', ':':
+        return symbol{string(c), rel, false}
+    case ',', ';':
+        return symbol{string(c), punct, false}
+    case '(', '[':
+        return symbol{string(c), open, false}
+    case ')', ']', '!':
+        return symbol{string(c), close, false}
+    }
+    return symbol{string(c), ord, false}
+}
@@ -0,0 +1,59 @@
+package maths_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CraigYanitski/mescli/internal/maths"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// golden compares output with a file in testdata, or writes it with -update
+func golden(t *testing.T, name, got string) {
+    t.Helper()
+    path := filepath.Join("testdata", name)
+    if *update {
+        if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    want, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got != string(want) {
+        t.Errorf("output differs from %s, got:\n%s", path, got)
+    }
+}
+
+func TestExpressions(t *testing.T) {
+    corpus, err := os.ReadFile(filepath.Join("testdata", "expressions.txt"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    var b strings.Builder
+    for _, tex := range strings.Split(strings.TrimSpace(string(corpus)), "\n") {
+        b.WriteString("# " + tex + "\n")
+        b.WriteString(maths.Inline(tex) + "\n\n")
+        b.WriteString(maths.Display(tex) + "\n\n")
+    }
+    golden(t, "expressions.golden", b.String())
+}
+
+func TestTypeset(t *testing.T) {
+    message, err := os.ReadFile(filepath.Join("testdata", "messages.md"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    golden(t, "messages.golden", maths.Typeset(string(message)))
+
+    // messages without maths are left alone
+    plain := "*no* maths, only $5\n\n```\n$x$\n```\n"
+    if got := maths.Typeset(plain); got != plain {
+        t.Errorf("expected %q, got %q", plain, got)
+    }
+}
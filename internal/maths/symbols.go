@@ -0,0 +1,299 @@
+package maths
+
+// class decides the spacing around a symbol, as in TeX
+type class int
+
+const (
+    ord class = iota
+    op
+    bin
+    rel
+    open
+    close
+    punct
+    fun
+)
+
+type symbol struct {
+    text    string
+    class   class
+    // scripts are stacked above and below in display maths
+    limits  bool
+}
+
+// symbols are the commands that stand for a single symbol
+var symbols = map[string]symbol{
+    // lowercase Greek
+    "alpha": {"α", ord, false},
+    "beta": {"β", ord, false},
+    "gamma": {"γ", ord, false},
+    "delta": {"δ", ord, false},
+    "epsilon": {"ϵ", ord, false},
+    "varepsilon": {"ε", ord, false},
+    "zeta": {"ζ", ord, false},
+    "eta": {"η", ord, false},
+    "theta": {"θ", ord, false},
+    "vartheta": {"ϑ", ord, false},
+    "iota": {"ι", ord, false},
+    "kappa": {"κ", ord, false},
+    "lambda": {"λ", ord, false},
+    "mu": {"μ", ord, false},
+    "nu": {"ν", ord, false},
+    "xi": {"ξ", ord, false},
+    "omicron": {"ο", ord, false},
+    "pi": {"π", ord, false},
+    "varpi": {"ϖ", ord, false},
+    "rho": {"ρ", ord, false},
+    "varrho": {"ϱ", ord, false},
+    "sigma": {"σ", ord, false},
+    "varsigma": {"ς", ord, false},
+    "tau": {"τ", ord, false},
+    "upsilon": {"υ", ord, false},
+    "phi": {"ϕ", ord, false},
+    "varphi": {"φ", ord, false},
+    "chi": {"χ", ord, false},
+    "psi": {"ψ", ord, false},
+    "omega": {"ω", ord, false},
+    // uppercase Greek
+    "Gamma": {"Γ", ord, false},
+    "Delta": {"Δ", ord, false},
+    "Theta": {"Θ", ord, false},
+    "Lambda": {"Λ", ord, false},
+    "Xi": {"Ξ", ord, false},
+    "Pi": {"Π", ord, false},
+    "Sigma": {"Σ", ord, false},
+    "Upsilon": {"Υ", ord, false},
+    "Phi": {"Φ", ord, false},
+    "Psi": {"Ψ", ord, false},
+    "Omega": {"Ω", ord, false},
+    // letter-like symbols
+    "infty": {"∞", ord, false},
+    "partial": {"∂", ord, false},
+    "nabla": {"∇", ord, false},
+    "hbar": {"ℏ", ord, false},
+    "ell": {"ℓ", ord, false},
+    "Re": {"ℜ", ord, false},
+    "Im": {"ℑ", ord, false},
+    "aleph": {"ℵ", ord, false},
+    "emptyset": {"∅", ord, false},
+    "varnothing": {"∅", ord, false},
+    "forall": {"∀", ord, false},
+    "exists": {"∃", ord, false},
+    "neg": {"¬", ord, false},
+    "angle": {"∠", ord, false},
+    "degree": {"°", ord, false},
+    "prime": {"′", ord, false},
+    "ldots": {"…", ord, false},
+    "dots": {"…", ord, false},
+    "cdots": {"⋯", ord, false},
+    "vdots": {"⋮", ord, false},
+    "ddots": {"⋱", ord, false},
+    "top": {"⊤", ord, false},
+    "bot": {"⊥", ord, false},
+    // big operators
+    "sum": {"∑", op, true},
+    "prod": {"∏", op, true},
+    "coprod": {"∐", op, true},
+    "bigcup": {"⋃", op, true},
+    "bigcap": {"⋂", op, true},
+    "bigoplus": {"⨁", op, true},
+    "bigotimes": {"⨂", op, true},
+    "bigvee": {"⋁", op, true},
+    "bigwedge": {"⋀", op, true},
+    "int": {"∫", op, false},
+    "iint": {"∬", op, false},
+    "iiint": {"∭", op, false},
+    "oint": {"∮", op, false},
+    // binary operators
+    "pm": {"±", bin, false},
+    "mp": {"∓", bin, false},
+    "times": {"×", bin, false},
+    "div": {"÷", bin, false},
+    "cdot": {"⋅", bin, false},
+    "ast": {"∗", bin, false},
+    "star": {"⋆", bin, false},
+    "circ": {"∘", bin, false},
+    "bullet": {"∙", bin, false},
+    "cup": {"∪", bin, false},
+    "cap": {"∩", bin, false},
+    "setminus": {"∖", bin, false},
+    "oplus": {"⊕", bin, false},
+    "otimes": {"⊗", bin, false},
+    "wedge": {"∧", bin, false},
+    "land": {"∧", bin, false},
+    "vee": {"∨", bin, false},
+    "lor": {"∨", bin, false},
+    "dagger": {"†", bin, false},
+    // relations
+    "leq": {"≤", rel, false},
+    "le": {"≤", rel, false},
+    "geq": {"≥", rel, false},
+    "ge": {"≥", rel, false},
+    "neq": {"≠", rel, false},
+    "ne": {"≠", rel, false},
+    "ll": {"≪", rel, false},
+    "gg": {"≫", rel, false},
+    "approx": {"≈", rel, false},
+    "equiv": {"≡", rel, false},
+    "sim": {"∼", rel, false},
+    "simeq": {"≃", rel, false},
+    "cong": {"≅", rel, false},
+    "propto": {"∝", rel, false},
+    "in": {"∈", rel, false},
+    "notin": {"∉", rel, false},
+    "ni": {"∋", rel, false},
+    "subset": {"⊂", rel, false},
+    "subseteq": {"⊆", rel, false},
+    "supset": {"⊃", rel, false},
+    "supseteq": {"⊇", rel, false},
+    "perp": {"⊥", rel, false},
+    "parallel": {"∥", rel, false},
+    "mid": {"∣", rel, false},
+    "to": {"→", rel, false},
+    "rightarrow": {"→", rel, false},
+    "leftarrow": {"←", rel, false},
+    "gets": {"←", rel, false},
+    "leftrightarrow": {"↔", rel, false},
+    "Rightarrow": {"⇒", rel, false},
+    "Leftarrow": {"⇐", rel, false},
+    "Leftrightarrow": {"⇔", rel, false},
+    "implies": {"⟹", rel, false},
+    "impliedby": {"⟸", rel, false},
+    "iff": {"⟺", rel, false},
+    "mapsto": {"↦", rel, false},
+    "uparrow": {"↑", rel, false},
+    "downarrow": {"↓", rel, false},
+    // delimiters
+    "{": {"{", open, false},
+    "}": {"}", close, false},
+    "langle": {"⟨", open, false},
+    "rangle": {"⟩", close, false},
+    "lfloor": {"⌊", open, false},
+    "rfloor": {"⌋", close, false},
+    "lceil": {"⌈", open, false},
+    "rceil": {"⌉", close, false},
+    "lvert": {"|", open, false},
+    "rvert": {"|", close, false},
+    "lVert": {"‖", open, false},
+    "rVert": {"‖", close, false},
+    "|": {"‖", ord, false},
+    "vert": {"|", ord, false},
+    "Vert": {"‖", ord, false},
+    // escaped characters
+    "$": {"$", ord, false},
+    "%": {"%", ord, false},
+    "&": {"&", ord, false},
+    "#": {"#", ord, false},
+    "_": {"_", ord, false},
+    // spaces
+    ",": {" ", ord, false},
+    ":": {" ", ord, false},
+    ";": {" ", ord, false},
+    " ": {" ", ord, false},
+    "!": {"", ord, false},
+    "quad": {"  ", ord, false},
+    "qquad": {"    ", ord, false},
+}
+
+// functions are set upright, some with limits like the big operators
+var functions = map[string]bool{
+    "sin": false, "cos": false, "tan": false, "sec": false, "csc": false, "cot": false,
+    "arcsin": false, "arccos": false, "arctan": false, "sinh": false, "cosh": false, "tanh": false,
+    "log": false, "ln": false, "lg": false, "exp": false, "dim": false, "ker": false, "deg": false,
+    "arg": false, "hom": false,
+    "lim": true, "liminf": true, "limsup": true, "max": true, "min": true, "sup": true, "inf": true,
+    "det": true, "gcd": true, "Pr": true,
+}
+
+// combining marks of the accent commands
+var accents = map[string]string{
+    "hat": "̂",
+    "widehat": "̂",
+    "check": "̌",
+    "tilde": "̃",
+    "widetilde": "̃",
+    "bar": "̄",
+    "overline": "̅",
+    "underline": "̲",
+    "vec": "⃗",
+    "dot": "̇",
+    "ddot": "̈",
+    "not": "̸",
+}
+
+// accents marking every character of their argument, rather than the last
+var wideAccents = map[string]bool{"overline": true, "underline": true, "not": true}
+
+// delimiters of the matrix environments
+var environments = map[string]struct {
+    open, close  string
+    // cells are aligned left rather than centred
+    left         bool
+}{
+    "matrix": {"", "", false},
+    "smallmatrix": {"", "", false},
+    "pmatrix": {"(", ")", false},
+    "bmatrix": {"[", "]", false},
+    "Bmatrix": {"{", "}", false},
+    "vmatrix": {"|", "|", false},
+    "Vmatrix": {"‖", "‖", false},
+    "cases": {"{", "", true},
+    "array": {"", "", false},
+    "aligned": {"", "", true},
+    "align": {"", "", true},
+    "align*": {"", "", true},
+    "gathered": {"", "", false},
+    "gather": {"", "", false},
+    "gather*": {"", "", false},
+    "split": {"", "", true},
+}
+
+var doubleStruck = map[rune]string{
+    'C': "ℂ", 'H': "ℍ", 'N': "ℕ", 'P': "ℙ", 'Q': "ℚ", 'R': "ℝ", 'Z': "ℤ",
+    'A': "𝔸", 'B': "𝔹", 'D': "𝔻", 'E': "𝔼", 'F': "𝔽", 'G': "𝔾", 'I': "𝕀", 'J': "𝕁",
+    'K': "𝕂", 'L': "𝕃", 'M': "𝕄", 'O': "𝕆", 'S': "𝕊", 'T': "𝕋", 'U': "𝕌", 'V': "𝕍",
+    'W': "𝕎", 'X': "𝕏", 'Y': "𝕐", '1': "𝟙",
+}
+
+var superscripts = map[rune]rune{
+    '0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴', '5': '⁵', '6': '⁶', '7': '⁷', '8': '⁸', '9': '⁹',
+    '+': '⁺', '-': '⁻', '−': '⁻', '=': '⁼', '(': '⁽', ')': '⁾', '′': '′',
+    'a': 'ᵃ', 'b': 'ᵇ', 'c': 'ᶜ', 'd': 'ᵈ', 'e': 'ᵉ', 'f': 'ᶠ', 'g': 'ᵍ', 'h': 'ʰ', 'i': 'ⁱ',
+    'j': 'ʲ', 'k': 'ᵏ', 'l': 'ˡ', 'm': 'ᵐ', 'n': 'ⁿ', 'o': 'ᵒ', 'p': 'ᵖ', 'r': 'ʳ', 's': 'ˢ',
+    't': 'ᵗ', 'u': 'ᵘ', 'v': 'ᵛ', 'w': 'ʷ', 'x': 'ˣ', 'y': 'ʸ', 'z': 'ᶻ',
+    'A': 'ᴬ', 'B': 'ᴮ', 'D': 'ᴰ', 'E': 'ᴱ', 'G': 'ᴳ', 'H': 'ᴴ', 'I': 'ᴵ', 'J': 'ᴶ', 'K': 'ᴷ',
+    'L': 'ᴸ', 'M': 'ᴹ', 'N': 'ᴺ', 'O': 'ᴼ', 'P': 'ᴾ', 'R': 'ᴿ', 'T': 'ᵀ', 'U': 'ᵁ', 'V': 'ⱽ',
+    'W': 'ᵂ', 'α': 'ᵅ', 'β': 'ᵝ', 'γ': 'ᵞ', 'δ': 'ᵟ', 'θ': 'ᶿ', 'φ': 'ᵠ', 'χ': 'ᵡ', '∗': '*',
+}
+
+var subscripts = map[rune]rune{
+    '0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄', '5': '₅', '6': '₆', '7': '₇', '8': '₈', '9': '₉',
+    '+': '₊', '-': '₋', '−': '₋', '=': '₌', '(': '₍', ')': '₎',
+    'a': 'ₐ', 'e': 'ₑ', 'h': 'ₕ', 'i': 'ᵢ', 'j': 'ⱼ', 'k': 'ₖ', 'l': 'ₗ', 'm': 'ₘ', 'n': 'ₙ',
+    'o': 'ₒ', 'p': 'ₚ', 'r': 'ᵣ', 's': 'ₛ', 't': 'ₜ', 'u': 'ᵤ', 'v': 'ᵥ', 'x': 'ₓ',
+    'β': 'ᵦ', 'γ': 'ᵧ', 'ρ': 'ᵨ', 'φ': 'ᵩ', 'χ': 'ᵪ',
+}
+
+// vulgar fractions for simple inline fractions
+var vulgarFractions = map[string]string{
+    "1/2": "½", "1/3": "⅓", "2/3": "⅔", "1/4": "¼", "3/4": "¾", "1/5": "⅕", "2/5": "⅖",
+    "3/5": "⅗", "4/5": "⅘", "1/6": "⅙", "5/6": "⅚", "1/7": "⅐", "1/8": "⅛", "3/8": "⅜",
+    "5/8": "⅝", "7/8": "⅞", "1/9": "⅑", "1/10": "⅒",
+}
+
+// pieces of delimiters stretched over several lines: top, extension, bottom
+// and middle
+var tallDelimiters = map[string][4]string{
+    "(": {"⎛", "⎜", "⎝", "⎜"},
+    ")": {"⎞", "⎟", "⎠", "⎟"},
+    "[": {"⎡", "⎢", "⎣", "⎢"},
+    "]": {"⎤", "⎥", "⎦", "⎥"},
+    "{": {"⎧", "⎪", "⎩", "⎨"},
+    "}": {"⎫", "⎪", "⎭", "⎬"},
+    "⌈": {"⎡", "⎢", "⎢", "⎢"},
+    "⌉": {"⎤", "⎥", "⎥", "⎥"},
+    "⌊": {"⎢", "⎢", "⎣", "⎢"},
+    "⌋": {"⎥", "⎥", "⎦", "⎥"},
+    "|": {"│", "│", "│", "│"},
+    "‖": {"‖", "‖", "‖", "‖"},
+}
@@ -0,0 +1,96 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+var ErrBlockedByContact = errors.New("error: blocked by contact")
+
+type BlockRequest struct {
+    UserID  uuid.UUID  `json:"user_id"`
+}
+type BlockResponse struct {
+    UserID     uuid.UUID  `json:"user_id"`
+    BlockedID  uuid.UUID  `json:"blocked_id"`
+    CreatedAt  time.Time  `json:"created_at"`
+}
+
+// BlockUser asks the server to reject messages and key requests from a user.
+func BlockUser(user uuid.UUID) error {
+    apiURL := viper.GetString("api_url")
+    httpClient := http.Client{}
+    data, err := json.Marshal(BlockRequest{UserID: user})
+    if err != nil {
+        return err
+    }
+    // send request to server
+    req, err := http.NewRequest(http.MethodPost, apiURL+"/blocks", bytes.NewBuffer(data))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := doAuthenticated(&httpClient, req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    // check if request successful
+    if resp.StatusCode != http.StatusCreated {
+        return fmt.Errorf("status %s: unable to block user", resp.Status)
+    }
+    return nil
+}
+
+func UnblockUser(user uuid.UUID) error {
+    apiURL := viper.GetString("api_url")
+    httpClient := http.Client{}
+    // send request to server
+    req, err := http.NewRequest(http.MethodDelete, apiURL+"/blocks/"+user.String(), nil)
+    if err != nil {
+        return err
+    }
+    resp, err := doAuthenticated(&httpClient, req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    // check if request successful
+    if resp.StatusCode != http.StatusNoContent {
+        return fmt.Errorf("status %s: unable to unblock user", resp.Status)
+    }
+    return nil
+}
+
+func GetBlocks() ([]BlockResponse, error) {
+    apiURL := viper.GetString("api_url")
+    httpClient := http.Client{}
+    // send request to server
+    req, err := http.NewRequest(http.MethodGet, apiURL+"/blocks", nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := doAuthenticated(&httpClient, req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("status %s: unable to get blocked users", resp.Status)
+    }
+    blocks := []BlockResponse{}
+    data, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return nil, err
+    }
+    err = json.Unmarshal(data, &blocks)
+    return blocks, err
+}
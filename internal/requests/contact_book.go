@@ -0,0 +1,174 @@
+package requests
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/cryptography"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/google/uuid"
+)
+
+// local contact book, keyed by contact UUID
+const contactsFile = "./.contacts"
+
+var ErrBlocked = errors.New("contact is blocked")
+var ErrKeyChanged = errors.New("contact's identity key has changed, compare fingerprints and trust it with \"contacts trust\"")
+
+type Contact struct {
+    ID              uuid.UUID  `json:"id"`
+    Email           string     `json:"email"`
+    Name            string     `json:"name"`
+    Nickname        string     `json:"nickname,omitempty"`
+    Fingerprint     string     `json:"fingerprint,omitempty"`
+    // fingerprint of a changed identity key, not yet trusted by the user
+    NewFingerprint  string     `json:"new_fingerprint,omitempty"`
+    Blocked         bool       `json:"blocked,omitempty"`
+    LastRead        time.Time  `json:"last_read,omitzero"`
+}
+
+// DisplayName returns the most readable identifier known for the contact.
+func (c Contact) DisplayName() string {
+    if c.Nickname != "" {
+        return c.Nickname
+    } else if c.Name != "" {
+        return c.Name
+    } else if c.Email != "" {
+        return c.Email
+    }
+    return c.ID.String()
+}
+
+func ReadContacts() (map[string]Contact, error) {
+    contacts := make(map[string]Contact)
+    contactBytes, err := os.ReadFile(contactsFile)
+    if errors.Is(err, os.ErrNotExist) {
+        return contacts, nil
+    } else if err != nil {
+        return contacts, err
+    }
+    err = json.Unmarshal(contactBytes, &contacts)
+    return contacts, err
+}
+
+func WriteContacts(contacts map[string]Contact) error {
+    contactBytes, err := json.MarshalIndent(contacts, "", "    ")
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(contactsFile, contactBytes, 0644)
+}
+
+// FindContact looks up a contact in the contact book by UUID, email or nickname.
+func FindContact(contacts map[string]Contact, user string) (Contact, bool) {
+    if c, ok := contacts[user]; ok {
+        return c, true
+    }
+    for _, c := range contacts {
+        if (c.Email != "" && c.Email == user) || (c.Nickname != "" && c.Nickname == user) {
+            return c, true
+        }
+    }
+    return Contact{}, false
+}
+
+// ResolveContact looks up a contact by UUID, email or nickname, first in the
+// contact book and then on the server, saving any new result.
+func ResolveContact(contacts map[string]Contact, user string) (Contact, error) {
+    if c, ok := FindContact(contacts, user); ok {
+        return c, nil
+    }
+    return SaveContact(contacts, user)
+}
+
+// SaveContact fetches a user and their identity key fingerprint from the
+// server and saves them in the contact book, keeping any local details.
+func SaveContact(contacts map[string]Contact, user string) (Contact, error) {
+    u, err := FetchContact(user)
+    if err != nil {
+        return Contact{}, err
+    }
+    c := MergeContact(contacts, u)
+    return c, WriteContacts(contacts)
+}
+
+// FetchContact fetches a user and their identity key fingerprint from the
+// server without touching the contact book.
+func FetchContact(user string) (Contact, error) {
+    u, err := GetUser(user)
+    if err != nil {
+        return Contact{}, err
+    }
+    if u.ID == uuid.Nil {
+        return Contact{}, errors.New("error: user not found")
+    }
+    c := Contact{ID: u.ID, Email: u.Email, Name: u.Name}
+    // the server hides the email from strangers, but it was looked up by it
+    if _, err := uuid.Parse(user); err != nil && c.Email == "" {
+        c.Email = user
+    }
+    if ik, err := GetUserIdentityKey(u.ID); err == nil && ik != nil {
+        c.Fingerprint = cryptography.Fingerprint(ik)
+    }
+    return c, nil
+}
+
+// MergeContact adds the server details of a contact to the contact book,
+// keeping any local details, and returns the result. The server only shows
+// the email and name of people who have messaged or accepted the user, so
+// those already known are kept when it does not.
+func MergeContact(contacts map[string]Contact, u Contact) Contact {
+    c := contacts[u.ID.String()]
+    c.ID = u.ID
+    if u.Email != "" {
+        c.Email = u.Email
+    }
+    if u.Name != "" {
+        c.Name = u.Name
+    }
+    c.UpdateFingerprint(u.Fingerprint)
+    contacts[c.ID.String()] = c
+    return c
+}
+
+// UpdateFingerprint records the fingerprint of the contact's identity key
+// from the server. A fingerprint differing from the trusted one is kept apart
+// until the user trusts it, so a replaced key is never trusted silently.
+func (c *Contact) UpdateFingerprint(fingerprint string) {
+    switch {
+    case fingerprint == "":
+    case c.Fingerprint == "":
+        c.Fingerprint = fingerprint
+    case fingerprint == c.Fingerprint:
+        c.NewFingerprint = ""
+    default:
+        c.NewFingerprint = fingerprint
+    }
+}
+
+// KeyChanged reports whether the contact's identity key has changed since it
+// was trusted.
+func (c Contact) KeyChanged() bool {
+    return c.NewFingerprint != ""
+}
+
+// TrustKey trusts the contact's changed identity key.
+func (c *Contact) TrustKey() {
+    if c.NewFingerprint != "" {
+        c.Fingerprint = c.NewFingerprint
+        c.NewFingerprint = ""
+    }
+}
+
+// Unread counts the messages from the contact received after they were last read.
+func (c Contact) Unread(messages []utils.RawMessage) int {
+    unread := 0
+    for _, m := range messages {
+        if m.Sender == utils.ContactType && m.Time.After(c.LastRead) {
+            unread++
+        }
+    }
+    return unread
+}
@@ -0,0 +1,87 @@
+package requests
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestContactDisplayName(t *testing.T) {
+    id := uuid.New()
+    cases := []struct {
+        name     string
+        contact  Contact
+        want     string
+    }{
+        {"nickname", Contact{ID: id, Email: "a@b.c", Name: "Alice", Nickname: "al"}, "al"},
+        {"name", Contact{ID: id, Email: "a@b.c", Name: "Alice"}, "Alice"},
+        {"email", Contact{ID: id, Email: "a@b.c"}, "a@b.c"},
+        {"id", Contact{ID: id}, id.String()},
+    }
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            if got := c.contact.DisplayName(); got != c.want {
+                t.Errorf("expected %q, got %q", c.want, got)
+            }
+        })
+    }
+}
+
+func TestFindContact(t *testing.T) {
+    alice := Contact{ID: uuid.New(), Email: "alice@example.com", Name: "Alice", Nickname: "al"}
+    bob := Contact{ID: uuid.New(), Email: "bob@example.com"}
+    contacts := map[string]Contact{
+        alice.ID.String(): alice,
+        bob.ID.String(): bob,
+    }
+    for _, user := range []string{alice.ID.String(), alice.Email, alice.Nickname} {
+        c, ok := FindContact(contacts, user)
+        if !ok || c.ID != alice.ID {
+            t.Errorf("expected %q to find alice, got %v", user, c)
+        }
+    }
+    // empty nicknames must not match
+    if _, ok := FindContact(contacts, ""); ok {
+        t.Error("expected empty user not to match a contact")
+    }
+    if _, ok := FindContact(contacts, "Alice"); ok {
+        t.Error("expected display names not to match a contact")
+    }
+}
+
+func TestMergeContactKeyChange(t *testing.T) {
+    id := uuid.New()
+    contacts := map[string]Contact{}
+
+    // the first key seen is trusted
+    c := MergeContact(contacts, Contact{ID: id, Name: "Alice", Fingerprint: "AAAA"})
+    if c.Fingerprint != "AAAA" || c.KeyChanged() {
+        t.Fatalf("expected the first fingerprint to be trusted, got %+v", c)
+    }
+
+    // a changed key is kept apart, with the trusted fingerprint
+    c = MergeContact(contacts, Contact{ID: id, Name: "Alice", Fingerprint: "BBBB"})
+    if c.Fingerprint != "AAAA" || c.NewFingerprint != "BBBB" || !c.KeyChanged() {
+        t.Fatalf("expected the changed key not to be trusted, got %+v", c)
+    }
+    if contacts[id.String()] != c {
+        t.Fatal("expected the changed key to be saved in the contact book")
+    }
+
+    // a lookup without a key, or with the trusted one, changes nothing
+    c = MergeContact(contacts, Contact{ID: id, Name: "Alice"})
+    if !c.KeyChanged() {
+        t.Fatal("expected the key to stay changed")
+    }
+    c = MergeContact(contacts, Contact{ID: id, Name: "Alice", Fingerprint: "AAAA"})
+    if c.KeyChanged() {
+        t.Fatal("expected the trusted key to clear the change")
+    }
+
+    // the new key is trusted only when the user says so
+    c = MergeContact(contacts, Contact{ID: id, Name: "Alice", Fingerprint: "BBBB"})
+    c.TrustKey()
+    if c.Fingerprint != "BBBB" || c.KeyChanged() {
+        t.Fatalf("expected the new key to be trusted, got %+v", c)
+    }
+}
@@ -3,6 +3,7 @@ package requests
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -36,11 +37,16 @@ func GetUser(userID string) (*UserResponse, error) {
 		}
 	}
     userReq.Header.Set("Content-Type", "application/json")
-    userReq.Header.Set("Authorization", "Bearer "+viper.GetString("access_token"))
-    userResp, err := httpClient.Do(userReq)
+    userResp, err := doAuthenticated(&httpClient, userReq)
     if err != nil {
         return nil, err
     }
+    defer userResp.Body.Close()
+    if userResp.StatusCode == http.StatusTooManyRequests {
+        return nil, ErrLookupLimit
+    } else if userResp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("status %s: unable to find user %s", userResp.Status, userID)
+    }
     user := &UserResponse{}
     userRespData, err := io.ReadAll(userResp.Body)
     if err != nil {
@@ -53,6 +59,20 @@ func GetUser(userID string) (*UserResponse, error) {
     return user, nil
 }
 
+// GetAccount gets the user's own account by the ID in their access token,
+// so that it is not counted as a lookup
+func GetAccount() (*UserResponse, error) {
+    token, err := AccessToken()
+    if err != nil {
+        return nil, err
+    }
+    id, err := tokenUser(token)
+    if err != nil {
+        return nil, fmt.Errorf("error reading access token: %w", err)
+    }
+    return GetUser(id.String())
+}
+
 func GetContactID(email string) (*uuid.UUID, error) {
     apiURL := viper.GetString("api_url")
     httpClient := http.Client{}
@@ -67,11 +87,16 @@ func GetContactID(email string) (*uuid.UUID, error) {
         return nil, err
     }
     userReq.Header.Set("Content-Type", "application/json")
-    userReq.Header.Set("Authorization", "Bearer "+viper.GetString("access_token"))
-    userResp, err := httpClient.Do(userReq)
+    userResp, err := doAuthenticated(&httpClient, userReq)
     if err != nil {
         return nil, err
     }
+    defer userResp.Body.Close()
+    if userResp.StatusCode == http.StatusTooManyRequests {
+        return nil, ErrLookupLimit
+    } else if userResp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("status %s: unable to find user %s", userResp.Status, email)
+    }
     user := &UserResponse{}
     userRespData, err := io.ReadAll(userResp.Body)
     if err != nil {
@@ -92,8 +117,7 @@ func GetContactEmail(uid uuid.UUID) (string, error) {
         return "", err
     }
     userReq.Header.Set("Content-Type", "application/json")
-    userReq.Header.Set("Authorization", "Bearer "+viper.GetString("access_token"))
-    userResp, err := httpClient.Do(userReq)
+    userResp, err := doAuthenticated(&httpClient, userReq)
     if err != nil {
         return "", err
     }
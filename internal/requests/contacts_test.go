@@ -0,0 +1,45 @@
+package requests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/auth"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+func TestGetAccount(t *testing.T) {
+    server, _ := newRefreshServer(t, http.StatusOK)
+    mux := server.Config.Handler.(*http.ServeMux)
+    lookups := 0
+    mux.HandleFunc("GET /users", func(w http.ResponseWriter, r *http.Request) {
+        lookups++
+        w.WriteHeader(http.StatusTooManyRequests)
+    })
+    mux.HandleFunc("GET /users/{userID}", func(w http.ResponseWriter, r *http.Request) {
+        id, err := uuid.Parse(r.PathValue("userID"))
+        if err != nil {
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+        json.NewEncoder(w).Encode(UserResponse{ID: id, Email: "me@example.com"})
+    })
+    id := uuid.New()
+    token, err := auth.MakeJWT(id, testSecret, time.Hour)
+    if err != nil {
+        t.Fatalf("error making JWT: %s", err)
+    }
+    viper.Set("access_token", token)
+
+    // the user's own account is read by their ID, not looked up by email
+    u, err := GetAccount()
+    if err != nil {
+        t.Fatalf("error getting account: %s", err)
+    }
+    if u.ID != id || lookups != 0 {
+        t.Fatalf("expected account %s without lookups, got %s after %d", id, u.ID, lookups)
+    }
+}
@@ -22,6 +22,7 @@ type CreateRequest struct {
     IdentityKey     string  `json:"identity_key"`
     SignedPrekey    string  `json:"signed_key"`
     SignedKey       string  `json:"signed_prekey"`
+    OnetimePrekey   string  `json:"onetime_prekey"`
 }
 type UserResponse struct {
     ID              uuid.UUID  `json:"id"`
@@ -56,6 +57,7 @@ func CreateAccount(name, email, password string) error {
         IdentityKey:  crypt.EncodeECDSAPublicKey(c.IdentityECDSA()),
         SignedPrekey: crypt.EncodeECDHPublicKey(c.SignedPrekey()),
         SignedKey:    hex.EncodeToString(c.SignedKey),
+        OnetimePrekey: crypt.EncodeECDHPublicKey(c.OnetimePrekey()),
     }
     data, err := json.Marshal(login)
     if err != nil {
@@ -83,9 +85,7 @@ func CreateAccount(name, email, password string) error {
     // update config
     viper.Set("name",  user.Name)
     viper.Set("email", user.Email)
-    viper.Set("refresh_token", user.RefreshToken)
-    viper.Set("access_token",  user.AccessToken)
-    viper.Set("last_refresh",  time.Now().Unix())
     viper.WriteConfig()
-    return nil
+    // the server does not issue tokens on creation, so login straight away
+    return LoginWithPassword(email, password)
 }
@@ -0,0 +1,51 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/viper"
+)
+
+type DeleteRequest struct {
+    Password  string  `json:"password"`
+}
+
+// DeleteAccount removes the account from the server after re-confirming the
+// password, then wipes all local keys and history.
+func DeleteAccount(password string) error {
+    apiURL := viper.GetString("api_url")
+    httpClient := http.Client{}
+    data, err := json.Marshal(DeleteRequest{Password: password})
+    if err != nil {
+        return err
+    }
+    // send request to server
+    req, err := http.NewRequest(http.MethodDelete, apiURL+"/users", bytes.NewBuffer(data))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := doAuthenticated(&httpClient, req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    // check if request successful
+    if resp.StatusCode == http.StatusForbidden {
+        return errors.New("error: password incorrect")
+    } else if resp.StatusCode != http.StatusNoContent {
+        return fmt.Errorf("status %s: unable to delete account", resp.Status)
+    }
+    // wipe local data
+    viper.Set("name", "")
+    viper.Set("email", "")
+    clearSession()
+    if err = ForgetKeys(); err != nil {
+        return err
+    }
+    return WipeHistory()
+}
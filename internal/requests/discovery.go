@@ -0,0 +1,90 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/CraigYanitski/mescli/internal/cryptography"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// maximum number of identifiers the server accepts in one request
+const discoveryBatchSize = 500
+
+var ErrLookupLimit = errors.New("error: lookup limit reached, try again later")
+
+type DiscoveryRequest struct {
+    Identifiers  []string  `json:"identifiers"`
+}
+type DiscoveryResponse struct {
+    Identifier   string     `json:"identifier"`
+    ID           uuid.UUID  `json:"id"`
+    Fingerprint  string     `json:"fingerprint"`
+}
+
+// DiscoverContacts finds which email addresses belong to mescli users. Only
+// prefixes of the address hashes are sent to the server, in batches.
+func DiscoverContacts(emails []string) ([]Contact, error) {
+    // map hash prefixes back to addresses
+    byPrefix := make(map[string][]string)
+    prefixes := []string{}
+    for _, email := range emails {
+        prefix := cryptography.TruncateIdentifier(cryptography.HashIdentifier(email))
+        if _, ok := byPrefix[prefix]; !ok {
+            prefixes = append(prefixes, prefix)
+        }
+        byPrefix[prefix] = append(byPrefix[prefix], email)
+    }
+    found := []Contact{}
+    for start := 0; start < len(prefixes); start += discoveryBatchSize {
+        end := min(start+discoveryBatchSize, len(prefixes))
+        matches, err := discoverBatch(prefixes[start:end])
+        if err != nil {
+            return found, err
+        }
+        for _, m := range matches {
+            for _, email := range byPrefix[m.Identifier] {
+                found = append(found, Contact{ID: m.ID, Email: email, Fingerprint: m.Fingerprint})
+            }
+        }
+    }
+    return found, nil
+}
+
+func discoverBatch(prefixes []string) ([]DiscoveryResponse, error) {
+    apiURL := viper.GetString("api_url")
+    httpClient := http.Client{}
+    data, err := json.Marshal(DiscoveryRequest{Identifiers: prefixes})
+    if err != nil {
+        return nil, err
+    }
+    // send request to server
+    req, err := http.NewRequest(http.MethodPost, apiURL+"/users/discover", bytes.NewBuffer(data))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := doAuthenticated(&httpClient, req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    // check if request successful
+    if resp.StatusCode == http.StatusTooManyRequests {
+        return nil, ErrLookupLimit
+    } else if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("status %s: unable to discover contacts", resp.Status)
+    }
+    matches := []DiscoveryResponse{}
+    respData, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return nil, err
+    }
+    err = json.Unmarshal(respData, &matches)
+    return matches, err
+}
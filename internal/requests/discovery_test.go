@@ -0,0 +1,50 @@
+package requests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/auth"
+	"github.com/CraigYanitski/mescli/internal/cryptography"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+func TestDiscoverContacts(t *testing.T) {
+    alice := uuid.New()
+    aliceHash := cryptography.HashIdentifier("alice@example.com")
+    server, _ := newRefreshServer(t, http.StatusOK)
+    mux := server.Config.Handler.(*http.ServeMux)
+    mux.HandleFunc("POST /users/discover", func(w http.ResponseWriter, r *http.Request) {
+        d := DiscoveryRequest{}
+        if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+        matches := []DiscoveryResponse{}
+        for _, identifier := range d.Identifiers {
+            if len(identifier) != cryptography.DiscoveryPrefixLength {
+                t.Errorf("identifier %q is not a truncated hash", identifier)
+            }
+            if identifier == cryptography.TruncateIdentifier(aliceHash) {
+                matches = append(matches, DiscoveryResponse{Identifier: identifier, ID: alice, Fingerprint: "AAAA"})
+            }
+        }
+        json.NewEncoder(w).Encode(matches)
+    })
+    token, err := auth.MakeJWT(uuid.New(), testSecret, time.Hour)
+    if err != nil {
+        t.Fatalf("error making JWT: %s", err)
+    }
+    viper.Set("access_token", token)
+
+    found, err := DiscoverContacts([]string{"bob@example.com", "Alice@example.com"})
+    if err != nil {
+        t.Fatalf("error discovering contacts: %s", err)
+    }
+    if len(found) != 1 || found[0].ID != alice || found[0].Email != "Alice@example.com" {
+        t.Fatalf("expected to find alice, got %v", found)
+    }
+}
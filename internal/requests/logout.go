@@ -0,0 +1,54 @@
+package requests
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/viper"
+)
+
+// config keys holding local cryptographic material
+var keyMaterial = []string{
+    "identity_key",
+    "signed_prekey",
+    "signed_key",
+    "onetime_prekey",
+    "contacts",
+    "send_ratchet",
+    "recv_ratchet",
+}
+
+func Logout() error {
+    apiURL := viper.GetString("api_url")
+    httpClient := http.Client{}
+    refreshToken := viper.GetString("refresh_token")
+    // always remove tokens locally, even if the server cannot be reached
+    defer clearSession()
+    if refreshToken == "" {
+        return nil
+    }
+    // revoke refresh token on server
+    req, err := http.NewRequest(http.MethodPost, apiURL+"/revoke", nil)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Authorization", "Bearer "+refreshToken)
+    resp, err := httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("status %s: unable to revoke refresh token", resp.Status)
+    }
+    return nil
+}
+
+// ForgetKeys wipes the identity keys and conversation ratchets from the config file.
+func ForgetKeys() error {
+    for _, key := range keyMaterial {
+        // viper cannot delete keys, so shadow them with an empty value
+        viper.Set(key, "")
+    }
+    return viper.WriteConfig()
+}
@@ -0,0 +1,122 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// PendingRequest is first contact from a user held by the server until it
+// is accepted or declined.
+type PendingRequest struct {
+    SenderID     uuid.UUID  `json:"sender_id"`
+    Email        string     `json:"email"`
+    Name         string     `json:"name"`
+    Messages     int        `json:"messages"`
+    FirstSentAt  time.Time  `json:"first_sent_at"`
+}
+
+type Settings struct {
+    MessageRequests  bool  `json:"message_requests"`
+}
+
+func GetMessageRequests() ([]PendingRequest, error) {
+    apiURL := viper.GetString("api_url")
+    httpClient := http.Client{}
+    // send request to server
+    req, err := http.NewRequest(http.MethodGet, apiURL+"/requests", nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := doAuthenticated(&httpClient, req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("status %s: unable to get message requests", resp.Status)
+    }
+    pending := []PendingRequest{}
+    data, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return nil, err
+    }
+    err = json.Unmarshal(data, &pending)
+    return pending, err
+}
+
+// AcceptMessageRequest accepts a user as a contact, releasing any messages
+// they sent while pending.
+func AcceptMessageRequest(user uuid.UUID) error {
+    return answerMessageRequest(http.MethodPost, user)
+}
+
+// DeclineMessageRequest drops the pending messages from a user without
+// accepting them.
+func DeclineMessageRequest(user uuid.UUID) error {
+    return answerMessageRequest(http.MethodDelete, user)
+}
+
+func answerMessageRequest(method string, user uuid.UUID) error {
+    apiURL := viper.GetString("api_url")
+    httpClient := http.Client{}
+    // send request to server
+    req, err := http.NewRequest(method, apiURL+"/requests/"+user.String(), nil)
+    if err != nil {
+        return err
+    }
+    resp, err := doAuthenticated(&httpClient, req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    // check if request successful
+    if resp.StatusCode != http.StatusNoContent {
+        return fmt.Errorf("status %s: unable to answer message request", resp.Status)
+    }
+    return nil
+}
+
+func GetSettings() (Settings, error) {
+    return doSettings(http.MethodGet, nil)
+}
+
+func UpdateSettings(s Settings) (Settings, error) {
+    data, err := json.Marshal(s)
+    if err != nil {
+        return Settings{}, err
+    }
+    return doSettings(http.MethodPut, data)
+}
+
+func doSettings(method string, data []byte) (Settings, error) {
+    apiURL := viper.GetString("api_url")
+    httpClient := http.Client{}
+    s := Settings{}
+    // send request to server
+    req, err := http.NewRequest(method, apiURL+"/settings", bytes.NewBuffer(data))
+    if err != nil {
+        return s, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := doAuthenticated(&httpClient, req)
+    if err != nil {
+        return s, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return s, fmt.Errorf("status %s: unable to get settings", resp.Status)
+    }
+    respData, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return s, err
+    }
+    err = json.Unmarshal(respData, &s)
+    return s, err
+}
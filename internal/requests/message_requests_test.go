@@ -0,0 +1,70 @@
+package requests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/auth"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+func newRequestsServer(t *testing.T) (map[string]bool, *Settings) {
+    answered := make(map[string]bool)
+    settings := &Settings{}
+    server, _ := newRefreshServer(t, http.StatusOK)
+    mux := server.Config.Handler.(*http.ServeMux)
+    mux.HandleFunc("POST /requests/{userID}", func(w http.ResponseWriter, r *http.Request) {
+        answered[r.PathValue("userID")] = true
+        w.WriteHeader(http.StatusNoContent)
+    })
+    mux.HandleFunc("DELETE /requests/{userID}", func(w http.ResponseWriter, r *http.Request) {
+        answered[r.PathValue("userID")] = false
+        w.WriteHeader(http.StatusNoContent)
+    })
+    mux.HandleFunc("PUT /settings", func(w http.ResponseWriter, r *http.Request) {
+        if err := json.NewDecoder(r.Body).Decode(settings); err != nil {
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+        json.NewEncoder(w).Encode(settings)
+    })
+    token, err := auth.MakeJWT(uuid.New(), testSecret, time.Hour)
+    if err != nil {
+        t.Fatalf("error making JWT: %s", err)
+    }
+    viper.Set("access_token", token)
+    return answered, settings
+}
+
+func TestAnswerMessageRequest(t *testing.T) {
+    answered, _ := newRequestsServer(t)
+    accept, decline := uuid.New(), uuid.New()
+
+    if err := AcceptMessageRequest(accept); err != nil {
+        t.Fatalf("error accepting request: %s", err)
+    }
+    if err := DeclineMessageRequest(decline); err != nil {
+        t.Fatalf("error declining request: %s", err)
+    }
+    if accepted, ok := answered[accept.String()]; !ok || !accepted {
+        t.Error("request not accepted")
+    }
+    if accepted, ok := answered[decline.String()]; !ok || accepted {
+        t.Error("request not declined")
+    }
+}
+
+func TestUpdateSettings(t *testing.T) {
+    _, settings := newRequestsServer(t)
+
+    s, err := UpdateSettings(Settings{MessageRequests: true})
+    if err != nil {
+        t.Fatalf("error updating settings: %s", err)
+    }
+    if !s.MessageRequests || !settings.MessageRequests {
+        t.Fatal("message requests not enabled")
+    }
+}
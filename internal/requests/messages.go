@@ -11,6 +11,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/CraigYanitski/mescli/internal/client"
@@ -20,6 +21,10 @@ import (
 	"github.com/spf13/viper"
 )
 
+// sessionMu keeps sends and receives from advancing the session ratchets at
+// the same time, such as a typing signal sent while a message is sent
+var sessionMu sync.Mutex
+
 type UserKeyPacket struct {
     UserID         uuid.UUID  `json:"user_id"`
     IdentityKey    string     `json:"identity_key"`
@@ -43,19 +48,23 @@ type MessageResponse struct {
     SenderIdentityKey   sql.NullString  `json:"sender_identity_key"`
     SenderEphemeralKey  sql.NullString  `json:"sender_ephemeral_key"`
     Message             string          `json:"message"`
+    Payload             utils.Payload   `json:"-"`
 }
 
 func AddContact(email string) (*client.MessagePacketJSON, error) {
     apiURL := viper.GetString("api_url")
     httpClient := http.Client{}
     u := client.Client{}
-    u.Initialise(false)
+    err := u.Initialise(false)
+    if err != nil {
+        return nil, err
+    }
     // get contact key packet
     sender, err := GetUser(email)
-	senderID := &sender.ID
     if err != nil {
         return nil, err
     }
+	senderID := &sender.ID
 	// TODO: isolate rest of function to GetPacket(user uuid)
     keyReqStruct := UserResponse{ID: *senderID}
     data, err := json.Marshal(keyReqStruct)
@@ -68,11 +77,14 @@ func AddContact(email string) (*client.MessagePacketJSON, error) {
         return nil, err
     }
     keyReq.Header.Set("Content-Type", "application/json")
-    keyReq.Header.Set("Authorization", "Bearer "+viper.GetString("access_token"))
-    keyResp, err := httpClient.Do(keyReq)
+    keyResp, err := doAuthenticated(&httpClient, keyReq)
     if err != nil {
         return nil, err
     }
+    defer keyResp.Body.Close()
+    if keyResp.StatusCode == http.StatusForbidden {
+        return nil, ErrBlockedByContact
+    }
     senderKeys := &UserKeyPacket{}
     keyRespData, err := io.ReadAll(keyResp.Body)
     if err != nil {
@@ -88,7 +100,17 @@ func AddContact(email string) (*client.MessagePacketJSON, error) {
         SignedKey: senderKeys.SignedKey,
         OnetimePrekey: senderKeys.OnetimePrekey,
     }
-    messageJSON := u.InitiateX3DH(senderKeyPacket, *senderID, false)
+    messageJSON, err := u.InitiateX3DH(senderKeyPacket, *senderID, false)
+    if err != nil {
+        return nil, err
+    }
+    // keep the key exchange until it is delivered with the first message
+    viper.Set("contacts."+senderID.String()+".identity_key", messageJSON.IdentityKey)
+    viper.Set("contacts."+senderID.String()+".ephemeral_key", messageJSON.EphemeralKey)
+    err = viper.WriteConfig()
+    if err != nil {
+        return nil, err
+    }
     return messageJSON, nil
 }
 
@@ -106,8 +128,7 @@ func GetUserIdentityKey(user uuid.UUID) (*ecdsa.PublicKey, error) {
         return nil, err
     }
     userReq.Header.Set("Content-Type", "application/json")
-    userReq.Header.Set("Authorization", "Bearer "+viper.GetString("access_token"))
-    userResp, err := httpClient.Do(userReq)
+    userResp, err := doAuthenticated(&httpClient, userReq)
     if err != nil {
         return nil, err
     }
@@ -132,30 +153,35 @@ func SendEncryptedMessage(contactID uuid.UUID, contactX3DHpacket *client.Message
     apiURL := viper.GetString("api_url")
     httpClient := http.Client{}
     c := client.Client{}
-    c.Initialise(false)
+    err := c.Initialise(false)
+    if err != nil {
+        return err
+    }
+    if !c.LoadSession(contactID) {
+        return fmt.Errorf("no session with contact %s", contactID)
+    }
     // get contact identity key (only need identity key for exchanging messages)
     contactIK, err := GetUserIdentityKey(contactID)  // TODO: change input to string (and function to decode string)
     if err != nil {
         return fmt.Errorf("error getting contact identity key: %s", err)
     }
+    // a key exchange the contact has not yet answered is sent with every
+    // message
+    if contactX3DHpacket == nil {
+        contactX3DHpacket = pendingPacket(contactID)
+    }
     // encrypt message and marshal request JSON
     encryptedMsg, err := c.SendMessage(message, contactIK, contactID, false)
     if err != nil {
         return err
     }
-    // check messagePacketJSON
-    if contactX3DHpacket == nil {
-        contactX3DHpacket = &client.MessagePacketJSON{
-            IdentityKey: cryptography.EncodeECDSAPublicKey(contactIK),
-            EphemeralKey: "",
-        }
-    }
-    // marshal request JSON
     msg := MessageRequest{
         UserID: contactID,
         Message: encryptedMsg,
-        SenderIdentityKey: contactX3DHpacket.IdentityKey,
-        SenderEphemeralKey: contactX3DHpacket.EphemeralKey,
+    }
+    if contactX3DHpacket != nil {
+        msg.SenderIdentityKey = contactX3DHpacket.IdentityKey
+        msg.SenderEphemeralKey = contactX3DHpacket.EphemeralKey
     }
     msgData, err := json.Marshal(msg)
     if err != nil {
@@ -167,39 +193,114 @@ func SendEncryptedMessage(contactID uuid.UUID, contactX3DHpacket *client.Message
         return fmt.Errorf("error making message request: %s", err)
     }
     msgReq.Header.Set("Content-Type", "application/json")
-	msgReq.Header.Set("Authorization", "Bearer "+viper.GetString("access_token")) //TODO: store token in context
-    msgResp, err := httpClient.Do(msgReq)
+    msgResp, err := doAuthenticated(&httpClient, msgReq)
     if err != nil {
         return err
     }
     defer msgResp.Body.Close()
     // check if request successful
-    if msgResp.StatusCode != 201 {
+    if msgResp.StatusCode == http.StatusForbidden {
+        return ErrBlockedByContact
+    } else if msgResp.StatusCode != 201 {
         return errors.New("error: update not successful")
     }
-    return nil
+    // the ratchet only moves on once the message is delivered, and the key
+    // exchange is kept until the contact replies, in case they never read it
+    return c.SaveSendRatchet(contactID)
+}
+
+// pendingPacket returns a key exchange the contact has not yet answered.
+func pendingPacket(contactID uuid.UUID) *client.MessagePacketJSON {
+    ek := viper.GetString("contacts."+contactID.String()+".ephemeral_key")
+    if ek == "" {
+        return nil
+    }
+    return &client.MessagePacketJSON{
+        IdentityKey: viper.GetString("contacts."+contactID.String()+".identity_key"),
+        EphemeralKey: ek,
+    }
 }
 
+// SendMessage sends a Markdown message to a contact in a new text payload.
 func SendMessage(user, message string) error {
-	var uid *uuid.UUID
-	u, err := GetUser(user)
-	if err != nil {
-		return err
-	}
-	uid = &u.ID
-	user = u.Email
-	packet, err := AddContact(user)
-	if err != nil {
-		return err
-	}
-	err = SendEncryptedMessage(*uid, packet, message)
-	if err != nil {
-		return err
-	}
-	return nil
+    return SendPayload(user, utils.NewPayload(utils.TextPayload, message))
+}
+
+// SendPayload encrypts the payload for a contact and sends it, exchanging
+// keys only if there is no established session. It is shared by the CLI and
+// the TUI so both send exactly the same thing.
+func SendPayload(user string, p utils.Payload) error {
+    sessionMu.Lock()
+    defer sessionMu.Unlock()
+    message, err := utils.EncodePayload(p)
+    if err != nil {
+        return err
+    }
+    contacts, err := ReadContacts()
+    if err != nil {
+        return err
+    }
+    u, err := ResolveContact(contacts, user)
+    if err != nil {
+        return err
+    }
+    if u.Blocked {
+        return ErrBlocked
+    }
+    if err = checkIdentityKey(contacts, u); err != nil {
+        return err
+    }
+    var packet *client.MessagePacketJSON
+    if !client.HasSession(u.ID) {
+        packet, err = AddContact(u.ID.String())
+        if err != nil {
+            return err
+        }
+    }
+    return SendEncryptedMessage(u.ID, packet, message)
+}
+
+// checkIdentityKey compares the contact's identity key on the server with the
+// trusted fingerprint, recording a changed key in the contact book and
+// refusing to send until the user trusts it
+func checkIdentityKey(contacts map[string]Contact, u Contact) error {
+    ik, err := GetUserIdentityKey(u.ID)
+    if err != nil {
+        return fmt.Errorf("error getting contact identity key: %s", err)
+    }
+    updated := u
+    updated.UpdateFingerprint(cryptography.Fingerprint(ik))
+    if updated != u {
+        contacts[u.ID.String()] = updated
+        if err = WriteContacts(contacts); err != nil {
+            return err
+        }
+    }
+    if updated.KeyChanged() {
+        return ErrKeyChanged
+    }
+    return nil
+}
+
+// SendSignal sends an ephemeral signal, such as typing, to a contact with an
+// established session. It never starts a key exchange, so nothing is sent to
+// contacts without a session.
+func SendSignal(user uuid.UUID, p utils.Payload) error {
+    sessionMu.Lock()
+    defer sessionMu.Unlock()
+    if !client.HasSession(user) || pendingPacket(user) != nil {
+        return nil
+    }
+    message, err := utils.EncodePayload(p)
+    if err != nil {
+        return err
+    }
+    return SendEncryptedMessage(user, nil, message)
 }
 
 func GetMessages() (messages []MessageResponse, err error) {
+    sessionMu.Lock()
+    defer sessionMu.Unlock()
     messages = []MessageResponse{}
     apiURL := viper.GetString("api_url")
     httpClient := http.Client{}
@@ -211,8 +312,7 @@ func GetMessages() (messages []MessageResponse, err error) {
         return
     }
     req.Header.Set("Content-Type", "application/json")
-    req.Header.Set("Authorization", "Bearer "+viper.GetString("access_token"))
-    resp, err := httpClient.Do(req)
+    resp, err := doAuthenticated(&httpClient, req)
     if err != nil {
         return
     }
@@ -236,8 +336,10 @@ func GetMessages() (messages []MessageResponse, err error) {
         senderEncryptedMessages := viper.GetStringSlice("contacts."+message.SenderID.String()+".encrypted_messages")
         senderEncryptedMessages = append(senderEncryptedMessages, message.Message)
         viper.Set("contacts."+message.SenderID.String()+".encrypted_messages", senderEncryptedMessages)
-        // check if X3DH initiated
-        if message.SenderEphemeralKey.Valid && message.SenderIdentityKey.Valid {
+        // check if X3DH initiated, which is repeated until it is answered
+        key := "contacts."+message.SenderID.String()
+        if message.SenderEphemeralKey.Valid && message.SenderIdentityKey.Valid &&
+            message.SenderEphemeralKey.String != viper.GetString(key+".received_ephemeral_key") {
             err = c.CompleteX3DH(
                 &client.MessagePacketJSON{
                     IdentityKey: message.SenderIdentityKey.String,
@@ -251,6 +353,13 @@ func GetMessages() (messages []MessageResponse, err error) {
                 viper.Set("contacts."+message.SenderID.String()+".messages", senderMessages)
                 continue
             }
+            viper.Set(key+".received_ephemeral_key", message.SenderEphemeralKey.String)
+        }
+        // messages after the key exchange use the saved session
+        if !c.LoadSession(message.SenderID) {
+            senderMessages = append(senderMessages, message.Message)
+            viper.Set("contacts."+message.SenderID.String()+".messages", senderMessages)
+            continue
         }
         // get the sender identity key
         senderIK, err := GetUserIdentityKey(message.SenderID)
@@ -269,14 +378,104 @@ func GetMessages() (messages []MessageResponse, err error) {
             viper.Set("contacts."+message.SenderID.String()+".messages", senderMessages)
             continue
         }
+        // a message from the contact means they have our session
+        viper.Set(key+".identity_key", "")
+        viper.Set(key+".ephemeral_key", "")
+        message.Payload = utils.DecodePayload(decryptedMessage)
+        message.Message = message.Payload.Summary()
+        messages = append(messages, message)
+        // signals are not kept in the history
+        if message.Signal() {
+            senderEncryptedMessages = senderEncryptedMessages[:len(senderEncryptedMessages)-1]
+            viper.Set("contacts."+message.SenderID.String()+".encrypted_messages", senderEncryptedMessages)
+            continue
+        }
         senderMessages = append(senderMessages, decryptedMessage)
         viper.Set("contacts."+message.SenderID.String()+".messages", senderMessages)
-        messages = append(messages, message)
     }
     viper.WriteConfig()
     return
 }
 
+// SyncMessages fetches new messages from the server, appends them to the local
+// conversations and saves them to disk.
+func SyncMessages(conversations map[string][]utils.RawMessage) ([]MessageResponse, error) {
+    messages, err := FetchMessages()
+    if err != nil {
+        return nil, err
+    }
+    messages, _ = SplitSignals(messages)
+    return messages, StoreMessages(conversations, messages)
+}
+
+// Signal reports whether a message is an ephemeral signal, such as typing,
+// rather than part of the conversation.
+func (m MessageResponse) Signal() bool {
+    return m.Payload.Type == utils.TypingPayload
+}
+
+// SplitSignals separates ephemeral signals from the conversation messages.
+func SplitSignals(messages []MessageResponse) (content, signals []MessageResponse) {
+    content = []MessageResponse{}
+    for _, m := range messages {
+        if m.Signal() {
+            signals = append(signals, m)
+        } else {
+            content = append(content, m)
+        }
+    }
+    return content, signals
+}
+
+// FetchMessages fetches new messages from the server, dropping any from
+// blocked contacts.
+func FetchMessages() ([]MessageResponse, error) {
+    messages, err := GetMessages()
+    if err != nil {
+        return nil, err
+    }
+    contacts, err := ReadContacts()
+    if err != nil {
+        return nil, err
+    }
+    received := []MessageResponse{}
+    for _, m := range messages {
+        if !contacts[m.SenderID.String()].Blocked {
+            received = append(received, m)
+        }
+    }
+    return received, nil
+}
+
+// StoreMessages appends fetched messages to the local conversations and saves
+// them to disk.
+func StoreMessages(conversations map[string][]utils.RawMessage, messages []MessageResponse) error {
+    for _, m := range messages {
+        contact := m.SenderID.String()
+        p := m.Payload
+        if p.Type == "" {
+            p = utils.Payload{Type: utils.TextPayload, Body: m.Message}
+        }
+        conversations[contact] = utils.ApplyPayload(conversations[contact], utils.ContactType, p, m.CreatedAt)
+    }
+    if len(messages) > 0 && !WriteMessages(conversations) {
+        return errors.New("unable to save messages locally")
+    }
+    return nil
+}
+
+func ReadMessages() (map[string][]utils.RawMessage, error) {
+    messages := make(map[string][]utils.RawMessage)
+    msgBytes, err := os.ReadFile("./.messages")
+    if errors.Is(err, os.ErrNotExist) {
+        return messages, nil
+    } else if err != nil {
+        return messages, err
+    }
+    err = json.Unmarshal(msgBytes, &messages)
+    return messages, err
+}
+
 func WriteMessages(messages map[string][]utils.RawMessage) bool {
     messageBytes, err := json.MarshalIndent(messages, "", "    ")
     if err != nil {
@@ -291,3 +490,12 @@ func WriteMessages(messages map[string][]utils.RawMessage) bool {
     return true
 }
 
+
+// WipeHistory removes the locally stored conversations.
+func WipeHistory() error {
+    err := os.Remove("./.messages")
+    if err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    return nil
+}
@@ -0,0 +1,141 @@
+package requests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/auth"
+	"github.com/CraigYanitski/mescli/internal/client"
+	crypt "github.com/CraigYanitski/mescli/internal/cryptography"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+func TestSendRetryKeepsRatchet(t *testing.T) {
+    // sessions are saved in the config file
+    config := filepath.Join(t.TempDir(), ".mescli.yaml")
+    os.WriteFile(config, nil, 0600)
+    viper.SetConfigFile(config)
+    t.Cleanup(func() {
+        viper.Set("identity_key", "")
+        viper.Set("contacts", nil)
+    })
+    viper.Set("identity_key", "")
+    aliceID, bobID := uuid.New(), uuid.New()
+    alice := client.Client{}
+    if err := alice.Initialise(false); err != nil {
+        t.Fatalf("error initialising client: %s", err)
+    }
+    bob := &client.Client{}
+    bob.Initialise(true)
+    bobPacket, err := bob.SendPrekeyPacketJSON()
+    if err != nil {
+        t.Fatal(err)
+    }
+    packet, err := alice.InitiateX3DH(bobPacket, bobID, false)
+    if err != nil {
+        t.Fatal(err)
+    }
+    viper.Set("contacts."+bobID.String()+".identity_key", packet.IdentityKey)
+    viper.Set("contacts."+bobID.String()+".ephemeral_key", packet.EphemeralKey)
+
+    // the first send fails, the second is delivered
+    server, _ := newRefreshServer(t, http.StatusOK)
+    mux := server.Config.Handler.(*http.ServeMux)
+    mux.HandleFunc("GET /users/identity/{userID}", func(w http.ResponseWriter, r *http.Request) {
+        json.NewEncoder(w).Encode(UserKeyPacket{IdentityKey: crypt.EncodeECDSAPublicKey(bob.IdentityECDSA())})
+    })
+    posts := 0
+    var delivered MessageRequest
+    mux.HandleFunc("POST /messages", func(w http.ResponseWriter, r *http.Request) {
+        posts++
+        if posts == 1 {
+            w.WriteHeader(http.StatusInternalServerError)
+            return
+        }
+        json.NewDecoder(r.Body).Decode(&delivered)
+        w.WriteHeader(http.StatusCreated)
+    })
+    token, err := auth.MakeJWT(aliceID, testSecret, time.Hour)
+    if err != nil {
+        t.Fatalf("error making JWT: %s", err)
+    }
+    viper.Set("access_token", token)
+
+    ratchet := viper.GetString("contacts."+bobID.String()+".send_ratchet")
+    if err := SendEncryptedMessage(bobID, nil, "hello"); err == nil {
+        t.Fatal("expected the failed send to return an error")
+    }
+    if viper.GetString("contacts."+bobID.String()+".send_ratchet") != ratchet {
+        t.Fatal("ratchet saved after a failed send")
+    }
+    if err := SendEncryptedMessage(bobID, nil, "hello"); err != nil {
+        t.Fatalf("error sending message: %s", err)
+    }
+    if viper.GetString("contacts."+bobID.String()+".send_ratchet") == ratchet {
+        t.Fatal("ratchet not saved after delivery")
+    }
+
+    // the message carries the key exchange, which is kept until Bob replies
+    if delivered.SenderEphemeralKey != packet.EphemeralKey || pendingPacket(bobID) == nil {
+        t.Fatal("key exchange not kept after delivery")
+    }
+    if err := bob.CompleteX3DH(packet, aliceID, true); err != nil {
+        t.Fatal(err)
+    }
+    plaintext, err := bob.ReceiveMessage(delivered.Message, alice.IdentityECDSA(), aliceID, true)
+    if err != nil || plaintext != "hello" {
+        t.Fatalf("expected the message to decrypt, got %q: %v", plaintext, err)
+    }
+}
+
+func TestSendRefusesChangedKey(t *testing.T) {
+    // the contact book is in the working directory
+    wd, _ := os.Getwd()
+    os.Chdir(t.TempDir())
+    t.Cleanup(func() { os.Chdir(wd) })
+    bob := &client.Client{}
+    bob.Initialise(true)
+    bobID := uuid.New()
+    contacts := map[string]Contact{bobID.String(): {ID: bobID, Name: "Bob", Fingerprint: "trusted"}}
+    if err := WriteContacts(contacts); err != nil {
+        t.Fatal(err)
+    }
+
+    server, _ := newRefreshServer(t, http.StatusOK)
+    mux := server.Config.Handler.(*http.ServeMux)
+    mux.HandleFunc("GET /users/identity/{userID}", func(w http.ResponseWriter, r *http.Request) {
+        json.NewEncoder(w).Encode(UserKeyPacket{IdentityKey: crypt.EncodeECDSAPublicKey(bob.IdentityECDSA())})
+    })
+    posts := 0
+    mux.HandleFunc("POST /messages", func(w http.ResponseWriter, r *http.Request) {
+        posts++
+        w.WriteHeader(http.StatusCreated)
+    })
+    token, err := auth.MakeJWT(uuid.New(), testSecret, time.Hour)
+    if err != nil {
+        t.Fatalf("error making JWT: %s", err)
+    }
+    viper.Set("access_token", token)
+
+    // nothing is sent to a key that is not the trusted one
+    if err := SendMessage(bobID.String(), "hello"); !errors.Is(err, ErrKeyChanged) {
+        t.Fatalf("expected ErrKeyChanged, got %v", err)
+    }
+    if posts != 0 {
+        t.Fatal("expected no message to be sent")
+    }
+    saved, err := ReadContacts()
+    if err != nil {
+        t.Fatal(err)
+    }
+    c := saved[bobID.String()]
+    if c.Fingerprint != "trusted" || c.NewFingerprint != crypt.Fingerprint(bob.IdentityECDSA()) {
+        t.Fatalf("expected the changed key to be recorded apart, got %+v", c)
+    }
+}
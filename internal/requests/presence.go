@@ -0,0 +1,102 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// presence statuses
+const (
+    PresenceOnline   = "online"
+    PresenceAway     = "away"
+    PresenceOffline  = "offline"
+)
+
+// ErrNoPresence is returned for contacts who do not share their presence.
+var ErrNoPresence = errors.New("error: contact does not share their presence")
+
+type PresenceRequest struct {
+    Status  string  `json:"status"`
+}
+type PresenceResponse struct {
+    UserID    uuid.UUID  `json:"user_id"`
+    Status    string     `json:"status"`
+    LastSeen  time.Time  `json:"last_seen"`
+}
+
+// PublishPresence shares the user's status with their accepted contacts. The
+// config records whether the user is shown online or away, so that they can
+// be shown offline once they stop sharing it.
+func PublishPresence(status string) error {
+    apiURL := viper.GetString("api_url")
+    httpClient := http.Client{}
+    data, err := json.Marshal(PresenceRequest{Status: status})
+    if err != nil {
+        return err
+    }
+    // send request to server
+    req, err := http.NewRequest(http.MethodPut, apiURL+"/presence", bytes.NewBuffer(data))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := doAuthenticated(&httpClient, req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    // check if request successful
+    if resp.StatusCode != http.StatusNoContent {
+        return fmt.Errorf("status %s: unable to publish presence", resp.Status)
+    }
+    shared := status != PresenceOffline
+    if viper.GetBool("presence_shared") == shared {
+        return nil
+    }
+    viper.Set("presence_shared", shared)
+    return viper.WriteConfig()
+}
+
+// WithdrawPresence shows the user as offline if they were last shown online
+// or away, such as when the TUI quits or presence is turned off.
+func WithdrawPresence() error {
+    if !viper.GetBool("presence_shared") {
+        return nil
+    }
+    return PublishPresence(PresenceOffline)
+}
+
+func GetPresence(user uuid.UUID) (PresenceResponse, error) {
+    apiURL := viper.GetString("api_url")
+    httpClient := http.Client{}
+    p := PresenceResponse{}
+    // send request to server
+    req, err := http.NewRequest(http.MethodGet, apiURL+"/presence/"+user.String(), nil)
+    if err != nil {
+        return p, err
+    }
+    resp, err := doAuthenticated(&httpClient, req)
+    if err != nil {
+        return p, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode == http.StatusNotFound {
+        return p, ErrNoPresence
+    } else if resp.StatusCode != http.StatusOK {
+        return p, fmt.Errorf("status %s: unable to get presence", resp.Status)
+    }
+    data, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return p, err
+    }
+    err = json.Unmarshal(data, &p)
+    return p, err
+}
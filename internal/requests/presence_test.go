@@ -0,0 +1,55 @@
+package requests
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/auth"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+func TestWithdrawPresence(t *testing.T) {
+    // whether presence was shared is saved in the config file
+    config := filepath.Join(t.TempDir(), ".mescli.yaml")
+    os.WriteFile(config, nil, 0600)
+    viper.SetConfigFile(config)
+    t.Cleanup(func() { viper.Set("presence_shared", false) })
+    viper.Set("presence_shared", false)
+
+    server, _ := newRefreshServer(t, http.StatusOK)
+    mux := server.Config.Handler.(*http.ServeMux)
+    var published []string
+    mux.HandleFunc("PUT /presence", func(w http.ResponseWriter, r *http.Request) {
+        var p PresenceRequest
+        json.NewDecoder(r.Body).Decode(&p)
+        published = append(published, p.Status)
+        w.WriteHeader(http.StatusNoContent)
+    })
+    token, err := auth.MakeJWT(uuid.New(), testSecret, time.Hour)
+    if err != nil {
+        t.Fatalf("error making JWT: %s", err)
+    }
+    viper.Set("access_token", token)
+
+    // nothing is sent for a user who never shared their presence
+    if err := WithdrawPresence(); err != nil || len(published) != 0 {
+        t.Fatalf("expected no update, got %v: %v", published, err)
+    }
+    if err := PublishPresence(PresenceOnline); err != nil {
+        t.Fatalf("error publishing presence: %s", err)
+    }
+    // the user is shown offline once, when they stop sharing it
+    for i := 0; i < 2; i++ {
+        if err := WithdrawPresence(); err != nil {
+            t.Fatalf("error withdrawing presence: %s", err)
+        }
+    }
+    if len(published) != 2 || published[1] != PresenceOffline {
+        t.Fatalf("expected online then offline, got %v", published)
+    }
+}
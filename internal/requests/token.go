@@ -0,0 +1,146 @@
+package requests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// refresh the access token this long before it actually expires
+const refreshMargin = time.Minute
+
+// ErrSessionExpired is returned when the refresh token is revoked or expired,
+// at which point the user needs to login again.
+var ErrSessionExpired = errors.New("session expired: please login again")
+
+type RefreshResponse struct {
+    Token  string  `json:"token"`
+}
+
+// AccessToken returns a valid access token, refreshing it first if it is
+// missing or about to expire.
+func AccessToken() (string, error) {
+    token := viper.GetString("access_token")
+    if token == "" {
+        return RefreshAccessToken()
+    }
+    // check expiry of current token
+    exp, err := tokenExpiry(token)
+    if err != nil || time.Until(exp) < refreshMargin {
+        return RefreshAccessToken()
+    }
+    return token, nil
+}
+
+// RefreshAccessToken exchanges the stored refresh token for a new access
+// token and saves it in the config file.
+func RefreshAccessToken() (string, error) {
+    apiURL := viper.GetString("api_url")
+    httpClient := http.Client{}
+    refreshToken := viper.GetString("refresh_token")
+    if refreshToken == "" {
+        return "", ErrSessionExpired
+    }
+    // send refresh token to server
+    req, err := http.NewRequest(http.MethodPost, apiURL+"/refresh", nil)
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("Authorization", "Bearer "+refreshToken)
+    resp, err := httpClient.Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    // the refresh token itself is no longer valid
+    if resp.StatusCode == http.StatusUnauthorized {
+        clearSession()
+        return "", ErrSessionExpired
+    }
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("status %s: unable to refresh access token", resp.Status)
+    }
+    // read new token
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return "", err
+    }
+    token := &RefreshResponse{}
+    err = json.Unmarshal(body, token)
+    if err != nil {
+        return "", err
+    }
+    if token.Token == "" {
+        return "", errors.New("error: server returned an empty access token")
+    }
+    // update token in config file
+    viper.Set("access_token", token.Token)
+    viper.Set("last_refresh", time.Now().Unix())
+    viper.WriteConfig()
+    return token.Token, nil
+}
+
+// doAuthenticated sends a request with the current access token, refreshing
+// and retrying once if the server rejects the token.
+func doAuthenticated(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+    token, err := AccessToken()
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err := httpClient.Do(req)
+    if err != nil || resp.StatusCode != http.StatusUnauthorized {
+        return resp, err
+    }
+    resp.Body.Close()
+    // token was rejected, so refresh it and try again
+    token, err = RefreshAccessToken()
+    if err != nil {
+        return nil, err
+    }
+    retry := req.Clone(req.Context())
+    if req.GetBody != nil {
+        retry.Body, err = req.GetBody()
+        if err != nil {
+            return nil, err
+        }
+    }
+    retry.Header.Set("Authorization", "Bearer "+token)
+    return httpClient.Do(retry)
+}
+
+func tokenExpiry(token string) (time.Time, error) {
+    // the client cannot verify the signature, it only needs the expiry
+    claims := &jwt.RegisteredClaims{}
+    _, _, err := jwt.NewParser().ParseUnverified(token, claims)
+    if err != nil {
+        return time.Time{}, err
+    }
+    if claims.ExpiresAt == nil {
+        return time.Time{}, errors.New("error: access token has no expiry")
+    }
+    return claims.ExpiresAt.Time, nil
+}
+
+// tokenUser reads the user's own ID from their access token
+func tokenUser(token string) (uuid.UUID, error) {
+    claims := &jwt.RegisteredClaims{}
+    _, _, err := jwt.NewParser().ParseUnverified(token, claims)
+    if err != nil {
+        return uuid.Nil, err
+    }
+    return uuid.Parse(claims.Subject)
+}
+
+func clearSession() {
+    viper.Set("access_token", "")
+    viper.Set("refresh_token", "")
+    viper.WriteConfig()
+}
@@ -0,0 +1,123 @@
+package requests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/auth"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+const testSecret = "test token secret"
+
+func newRefreshServer(t *testing.T, refreshStatus int) (*httptest.Server, *int) {
+    refreshCount := 0
+    mux := http.NewServeMux()
+    mux.HandleFunc("POST /refresh", func(w http.ResponseWriter, r *http.Request) {
+        refreshCount++
+        if refreshStatus != http.StatusOK {
+            w.WriteHeader(refreshStatus)
+            return
+        }
+        token, err := auth.MakeJWT(uuid.New(), testSecret, time.Hour)
+        if err != nil {
+            t.Fatalf("error making JWT: %s", err)
+        }
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte(`{"token":"` + token + `"}`))
+    })
+    mux.HandleFunc("GET /messages", func(w http.ResponseWriter, r *http.Request) {
+        token, err := auth.GetBearerToken(r.Header)
+        if err != nil {
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+        if _, err = auth.ValidateJWT(token, testSecret); err != nil {
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+        w.WriteHeader(http.StatusOK)
+    })
+    server := httptest.NewServer(mux)
+    t.Cleanup(server.Close)
+    viper.Set("api_url", server.URL)
+    viper.Set("refresh_token", "refresh")
+    return server, &refreshCount
+}
+
+func TestAccessTokenValid(t *testing.T) {
+    _, refreshCount := newRefreshServer(t, http.StatusOK)
+    token, err := auth.MakeJWT(uuid.New(), testSecret, time.Hour)
+    if err != nil {
+        t.Fatalf("error making JWT: %s", err)
+    }
+    viper.Set("access_token", token)
+
+    got, err := AccessToken()
+    if err != nil {
+        t.Fatalf("error getting access token: %s", err)
+    }
+    if got != token || *refreshCount != 0 {
+        t.Fatalf("valid token was refreshed %d times", *refreshCount)
+    }
+}
+
+func TestAccessTokenProactiveRefresh(t *testing.T) {
+    _, refreshCount := newRefreshServer(t, http.StatusOK)
+    token, err := auth.MakeJWT(uuid.New(), testSecret, refreshMargin/2)
+    if err != nil {
+        t.Fatalf("error making JWT: %s", err)
+    }
+    viper.Set("access_token", token)
+
+    got, err := AccessToken()
+    if err != nil {
+        t.Fatalf("error getting access token: %s", err)
+    }
+    if got == token || *refreshCount != 1 {
+        t.Fatalf("expiring token not refreshed (%d refreshes)", *refreshCount)
+    }
+    if viper.GetString("access_token") != got {
+        t.Fatal("refreshed token not saved in config")
+    }
+}
+
+func TestRefreshTokenRevoked(t *testing.T) {
+    newRefreshServer(t, http.StatusUnauthorized)
+    viper.Set("access_token", "")
+
+    _, err := AccessToken()
+    if !errors.Is(err, ErrSessionExpired) {
+        t.Fatalf("expected session expired error, got: %v", err)
+    }
+    if viper.GetString("refresh_token") != "" {
+        t.Fatal("revoked refresh token not removed from config")
+    }
+}
+
+func TestReactiveRefresh(t *testing.T) {
+    server, refreshCount := newRefreshServer(t, http.StatusOK)
+    // token looks valid to the client but has the wrong signature
+    token, err := auth.MakeJWT(uuid.New(), "another secret", time.Hour)
+    if err != nil {
+        t.Fatalf("error making JWT: %s", err)
+    }
+    viper.Set("access_token", token)
+
+    req, err := http.NewRequest(http.MethodGet, server.URL+"/messages", nil)
+    if err != nil {
+        t.Fatalf("error making request: %s", err)
+    }
+    resp, err := doAuthenticated(&http.Client{}, req)
+    if err != nil {
+        t.Fatalf("error sending request: %s", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || *refreshCount != 1 {
+        t.Fatalf("request not retried after refresh: %s (%d refreshes)", resp.Status, *refreshCount)
+    }
+}
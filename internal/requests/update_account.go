@@ -11,12 +11,13 @@ import (
 )
 
 type UpdateRequest struct {
-    Email         string  `json:"email"`
-    Password      string  `json:"password"`
-    Name          string  `json:"name"`
-    IdentityKey   string  `json:"identity_key"`
-    SignedPrekey  string  `json:"signed_prekey"`
-    SignedKey     string  `json:"signed_key"`
+    Email          string  `json:"email"`
+    Password       string  `json:"password"`
+    Name           string  `json:"name"`
+    IdentityKey    string  `json:"identity_key"`
+    SignedPrekey   string  `json:"signed_key"`
+    SignedKey      string  `json:"signed_prekey"`
+    OnetimePrekey  string  `json:"onetime_prekey"`
 }
 
 
@@ -29,6 +30,8 @@ func UpdateAccount(name, email, password string) error {
     spk := crypt.DecodeECDHPrivateKey(viper.GetString("signed_prekey"))
     SPK := crypt.EncodeECDHPublicKey(spk.PublicKey())
     SK := viper.GetString("signed_key")
+    opk := crypt.DecodeECDHPrivateKey(viper.GetString("onetime_prekey"))
+    OPK := crypt.EncodeECDHPublicKey(opk.PublicKey())
     // create JSON to send as request
     user := UpdateRequest{
         Email: email,
@@ -37,6 +40,7 @@ func UpdateAccount(name, email, password string) error {
         IdentityKey: IK,
         SignedPrekey: SPK,
         SignedKey: SK,
+        OnetimePrekey: OPK,
     }
     data, err := json.Marshal(user)
     if err != nil {
@@ -49,19 +53,19 @@ func UpdateAccount(name, email, password string) error {
         return err
     }
     req.Header.Set("Content-Type", "application/json")
-    req.Header.Set("Authorization", "Bearer "+viper.GetString("access_token"))
-    resp, err := client.Do(req)
+    resp, err := doAuthenticated(client, req)
     if err != nil {
         return err
     }
     defer resp.Body.Close()
     // check if request successful
-    if resp.StatusCode != 200 {
+    if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
         return errors.New("error: update not successful")
     }
     // save config changes
     viper.Set("name", name)
     viper.Set("email", email)
+    viper.WriteConfig()
     return nil
 }
 
@@ -0,0 +1,190 @@
+package tui
+
+import (
+	"errors"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+const (
+    // typing is signalled again after this long, and stops after this long
+    // without an edit
+    typingRefresh  = 5 * time.Second
+    typingIdle     = 4 * time.Second
+    // a contact's typing is shown until this long after their last signal,
+    // which arrives with the polls
+    typingExpiry   = pollInterval + 3*typingRefresh
+    // the user is shown as away after this long without a key press
+    awayAfter      = 5 * time.Minute
+)
+
+type typingIdleMsg struct {
+    id  int
+}
+
+type presenceMsg struct {
+    conversation  string
+    presence      requests.PresenceResponse
+    err           error
+}
+
+// updateActivity tracks the user's activity and the typing and presence of
+// their contacts
+func updateActivity(msg tea.Msg, m Model) (Model, tea.Cmd, bool) {
+    switch msg := msg.(type) {
+    case tea.KeyMsg:
+        m.lastInput = time.Now()
+    case typingIdleMsg:
+        if msg.id == m.typingCount {
+            m, cmd := stopTyping(m)
+            return m, cmd, true
+        }
+        return m, nil, true
+    case presenceMsg:
+        if msg.err == nil {
+            m.presence[msg.conversation] = msg.presence
+        } else if errors.Is(msg.err, requests.ErrNoPresence) {
+            delete(m.presence, msg.conversation)
+        }
+        return m, nil, true
+    }
+    return m, nil, false
+}
+
+// typed signals that the user is typing in the open conversation, to
+// contacts who have replied to them, and stops after a pause
+func typed(m Model) (Model, tea.Cmd) {
+    if !m.settings.typingIndicators || !replied(m) {
+        return m, nil
+    }
+    if m.textarea.Value() == "" {
+        return stopTyping(m)
+    }
+    m.typingCount++
+    id := m.typingCount
+    idle := tea.Tick(typingIdle, func(time.Time) tea.Msg {
+        return typingIdleMsg{id: id}
+    })
+    if m.typingTo == m.conversation && time.Since(m.typingSent) < typingRefresh {
+        return m, idle
+    }
+    m, stop := stopTyping(m)
+    m.typingTo = m.conversation
+    m.typingSent = time.Now()
+    return m, tea.Batch(idle, stop, sendSignal(m.conversation, utils.TypingStarted))
+}
+
+func stopTyping(m Model) (Model, tea.Cmd) {
+    if m.typingTo == "" {
+        return m, nil
+    }
+    conversation := m.typingTo
+    m.typingTo = ""
+    return m, sendSignal(conversation, utils.TypingStopped)
+}
+
+// replied reports whether the contact has sent a message in the conversation,
+// so signals are not sent to people who have not accepted the user
+func replied(m Model) bool {
+    for _, msg := range m.cfg.Messages[m.conversation] {
+        if msg.Sender == utils.ContactType {
+            return true
+        }
+    }
+    return false
+}
+
+// sendSignal sends a typing signal, ignoring failures since it is ephemeral
+func sendSignal(conversation, body string) tea.Cmd {
+    id, err := uuid.Parse(conversation)
+    if err != nil {
+        return nil
+    }
+    return background(func() tea.Msg {
+        requests.SendSignal(id, utils.NewPayload(utils.TypingPayload, body))
+        return nil
+    })
+}
+
+// applySignals records which contacts are typing, in the order the messages
+// were sent, since any other message ends their typing
+func applySignals(m Model, messages []requests.MessageResponse) Model {
+    for _, msg := range messages {
+        id := msg.SenderID.String()
+        if msg.Signal() && msg.Payload.Body == utils.TypingStarted {
+            m.typing[id] = time.Now().Add(typingExpiry)
+        } else {
+            delete(m.typing, id)
+        }
+    }
+    return m
+}
+
+// presenceCmd publishes the user's presence and fetches that of the open
+// conversation, if they opted in to sharing it
+func presenceCmd(m Model) tea.Cmd {
+    if !m.settings.presence {
+        return nil
+    }
+    status := requests.PresenceOnline
+    if time.Since(m.lastInput) >= awayAfter {
+        status = requests.PresenceAway
+    }
+    cmds := []tea.Cmd{background(func() tea.Msg {
+        requests.PublishPresence(status)
+        return nil
+    })}
+    if id, err := uuid.Parse(m.conversation); err == nil {
+        conversation := m.conversation
+        cmds = append(cmds, background(func() tea.Msg {
+            p, err := requests.GetPresence(id)
+            return presenceMsg{conversation: conversation, presence: p, err: err}
+        }))
+    }
+    return tea.Batch(cmds...)
+}
+
+// withdrawPresence shows the user offline when they no longer share their
+// presence, having shared it before
+func withdrawPresence(m Model) tea.Cmd {
+    if m.settings.presence {
+        return nil
+    }
+    return background(func() tea.Msg {
+        requests.WithdrawPresence()
+        return nil
+    })
+}
+
+// Offline shows the user offline once the TUI has quit, waiting for any
+// network work still running.
+func Offline() error {
+    configLock.Lock()
+    defer configLock.Unlock()
+    return requests.WithdrawPresence()
+}
+
+// activity describes whether the contact is typing, or their presence
+func activity(m Model) string {
+    if time.Now().Before(m.typing[m.conversation]) {
+        return "typing…"
+    }
+    if !m.settings.presence {
+        return ""
+    }
+    p, ok := m.presence[m.conversation]
+    if !ok {
+        return ""
+    }
+    switch p.Status {
+    case requests.PresenceOnline:
+        return "online"
+    case requests.PresenceAway:
+        return "away"
+    }
+    return "last seen " + messageTime(p.LastSeen)
+}
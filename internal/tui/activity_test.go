@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+func TestTyping(t *testing.T) {
+    // received messages are saved to the working directory
+    wd, _ := os.Getwd()
+    os.Chdir(t.TempDir())
+    t.Cleanup(func() { os.Chdir(wd) })
+    alice := uuid.New()
+    cfg := &ApiConfig{
+        Messages: map[string][]utils.RawMessage{alice.String(): {}},
+        Contacts: map[string]requests.Contact{
+            alice.String(): {ID: alice, Name: "Alice"},
+        },
+    }
+    m := InitialModel(cfg).resize(80, 40)
+    m.loggedIn = true
+    m.Chosen = 1
+    m.conversation = alice.String()
+    m.textarea.Focus()
+    var cmd tea.Cmd
+    update := func(msg tea.Msg) {
+        var next tea.Model
+        next, cmd = m.Update(msg)
+        m = next.(Model)
+    }
+    signal := func(body string) requests.MessageResponse {
+        p := utils.NewPayload(utils.TypingPayload, body)
+        return requests.MessageResponse{SenderID: alice, Message: p.Summary(), Payload: p}
+    }
+
+    // contacts who have not replied are not told the user is typing
+    m.settings.typingIndicators = true
+    update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("h")})
+    if m.typingTo != "" {
+        t.Fatal("expected no typing signal before alice replies")
+    }
+
+    // typing signals are shown in the header but not stored
+    text := utils.NewPayload(utils.TextPayload, "hi")
+    update(messagesMsg{messages: []requests.MessageResponse{
+        {SenderID: alice, Message: text.Body, Payload: text},
+        signal(utils.TypingStarted),
+    }})
+    if !strings.Contains(conversationHeader(m), "typing") {
+        t.Fatal("expected alice to be shown typing")
+    }
+    if msgs := cfg.Messages[alice.String()]; len(msgs) != 1 || msgs[0].Message != "hi" {
+        t.Fatalf("expected only the text to be stored, got %+v", msgs)
+    }
+    update(messagesMsg{messages: []requests.MessageResponse{signal(utils.TypingStopped)}})
+    if strings.Contains(conversationHeader(m), "typing") {
+        t.Fatal("expected alice to stop typing")
+    }
+    m = applySignals(m, []requests.MessageResponse{signal(utils.TypingStarted)})
+    m.typing[alice.String()] = time.Now().Add(-time.Second)
+    if strings.Contains(conversationHeader(m), "typing") {
+        t.Fatal("expected the typing indicator to expire")
+    }
+
+    // typing is signalled once, then stops after a pause
+    update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+    if m.typingTo != alice.String() || cmd == nil {
+        t.Fatal("expected the user to be shown typing")
+    }
+    sent := m.typingSent
+    update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+    if m.typingSent != sent {
+        t.Fatal("expected typing not to be signalled again so soon")
+    }
+    update(typingIdleMsg{id: m.typingCount - 1})
+    if m.typingTo == "" {
+        t.Fatal("expected an earlier pause not to stop typing")
+    }
+    update(typingIdleMsg{id: m.typingCount})
+    if m.typingTo != "" || cmd == nil {
+        t.Fatal("expected typing to stop after a pause")
+    }
+
+    // typing can be turned off
+    m.settings.typingIndicators = false
+    update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("!")})
+    if m.typingTo != "" {
+        t.Fatal("expected no typing signal when turned off")
+    }
+
+    // presence is only shown when sharing it
+    m.presence[alice.String()] = requests.PresenceResponse{Status: requests.PresenceAway}
+    if strings.Contains(conversationHeader(m), "away") {
+        t.Fatal("expected presence to be hidden when not shared")
+    }
+    m.settings.presence = true
+    if !strings.Contains(conversationHeader(m), "away") {
+        t.Fatal("expected alice to be shown away")
+    }
+    update(presenceMsg{conversation: alice.String(), err: requests.ErrNoPresence})
+    if conversationHeader(m) != conversationStyle.Render("Alice") {
+        t.Fatal("expected no presence for alice")
+    }
+}
@@ -0,0 +1,173 @@
+package tui
+
+import (
+	"sync"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+// results of the network calls run away from the update loop
+type (
+    loginResultMsg   struct{ err error }
+    createResultMsg  struct{ err error }
+    updateResultMsg  struct{ err error }
+    deleteResultMsg  struct{ err error }
+    sendResultMsg    struct {
+        conversation  string
+        err           error
+    }
+    inboxResultMsg   struct {
+        pending  []requests.PendingRequest
+        err      error
+    }
+    answerResultMsg  struct {
+        id       string
+        contact  requests.Contact
+        block    bool
+        status   string
+        err      error
+    }
+)
+
+// configLock is held by the commands run away from the update loop, which
+// read and write the tokens and session ratchets in viper. The update loop
+// itself reads its settings once, at start-up.
+var configLock sync.Mutex
+
+// background runs a command holding the config lock
+func background(cmd tea.Cmd) tea.Cmd {
+    return func() tea.Msg {
+        configLock.Lock()
+        defer configLock.Unlock()
+        return cmd()
+    }
+}
+
+func login(email, password string) tea.Cmd {
+    return background(func() tea.Msg {
+        return loginResultMsg{err: requests.LoginWithPassword(email, password)}
+    })
+}
+
+func createAccount(name, email, password string) tea.Cmd {
+    return background(func() tea.Msg {
+        return createResultMsg{err: requests.CreateAccount(name, email, password)}
+    })
+}
+
+func updateAccount(name, email, password string) tea.Cmd {
+    return background(func() tea.Msg {
+        return updateResultMsg{err: requests.UpdateAccount(name, email, password)}
+    })
+}
+
+func deleteAccount(password string) tea.Cmd {
+    return background(func() tea.Msg {
+        return deleteResultMsg{err: requests.DeleteAccount(password)}
+    })
+}
+
+func sendMessage(conversation string, ps ...utils.Payload) tea.Cmd {
+    return background(func() tea.Msg {
+        for _, p := range ps {
+            if err := requests.SendPayload(conversation, p); err != nil {
+                return sendResultMsg{conversation: conversation, err: err}
+            }
+        }
+        return sendResultMsg{conversation: conversation}
+    })
+}
+
+var fetchInbox = background(func() tea.Msg {
+    pending, err := requests.GetMessageRequests()
+    return inboxResultMsg{pending: pending, err: err}
+})
+
+// answer accepts, declines or blocks the sender of a message request,
+// fetching their details for the contact book when they are kept
+func answer(id uuid.UUID, name string, accept, block bool) tea.Cmd {
+    return background(func() tea.Msg {
+        var (
+            c       requests.Contact
+            err     error
+            status  string
+        )
+        switch {
+        case accept:
+            err = requests.AcceptMessageRequest(id)
+            if err == nil {
+                c, err = requests.FetchContact(id.String())
+            }
+            status = "Accepted " + name
+        case block:
+            err = requests.DeclineMessageRequest(id)
+            if err == nil {
+                err = requests.BlockUser(id)
+            }
+            if err == nil {
+                c, err = requests.FetchContact(id.String())
+            }
+            status = "Blocked " + name
+        default:
+            err = requests.DeclineMessageRequest(id)
+            status = "Declined " + name
+        }
+        return answerResultMsg{id: id.String(), contact: c, block: block, status: status, err: err}
+    })
+}
+
+// startPending shows the spinner with a description of the work in progress
+func startPending(m Model, description string, cmd tea.Cmd) (Model, tea.Cmd) {
+    m.pending = description
+    return m, tea.Batch(m.spinner.Tick, cmd)
+}
+
+// updateSpinner keeps the spinner turning while network work is pending
+func updateSpinner(msg tea.Msg, m Model) (Model, tea.Cmd, bool) {
+    tick, ok := msg.(spinner.TickMsg)
+    if !ok {
+        return m, nil, false
+    }
+    if m.pending == "" {
+        return m, nil, true
+    }
+    var cmd tea.Cmd
+    m.spinner, cmd = m.spinner.Update(tick)
+    return m, cmd, true
+}
+
+// pendingStatus shows the pending work, or else the given message
+func pendingStatus(m Model, msg string) string {
+    if m.pending != "" {
+        return m.spinner.View() + " " + m.pending
+    }
+    return msg
+}
+
+// updateResults handles the results of network calls whatever screen is
+// shown, since the user may have moved on while they were pending
+func updateResults(msg tea.Msg, m Model) (Model, tea.Cmd, bool) {
+    switch msg := msg.(type) {
+    case loginResultMsg:
+        return loginResult(msg, m), nil, true
+    case createResultMsg:
+        return createResult(msg, m), nil, true
+    case updateResultMsg:
+        return updateResult(msg, m), nil, true
+    case deleteResultMsg:
+        return deleteResult(msg, m), nil, true
+    case sendResultMsg:
+        return sendResult(msg, m), nil, true
+    case inboxResultMsg:
+        return inboxResult(msg, m), nil, true
+    case answerResultMsg:
+        return answerResult(msg, m), nil, true
+    case newConversationMsg:
+        return newConversationResult(msg, m), nil, true
+    }
+    return m, nil, false
+}
@@ -18,16 +18,26 @@ func updateChoices(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
         case key.Matches(msg, m.keys.Back):
             m.loggedIn = false
             return m, nil
+        case key.Matches(msg, m.keys.NewConversation):
+            return openNew(m), nil
         case key.Matches(msg, m.keys.Enter):
             o, _ := m.options.SelectedItem().(option)
             m.Chosen = o.o
             if m.Chosen == 2 {
                 m.updated = false
                 m.Chosen = 0
-                m.updateMsg = fmt.Sprintf(updateMsgWrapping, "")
+                m.updateMsg = ""
             } else if m.Chosen == 3 {
                 m.Quitting = true
                 return m, tea.Quit
+            } else if m.Chosen == 4 {
+                m.deleteMsg = ""
+                m.deleteInput.Focus()
+            } else if m.Chosen == 5 {
+                return openInbox(m)
+            } else if m.Chosen == 6 {
+                m = openNew(m)
+                return m, nil
             }
         }
     case tea.WindowSizeMsg:
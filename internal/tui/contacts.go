@@ -2,13 +2,22 @@ package tui
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
 )
 
+// number of characters of the last message shown in the contact list
+const previewLength = 32
+
 func updateContacts(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
     switch msg := msg.(type) {
     case tea.KeyMsg:
@@ -22,10 +31,14 @@ func updateContacts(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
         // case key.Matches(msg, m.keys.findOption):
         //     m.search = true
         //     return m, nil
+        case key.Matches(msg, m.keys.NewConversation):
+            return openNew(m), nil
         case key.Matches(msg, m.keys.Enter):
             c, _ := m.contacts.SelectedItem().(contact)
-            m.conversation = c.name
+            m.conversation = c.id
+            m = resetActions(m)
             m = initialiseConversation(m)
+            m = markRead(m)
         }
     case tea.WindowSizeMsg:
         m = m.resize(msg.Width, msg.Height)
@@ -49,16 +62,17 @@ func contactsView(m Model) string {
 }
 
 func initialiseConversation(m Model) Model {
-    // read conversation if it exists
+    // render the whole conversation, since edits, deletions and reactions
+    // change earlier messages
     msgs := m.cfg.Messages[m.conversation]
-    if msgView := m.messages[m.conversation]; len(msgView) < len(msgs) {
-        for i, msg := range msgs {
-            if i < len(msgView) {
-                continue
-            }
-            m.messages[m.conversation] = append(m.messages[m.conversation], renderMessage(m, msg))
+    rendered := make([]string, len(msgs))
+    for i, msg := range msgs {
+        rendered[i] = renderMessage(m, msg)
+        if m.selecting && i == m.selected {
+            rendered[i] = selectedMessageStyle.Render(rendered[i])
         }
     }
+    m.messages[m.conversation] = rendered
     // Wrap content before setting it
     if len(m.messages[m.conversation]) > 0 {
         m.viewport.SetContent(lipgloss.NewStyle().Width(m.viewport.Width).
@@ -68,7 +82,106 @@ func initialiseConversation(m Model) Model {
             "Welcome to the chat room!\nType a message and press Enter to send.",
         ))
     }
+    if m.selecting {
+        return scrollToSelection(m)
+    }
     m.viewport.GotoBottom()
     return m
 }
 
+
+// contactItems builds the contact list from the stored conversations and the
+// contact book, most recently active first, leaving out blocked contacts
+func contactItems(cfg *ApiConfig) []list.Item {
+    contacts := []contact{}
+    for id, c := range cfg.Contacts {
+        if c.Blocked {
+            continue
+        }
+        contacts = append(contacts, newContactItem(id, c, cfg.Messages[id]))
+    }
+    for id, msgs := range cfg.Messages {
+        if _, ok := cfg.Contacts[id]; !ok {
+            contacts = append(contacts, newContactItem(id, requests.Contact{}, msgs))
+        }
+    }
+    sort.Slice(contacts, func(i, j int) bool {
+        if !contacts[i].latest.Equal(contacts[j].latest) {
+            return contacts[i].latest.After(contacts[j].latest)
+        }
+        return strings.ToLower(contacts[i].name) < strings.ToLower(contacts[j].name)
+    })
+    items := make([]list.Item, len(contacts))
+    for i, c := range contacts {
+        items[i] = c
+    }
+    return items
+}
+
+func newContactItem(id string, c requests.Contact, msgs []utils.RawMessage) contact {
+    item := contact{id: id, name: c.DisplayName(), unread: c.Unread(msgs)}
+    if c.ID == uuid.Nil {
+        item.name = id
+    }
+    if len(msgs) == 0 {
+        item.desc = "No messages (yet)"
+        if c.Email != "" {
+            item.desc = c.Email
+        }
+        return item
+    }
+    last := msgs[len(msgs)-1]
+    item.latest = last.Time
+    preview := messagePreview(last.Message, previewLength)
+    if last.Sender == utils.SelfType {
+        preview = "You: " + preview
+    }
+    item.desc = preview + dotStyle + messageTime(last.Time)
+    return item
+}
+
+// messagePreview returns the first line of a message, shortened to a number
+// of characters
+func messagePreview(message string, length int) string {
+    line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+    runes := []rune(line)
+    if len(runes) > length {
+        return string(runes[:length-1]) + "…"
+    }
+    return line
+}
+
+// messageTime shows the time of day for messages sent today, otherwise the date
+func messageTime(t time.Time) string {
+    now := time.Now()
+    if y, m, d := t.Date(); y == now.Year() && m == now.Month() && d == now.Day() {
+        return t.Format("15:04")
+    }
+    return t.Format("02-01-2006")
+}
+
+// markRead records the open conversation as read in the contact book
+func markRead(m Model) Model {
+    id, err := uuid.Parse(m.conversation)
+    if err != nil {
+        return m
+    }
+    if m.cfg.Contacts == nil {
+        m.cfg.Contacts = make(map[string]requests.Contact)
+    }
+    c := m.cfg.Contacts[m.conversation]
+    c.ID = id
+    c.LastRead = time.Now()
+    m.cfg.Contacts[m.conversation] = c
+    _ = requests.WriteContacts(m.cfg.Contacts)
+    m.contacts.SetItems(contactItems(m.cfg))
+    return m
+}
+
+// contactName returns the display name of the open conversation
+func contactName(m Model) string {
+    if c, ok := m.cfg.Contacts[m.conversation]; ok {
+        return c.DisplayName()
+    }
+    return m.conversation
+}
@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/google/uuid"
+)
+
+func TestContactItems(t *testing.T) {
+    now := time.Now()
+    alice, bob, carol, dave := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+    cfg := &ApiConfig{
+        Contacts: map[string]requests.Contact{
+            alice.String(): {ID: alice, Name: "Alice", LastRead: now.Add(-2*time.Hour)},
+            bob.String(): {ID: bob, Name: "Bob", Blocked: true},
+            dave.String(): {ID: dave, Email: "dave@example.com"},
+        },
+        Messages: map[string][]utils.RawMessage{
+            alice.String(): {
+                {Sender: utils.ContactType, Message: "old", Time: now.Add(-3*time.Hour)},
+                {Sender: utils.ContactType, Message: "first line\nsecond line", Time: now.Add(-time.Hour)},
+            },
+            bob.String(): {
+                {Sender: utils.ContactType, Message: "blocked", Time: now},
+            },
+            carol.String(): {
+                {Sender: utils.SelfType, Message: strings.Repeat("x", 2*previewLength), Time: now.Add(-time.Minute)},
+            },
+        },
+    }
+
+    items := contactItems(cfg)
+    if len(items) != 3 {
+        t.Fatalf("expected 3 contacts without the blocked one, got %d", len(items))
+    }
+    order := []string{carol.String(), alice.String(), dave.String()}
+    for i, id := range order {
+        if c := items[i].(contact); c.id != id {
+            t.Errorf("expected %s at position %d, got %s", id, i, c.id)
+        }
+    }
+
+    carolItem := items[0].(contact)
+    if carolItem.name != carol.String() || !strings.HasPrefix(carolItem.desc, "You: ") ||
+        !strings.Contains(carolItem.desc, "…") {
+        t.Errorf("unexpected item for unknown contact: %+v", carolItem)
+    }
+    aliceItem := items[1].(contact)
+    if aliceItem.unread != 1 || !strings.Contains(aliceItem.desc, "first line") ||
+        strings.Contains(aliceItem.desc, "second line") {
+        t.Errorf("unexpected item for alice: %+v", aliceItem)
+    }
+    if daveItem := items[2].(contact); daveItem.desc != "dave@example.com" {
+        t.Errorf("expected email description for contact without messages, got %q", daveItem.desc)
+    }
+}
+
+func TestMessagePreview(t *testing.T) {
+    cases := []struct {
+        message  string
+        want     string
+    }{
+        {"hello", "hello"},
+        {"  hello\nworld", "hello"},
+        {"héllo wörld", "héllo…"},
+    }
+    for _, c := range cases {
+        if got := messagePreview(c.message, 6); got != c.want {
+            t.Errorf("preview of %q: expected %q, got %q", c.message, c.want, got)
+        }
+    }
+}
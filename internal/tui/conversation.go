@@ -2,22 +2,31 @@ package tui
 
 import (
 	"fmt"
-	"log"
 	"strings"
-	"time"
+	"unicode/utf8"
 
-	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/maths"
 	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
 )
 
 func updateConversation(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
+    if m.selecting {
+        return updateSelection(msg, m)
+    }
+    if msg, ok := msg.(tea.KeyMsg); ok && key.Matches(msg, m.keys.Select) {
+        return startSelection(m), nil
+    }
+
     var (
         tiCmd tea.Cmd
         vpCmd tea.Cmd
     )
 
+    draft := m.textarea.Value()
     m.textarea, tiCmd = m.textarea.Update(msg)
     m.viewport, vpCmd = m.viewport.Update(msg)
 
@@ -32,67 +41,135 @@ func updateConversation(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
     case tea.WindowSizeMsg:
         m = m.resize(msg.Width, msg.Height)
     case tea.KeyMsg:
-        switch msg.Type {
-        case tea.KeyCtrlC:
-            //fmt.Println(m.textarea.Value())
+        switch {
+        case key.Matches(msg, m.keys.ForceQuit):
             m.Quitting = true
             return m, tea.Quit
-        case tea.KeyEsc:
+        case key.Matches(msg, m.keys.Cancel):
+            // esc first cancels a reply or edit
+            if m.editing != "" {
+                m.textarea.Reset()
+            }
+            if m.replyTo != "" || m.editing != "" {
+                m = resetActions(m)
+                return m, nil
+            }
+            m = markRead(m)
             m.conversation = ""
+            m.sendMsg = ""
             return m, nil
-        case tea.KeyCtrlH:
+        case key.Matches(msg, m.keys.ConversationHelp):
             m.viewHelp = true
-        case tea.KeyEnter:
-            if strings.TrimSpace(m.textarea.Value()) != "" {
-                rawMsg := utils.RawMessage{
-                    Sender: utils.SelfType,
-                    Message: strings.TrimSpace(m.textarea.Value()),
-                    Time: time.Now(),
-                }
-                m.cfg.Messages[m.conversation] = append(
-                    m.cfg.Messages[m.conversation], 
-                    rawMsg,
-                )
-                message := renderMessage(m, rawMsg)
-                m.messages[m.conversation] = append(
-                    m.messages[m.conversation], 
-                    message,
-                )
-				err := requests.SendMessage(m.conversation, message)
-				if err != nil {
-					m.messages[m.conversation] = append(
-						m.messages[m.conversation], 
-						"Send failed...",
-					)
-				}
-                ok := requests.WriteMessages(m.cfg.Messages)
-                if !ok {
-                    log.Fatal("error writing messages")
-                }
-                m.viewport.SetContent(strings.Join(m.messages[m.conversation], "\n"))
-                m.textarea.Reset()
-                m.viewport.GotoBottom()
-            } else {
-                m.textarea.Reset()
+        case key.Matches(msg, m.keys.Editor):
+            return openEditor(m)
+        case key.Matches(msg, m.keys.Send):
+            // one message is sent at a time so the session ratchets stay in order
+            if m.pending != "" {
+                return m, tea.Batch(tiCmd, vpCmd)
             }
+            return sendDraft(m)
         }
     case errMsg:
         m.err = msg
         return m, nil
     }
 
-    return m, tea.Batch(tiCmd, vpCmd)
+    var typingCmd tea.Cmd
+    if m.textarea.Value() != draft {
+        m, typingCmd = typed(m)
+    }
+    return m, tea.Batch(tiCmd, vpCmd, typingCmd)
+}
+
+// sendDraft sends the text in the textarea, split into several messages when
+// it is too long for one, as the CLI does
+func sendDraft(m Model) (Model, tea.Cmd) {
+    text := strings.TrimSpace(m.textarea.Value())
+    if text == "" {
+        m.textarea.Reset()
+        return m, nil
+    }
+    var ps []utils.Payload
+    if m.editing != "" {
+        if utf8.RuneCountInString(text) > utils.MaxMessageLength {
+            m.sendMsg = utils.ErrorStyle.Render(fmt.Sprintf("Edited messages are limited to %d characters",
+                utils.MaxMessageLength))
+            return m, nil
+        }
+        p := utils.NewPayload(utils.EditPayload, text)
+        p.Target = m.editing
+        ps = append(ps, p)
+    } else {
+        // send the Markdown itself, it is rendered by the recipient
+        for _, chunk := range utils.SplitMessage(text, utils.MaxMessageLength) {
+            ps = append(ps, utils.NewPayload(utils.TextPayload, chunk))
+        }
+        ps[0].ReplyTo = m.replyTo
+    }
+    m = resetActions(m)
+    m.textarea.Reset()
+    // the message itself ends the typing indicator
+    m.typingTo = ""
+    return sendPayload(m, ps...)
+}
+
+func sendResult(msg sendResultMsg, m Model) Model {
+    m.pending = ""
+    if msg.err == nil {
+        return m
+    }
+    if expired, ok := sessionExpired(m, msg.err); ok {
+        return expired
+    }
+    m.messages[msg.conversation] = append(
+        m.messages[msg.conversation], 
+        "Send failed...",
+    )
+    m.sendMsg = utils.ErrorStyle.Render("Send failed: " + msg.err.Error())
+    if m.conversation == msg.conversation {
+        m.viewport.SetContent(strings.Join(m.messages[m.conversation], "\n"))
+        m.viewport.GotoBottom()
+    }
+    return m
 }
 
 func conversationView(m Model) string {
     return fmt.Sprintf(
         conversationWrapping,
-        conversationStyle.Render(m.conversation),
+        conversationHeader(m),
         m.viewport.View(),
         m.textarea.View(),
+        pendingStatus(m, conversationStatus(m)),
     )
 }
 
+// conversationHeader names the contact, with their typing or presence
+func conversationHeader(m Model) string {
+    header := conversationStyle.Render(contactName(m))
+    if a := activity(m); a != "" {
+        header += " " + subtleStyle.Render(a)
+    }
+    return header
+}
+
+// conversationStatus shows errors first, then what the keys will act on
+func conversationStatus(m Model) string {
+    if m.sendMsg != "" {
+        return m.sendMsg
+    }
+    if m.selecting {
+        k := m.keys
+        return subtleStyle.Render(strings.Join([]string{
+            k.Reply.Help().Key + " " + k.Reply.Help().Desc,
+            k.Edit.Help().Key + " " + k.Edit.Help().Desc,
+            k.Delete.Help().Key + " " + k.Delete.Help().Desc,
+            k.React.Help().Key + " " + k.React.Help().Desc + " " + strings.Join(reactions, ""),
+            k.Cancel.Help().Key + " cancel",
+        }, " · "))
+    }
+    return composeContext(m)
+}
+
 func renderMessage(m Model, rawMsg utils.RawMessage) string {
     var prompt string
     switch rawMsg.Sender {
@@ -102,23 +179,39 @@ func renderMessage(m Model, rawMsg utils.RawMessage) string {
         if m.receivePrompt != "" {
             prompt = m.receiveStyle.Render(m.receivePrompt)
         } else {
-            prompt = m.receiveStyle.Render(m.conversation)
+            prompt = m.receiveStyle.Render(contactName(m))
         }
     }
     renderer, err := glamour.NewTermRenderer(
-        glamour.WithStylePath("tokyo-night"), 
+        glamour.WithStylePath(glamourStyle()),
+        glamour.WithColorProfile(lipgloss.ColorProfile()),
         glamour.WithWordWrap(m.viewport.Width - len(m.senderPrompt)),
     )
     if err != nil {
         renderer, _ = glamour.NewTermRenderer()
     }
-    messageMD, err := renderer.Render(rawMsg.Message)
+    if rawMsg.Deleted {
+        return prompt + quoteStyle.Render("message deleted")
+    }
+    body := rawMsg.Message
+    if m.settings.maths {
+        body = maths.Typeset(body)
+    }
+    messageMD, err := renderer.Render(body)
     if err != nil {
         // fallback to unformatted text if there is an issue rendering the markdown
         messageMD = rawMsg.Message
     }
     messageMD = strings.TrimSpace(messageMD)
     message := prompt + strings.Replace(messageMD, "m  ", "m", 1)
+    if rawMsg.Edited {
+        message += subtleStyle.Render(" (edited)")
+    }
+    if rawMsg.ReplyTo != "" {
+        message = quote(m, rawMsg.ReplyTo) + "\n" + message
+    }
+    if len(rawMsg.Reactions) > 0 {
+        message += "\n" + reactionLine(rawMsg.Reactions)
+    }
     return message
 }
-
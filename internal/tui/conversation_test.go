@@ -0,0 +1,219 @@
+package tui
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/auth"
+	"github.com/CraigYanitski/mescli/internal/client"
+	"github.com/CraigYanitski/mescli/internal/cryptography"
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// newMessageServer serves bob's keys and records every message sent to him
+func newMessageServer(t *testing.T, bob *client.Client, bobID uuid.UUID) (*[][]byte, *int) {
+    sent := [][]byte{}
+    exchanges := 0
+    mux := http.NewServeMux()
+    mux.HandleFunc("GET /users/{userID}", func(w http.ResponseWriter, r *http.Request) {
+        json.NewEncoder(w).Encode(requests.UserResponse{ID: bobID, Email: "bob@example.com", Name: "Bob"})
+    })
+    mux.HandleFunc("GET /users/crypto/{userID}", func(w http.ResponseWriter, r *http.Request) {
+        exchanges++
+        packet, _ := bob.SendPrekeyPacketJSON()
+        json.NewEncoder(w).Encode(packet)
+    })
+    mux.HandleFunc("GET /users/identity/{userID}", func(w http.ResponseWriter, r *http.Request) {
+        json.NewEncoder(w).Encode(requests.UserKeyPacket{
+            UserID: bobID,
+            IdentityKey: cryptography.EncodeECDSAPublicKey(bob.IdentityECDSA()),
+        })
+    })
+    mux.HandleFunc("POST /messages", func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        sent = append(sent, body)
+        w.WriteHeader(http.StatusCreated)
+    })
+    server := httptest.NewServer(mux)
+    t.Cleanup(server.Close)
+    viper.Set("api_url", server.URL)
+    token, err := auth.MakeJWT(uuid.New(), "secret", time.Hour)
+    if err != nil {
+        t.Fatalf("error making JWT: %s", err)
+    }
+    viper.Set("access_token", token)
+    t.Cleanup(func() { viper.Set("access_token", nil) })
+    return &sent, &exchanges
+}
+
+// runPending runs the commands returned for network work and applies their
+// results, ignoring the spinner
+func runPending(t *testing.T, m Model, cmd tea.Cmd) Model {
+    t.Helper()
+    cmds := []tea.Cmd{cmd}
+    for len(cmds) > 0 {
+        c := cmds[0]
+        cmds = cmds[1:]
+        if c == nil {
+            continue
+        }
+        switch msg := c().(type) {
+        case tea.BatchMsg:
+            cmds = append(cmds, msg...)
+        case spinner.TickMsg:
+        default:
+            next, _ := m.Update(msg)
+            m = next.(Model)
+        }
+    }
+    if m.pending != "" {
+        t.Fatal("expected pending work to finish")
+    }
+    return m
+}
+
+func TestSendMatchesCLI(t *testing.T) {
+    // the config, contact book and messages are written to the working directory
+    wd, _ := os.Getwd()
+    dir := t.TempDir()
+    os.Chdir(dir)
+    t.Cleanup(func() { os.Chdir(wd) })
+    viper.SetConfigFile(filepath.Join(dir, ".mescli.yaml"))
+
+    bob := &client.Client{}
+    if err := bob.Initialise(true); err != nil {
+        t.Fatalf("error initialising bob: %s", err)
+    }
+    bobID := uuid.New()
+    sent, exchanges := newMessageServer(t, bob, bobID)
+    cfg := &ApiConfig{
+        Messages: map[string][]utils.RawMessage{},
+        Contacts: map[string]requests.Contact{
+            bobID.String(): {ID: bobID, Email: "bob@example.com", Name: "Bob"},
+        },
+    }
+    if err := requests.WriteContacts(cfg.Contacts); err != nil {
+        t.Fatalf("error writing contacts: %s", err)
+    }
+
+    m := InitialModel(cfg).resize(100, 40)
+    m.loggedIn = true
+    m.Chosen = 1
+    m.conversation = bobID.String()
+    send := func(text string) {
+        m.textarea.SetValue(text)
+        next, cmd := updateConversation(tea.KeyMsg{Type: tea.KeyEnter}, m)
+        m = next.(Model)
+        if m.pending == "" {
+            t.Fatal("expected the send to be pending")
+        }
+        // run the send away from the update loop and hand back the result
+        m = runPending(t, m, cmd)
+    }
+
+    // the first message carries the key exchange
+    send("**hello** bob")
+    // later messages reuse the established session, repeating the key
+    // exchange until bob replies
+    send("_second_ message")
+    if *exchanges != 1 || len(*sent) != 2 {
+        t.Fatalf("expected 1 key exchange and 2 messages, got %d and %d", *exchanges, len(*sent))
+    }
+    first, second := &requests.MessageRequest{}, &requests.MessageRequest{}
+    json.Unmarshal((*sent)[0], first)
+    json.Unmarshal((*sent)[1], second)
+    if first.SenderEphemeralKey == "" || second.SenderEphemeralKey != first.SenderEphemeralKey {
+        t.Fatal("expected both messages to carry the same ephemeral key")
+    }
+
+    // bob decrypts the raw Markdown, not the rendered message
+    aliceIK := cryptography.DecodeECDSAPublicKey(first.SenderIdentityKey)
+    aliceID := uuid.New()
+    err := bob.CompleteX3DH(
+        &client.MessagePacketJSON{IdentityKey: first.SenderIdentityKey, EphemeralKey: first.SenderEphemeralKey},
+        aliceID,
+        true,
+    )
+    if err != nil {
+        t.Fatalf("error completing X3DH: %s", err)
+    }
+    for i, want := range []string{"**hello** bob", "_second_ message"} {
+        msg := first
+        if i == 1 {
+            msg = second
+        }
+        got, err := bob.ReceiveMessage(msg.Message, aliceIK, aliceID, true)
+        if err != nil {
+            t.Fatalf("error decrypting message %d: %s", i, err)
+        }
+        if p := utils.DecodePayload(got); p.Type != utils.TextPayload || p.ID == "" || p.Body != want {
+            t.Errorf("expected bob to receive %q, got %q", want, got)
+        }
+    }
+
+    // the CLI sends the same bytes for the same payload and session state
+    key := "contacts."+bobID.String()+".send_ratchet"
+    ratchet := viper.GetString(key)
+    send("`third`")
+    viper.Set(key, ratchet)
+    stored := cfg.Messages[bobID.String()][2]
+    p := utils.Payload{
+        Version: utils.PayloadVersion,
+        Type: utils.TextPayload,
+        ID: stored.ID,
+        SentAt: stored.Time.UTC(),
+        Body: stored.Message,
+    }
+    if err := requests.SendPayload(bobID.String(), p); err != nil {
+        t.Fatalf("error sending from the CLI: %s", err)
+    }
+    if len(*sent) != 4 {
+        t.Fatalf("expected 4 messages, got %d", len(*sent))
+    }
+    if string((*sent)[2]) != string((*sent)[3]) {
+        t.Errorf("TUI and CLI sends differ:\n%s\n%s", (*sent)[2], (*sent)[3])
+    }
+}
+
+func TestNetworkErrorBanners(t *testing.T) {
+    cfg := &ApiConfig{Messages: map[string][]utils.RawMessage{}}
+    m := InitialModel(cfg)
+    m.pending = "Working..."
+    failed := errors.New("connection refused")
+
+    // failures are shown inline rather than exiting
+    m = loginResult(loginResultMsg{err: failed}, m)
+    if m.pending != "" || m.loggedIn || m.Quitting {
+        t.Fatal("expected failed login to stay on the login screen")
+    }
+
+    m.loggedIn = true
+    m.conversation = uuid.NewString()
+    m = sendResult(sendResultMsg{conversation: m.conversation, err: failed}, m)
+    if m.sendMsg == "" || len(m.messages[m.conversation]) != 1 {
+        t.Fatal("expected failed send to be reported in the conversation")
+    }
+
+    m = inboxResult(inboxResultMsg{err: failed}, m)
+    if m.inboxMsg == "" || len(m.inbox.Items()) != 0 {
+        t.Fatal("expected failed fetch to be reported in the inbox")
+    }
+
+    // an expired session returns to the login screen
+    m = sendResult(sendResultMsg{conversation: m.conversation, err: requests.ErrSessionExpired}, m)
+    if m.loggedIn {
+        t.Fatal("expected expired session to return to login")
+    }
+}
@@ -5,8 +5,8 @@ import (
 	"strings"
 
 	"github.com/CraigYanitski/mescli/assets"
-	"github.com/CraigYanitski/mescli/internal/requests"
 	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -14,40 +14,33 @@ func updateCreate(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
     cmds := make([]tea.Cmd, len(m.updateInputs))
     switch msg := msg.(type) {
     case tea.KeyMsg:
-        switch msg.Type {
-        case tea.KeyCtrlC, tea.KeyEscape:
+        switch {
+        case key.Matches(msg, m.keys.ForceQuit), key.Matches(msg, m.keys.Cancel):
             m.Quitting = true
             return m, tea.Quit 
-        case tea.KeyTab, tea.KeyDown:
+        case key.Matches(msg, m.keys.NextField):
             m.updateFocus = (m.updateFocus + 1) % len(m.updateInputs)
-        case tea.KeyShiftTab, tea.KeyUp:
+        case key.Matches(msg, m.keys.PrevField):
             m.updateFocus = ((m.updateFocus - 1) % len(m.updateInputs) + len(m.updateInputs)) % len(m.updateInputs)
-        case tea.KeyEnter:
+        case key.Matches(msg, m.keys.Submit):
+            if m.pending != "" {
+                return m, nil
+            }
             if err := m.updateInputs[updateEmail].Err; err != nil {
-                m.createMsg = fmt.Sprintf(createMsgWrapping, utils.ErrorStyle.Render(err.Error()))
+                m.createMsg = utils.ErrorStyle.Render(err.Error())
                 return m, nil
             } else if err = m.updateInputs[updatePassword].Err; err != nil {
-                m.createMsg = fmt.Sprintf(createMsgWrapping, utils.ErrorStyle.Render(err.Error()))
+                m.createMsg = utils.ErrorStyle.Render(err.Error())
                 return m, nil
             } else if m.updateInputs[updateRetypePassword].Value() != m.updateInputs[updatePassword].Value() {
-                m.createMsg = fmt.Sprintf(createMsgWrapping, utils.ErrorStyle.Render("Passwords do not match"))
+                m.createMsg = utils.ErrorStyle.Render("Passwords do not match")
                 return m, nil
             }
-            err := requests.CreateAccount(
+            return startPending(m, "Creating account...", createAccount(
                 m.updateInputs[updateName].Value(),
                 m.updateInputs[updateEmail].Value(),
                 m.updateInputs[updatePassword].Value(),
-            )
-            if err != nil {
-                m.createMsg = fmt.Sprintf(createMsgWrapping, utils.ErrorStyle.Render("Account creation failed"))
-                return m, nil
-            }
-            m.created = true
-            m.loggedIn = true
-            m.updateFocus = 0
-            for i, _ := range m.updateInputs {
-                m.updateInputs[i].SetValue("")
-            }
+            ))
         }
         for i := range m.updateInputs {
             m.updateInputs[i].Blur()
@@ -60,6 +53,22 @@ func updateCreate(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
     return m, tea.Batch(cmds...)
 }
 
+func createResult(msg createResultMsg, m Model) Model {
+    m.pending = ""
+    if msg.err != nil {
+        m.createMsg = utils.ErrorStyle.Render("Account creation failed: "+msg.err.Error())
+        return m
+    }
+    m.created = true
+    m.loggedIn = true
+    m.updateFocus = 0
+    m.createMsg = ""
+    for i, _ := range m.updateInputs {
+        m.updateInputs[i].SetValue("")
+    }
+    return m
+}
+
 func createView(m Model) string{
     // obscure password
     pw := m.updateInputs[updatePassword].Value()
@@ -76,7 +85,7 @@ func createView(m Model) string{
         m.updateInputs[updateEmail].View(), 
         m.updateInputs[updatePassword].View(),
         m.updateInputs[updateRetypePassword].View(),
-        m.createMsg,
+        formStatus(m, m.createMsg, hint(m.keys.Submit, "create a new account")),
     )
     // restore password
     m.updateInputs[updatePassword].SetValue(pw)
@@ -0,0 +1,79 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CraigYanitski/mescli/assets"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func updateDelete(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
+    switch msg := msg.(type) {
+    case tea.KeyMsg:
+        switch {
+        case key.Matches(msg, m.keys.ForceQuit):
+            m.Quitting = true
+            return m, tea.Quit
+        case key.Matches(msg, m.keys.Cancel):
+            m.Chosen = 0
+            m.deleteInput.SetValue("")
+            return m, nil
+        case key.Matches(msg, m.keys.Submit):
+            if m.pending != "" {
+                return m, nil
+            }
+            password := m.deleteInput.Value()
+            m.deleteInput.SetValue("")
+            return startPending(m, "Deleting account...", deleteAccount(password))
+        }
+    }
+    var cmd tea.Cmd
+    m.deleteInput, cmd = m.deleteInput.Update(msg)
+    return m, cmd
+}
+
+func deleteResult(msg deleteResultMsg, m Model) Model {
+    m.pending = ""
+    if msg.err != nil {
+        if expired, ok := sessionExpired(m, msg.err); ok {
+            return expired
+        }
+        m.deleteMsg = utils.ErrorStyle.Render("Deletion failed: "+msg.err.Error())
+        return m
+    }
+    // wipe local history and return to the login screen
+    m.cfg.Messages = make(map[string][]utils.RawMessage)
+    m.messages = make(map[string][]string)
+    m.contacts.SetItems([]list.Item{})
+    m.conversation = ""
+    m.Chosen = 0
+    m.loggedIn = false
+    m.deleteMsg = ""
+    m.loginMsg = utils.StatusStyle.Render("Account deleted")
+    return m
+}
+
+func deleteView(m Model) string {
+    // obscure password
+    pw := m.deleteInput.Value()
+    san := strings.Repeat("*", len(pw))
+    m.deleteInput.SetValue(san)
+    // set output string
+    s := fmt.Sprintf(
+        deleteWrapping,
+        assets.Logo,
+        utils.ErrorStyle.Bold(true).Render(deleteWarning),
+        m.deleteInput.View(),
+        formStatus(m, m.deleteMsg,
+            hint(m.keys.Submit, "permanently delete your account"),
+            hint(m.keys.Cancel, "cancel"),
+        ),
+    )
+    // restore password
+    m.deleteInput.SetValue(pw)
+    return s
+}
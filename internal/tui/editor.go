@@ -0,0 +1,82 @@
+package tui
+
+import (
+	"os"
+	"strings"
+
+	"github.com/CraigYanitski/mescli/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type editorMsg struct {
+    conversation  string
+    body          string
+    err           error
+}
+
+// openEditor suspends the TUI to edit the draft of the open conversation in
+// the user's editor
+func openEditor(m Model) (Model, tea.Cmd) {
+    f, err := os.CreateTemp("", "mescli-*.md")
+    if err != nil {
+        m.sendMsg = utils.ErrorStyle.Render("Unable to open editor: " + err.Error())
+        return m, nil
+    }
+    path := f.Name()
+    _, err = f.WriteString(m.textarea.Value())
+    f.Close()
+    if err != nil {
+        os.Remove(path)
+        m.sendMsg = utils.ErrorStyle.Render("Unable to open editor: " + err.Error())
+        return m, nil
+    }
+    m, stop := stopTyping(m)
+    conversation := m.conversation
+    edit := tea.ExecProcess(utils.EditorCommand(path), func(err error) tea.Msg {
+        defer os.Remove(path)
+        if err != nil {
+            return editorMsg{conversation: conversation, err: err}
+        }
+        body, err := os.ReadFile(path)
+        return editorMsg{conversation: conversation, body: strings.TrimRight(string(body), "\n"), err: err}
+    })
+    return m, tea.Batch(stop, edit)
+}
+
+// updateEditor puts the text written in the editor back in the textarea, or
+// sends it with editor_send
+func updateEditor(msg tea.Msg, m Model) (Model, tea.Cmd, bool) {
+    result, ok := msg.(editorMsg)
+    if !ok {
+        return m, nil, false
+    }
+    if result.err != nil {
+        m.sendMsg = utils.ErrorStyle.Render("Error running editor: " + result.err.Error())
+        return m, nil, true
+    }
+    // the conversation was closed while editing
+    if result.conversation != m.conversation {
+        m.drafts[result.conversation] = result.body
+        return m, nil, true
+    }
+    m.textarea.SetValue(result.body)
+    m.sendMsg = ""
+    if m.settings.editorSend && m.pending == "" {
+        m, cmd := sendDraft(m)
+        return m, cmd, true
+    }
+    return m, nil, true
+}
+
+// switchDraft keeps the draft of the conversation that was left, and restores
+// that of the one opened
+func switchDraft(m Model, left, draft string) Model {
+    if draft == "" {
+        delete(m.drafts, left)
+    } else if left != "" {
+        m.drafts[left] = draft
+    }
+    m.textarea.SetValue(m.drafts[m.conversation])
+    delete(m.drafts, m.conversation)
+    return m
+}
@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+func TestDrafts(t *testing.T) {
+    // sent messages are saved to the working directory
+    wd, _ := os.Getwd()
+    os.Chdir(t.TempDir())
+    t.Cleanup(func() { os.Chdir(wd) })
+    alice, bob := uuid.New().String(), uuid.New().String()
+    cfg := &ApiConfig{
+        Messages: map[string][]utils.RawMessage{alice: {}, bob: {}},
+        Contacts: map[string]requests.Contact{},
+    }
+    m := InitialModel(cfg).resize(80, 40)
+    m.loggedIn = true
+    m.Chosen = 1
+    update := func(msg tea.Msg) {
+        next, _ := m.Update(msg)
+        m = next.(Model)
+    }
+    open := func(id string) {
+        for i, item := range m.contacts.Items() {
+            if item.(contact).id == id {
+                m.contacts.Select(i)
+            }
+        }
+        update(tea.KeyMsg{Type: tea.KeyEnter})
+    }
+    open(alice)
+
+    // each conversation keeps its draft
+    m.textarea.SetValue("for alice")
+    update(tea.KeyMsg{Type: tea.KeyEsc})
+    if m.textarea.Value() != "" || m.drafts[alice] != "for alice" {
+        t.Fatalf("expected alice's draft to be kept, got %q", m.drafts[alice])
+    }
+    open(bob)
+    update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+    update(tea.KeyMsg{Type: tea.KeyEsc})
+    open(alice)
+    update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("!")})
+    if m.textarea.Value() != "for alice!" || m.drafts[bob] != "b" {
+        t.Fatalf("expected alice's draft to be restored, got %q", m.textarea.Value())
+    }
+
+    // the text written in the editor replaces the draft
+    update(editorMsg{conversation: alice, body: "# Title\n\nfrom the editor"})
+    if m.textarea.Value() != "# Title\n\nfrom the editor" {
+        t.Fatalf("expected the edited text in the textarea, got %q", m.textarea.Value())
+    }
+    update(editorMsg{conversation: bob, body: "later"})
+    update(editorMsg{conversation: alice, err: errors.New("exit status 1")})
+    if m.drafts[bob] != "later" || !strings.Contains(m.sendMsg, "exit status 1") {
+        t.Fatalf("expected bob's draft and an error, got %q and %q", m.drafts[bob], m.sendMsg)
+    }
+
+    // or is sent, split into messages of the maximum length
+    m.settings.editorSend = true
+    long := strings.Repeat("word ", utils.MaxMessageLength/2)
+    update(editorMsg{conversation: alice, body: long})
+    if msgs := cfg.Messages[alice]; len(msgs) != 3 || m.pending == "" || m.textarea.Value() != "" {
+        t.Fatalf("expected the long message to be sent in three parts, got %d", len(msgs))
+    }
+
+    // code blocks are never split, however long
+    m.pending = ""
+    code := "```\n" + strings.Repeat("x := 1\n", utils.MaxMessageLength/4) + "```"
+    update(editorMsg{conversation: alice, body: "code:\n\n" + code})
+    msgs := cfg.Messages[alice]
+    if len(msgs) != 5 || msgs[4].Message != code {
+        t.Fatalf("expected the code block to be sent whole, got %d messages", len(msgs))
+    }
+}
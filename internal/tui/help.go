@@ -2,11 +2,23 @@ package tui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
+// hint describes what a key does on a form, with the key currently bound
+func hint(b key.Binding, action string) string {
+    return b.Help().Key + " to " + action
+}
+
+// formStatus shows the status of a form below the hints for its keys
+func formStatus(m Model, msg string, hints ...string) string {
+    return pendingStatus(m, strings.Join(hints, "\n") + "\n\n" + msg)
+}
+
 // add struct functions to implement help.KeyMap
 
 // ShortHelp returns bindings to show in the abbreviated help view. It's part
@@ -28,40 +40,37 @@ func (m Model) ShortHelp() []key.Binding {
 	)
 }
 
-// FullHelp returns bindings to show the full help view. It's part of the
-// help.KeyMap interface.
+// FullHelp returns bindings to show the full help view, a column for each
+// group of the active key bindings. It's part of the help.KeyMap interface.
 func (m Model) FullHelp() [][]key.Binding {
-	kb := [][]key.Binding{{
-		m.viewport.KeyMap.Up,
-		m.viewport.KeyMap.Down,
-        m.viewport.KeyMap.HalfPageUp,
-		m.viewport.KeyMap.HalfPageDown,
-        m.viewport.KeyMap.PageUp,
-        m.viewport.KeyMap.PageDown,
-	}}
-
-    taKB := [][]key.Binding {{
-        key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "return to previous screen")),
-        key.NewBinding(key.WithKeys("ctrl+h"), key.WithHelp("ctrl+h", "show this help screen")),
-        m.textarea.KeyMap.Paste,
-        m.textarea.KeyMap.InsertNewline,
-        m.textarea.KeyMap.CharacterForward,
-        m.textarea.KeyMap.CharacterBackward,
-    }}
+    var kb [][]key.Binding
+    for _, group := range keyGroups {
+        kb = append(kb, groupBindings(m, group.name))
+    }
+    return kb
+}
 
-	return append(kb, taKB...)
+// groupBindings are the enabled bindings of a group
+func groupBindings(m Model, group string) []key.Binding {
+    var kb []key.Binding
+    for _, a := range modelKeyBindings(m).actions() {
+        if a.group == group && a.binding.Enabled() {
+            kb = append(kb, *a.binding)
+        }
+    }
+    return kb
 }
 
 func updateHelp(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
     switch msg := msg.(type) {
     case tea.KeyMsg:
-        switch msg.Type {
-        case tea.KeyCtrlC:
+        switch {
+        case key.Matches(msg, m.keys.ForceQuit):
             m.Quitting = true
             m.Chosen = 3
             m.viewHelp = false
             return m, tea.Quit
-        case tea.KeyEsc, tea.KeyCtrlQ:
+        case key.Matches(msg, m.keys.Cancel), key.Matches(msg, m.keys.ConversationHelp):
             m.viewHelp = false
         }
     }
@@ -69,9 +78,27 @@ func updateHelp(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
 }
 
 func helpView(m Model) string {
-    m.help.ShowAll = true
-    help := helpStyle.Width(m.viewport.Width).Margin(optionMargin.height, optionMargin.width).
-        Render(m.help.View(m))
+    // each group is a titled column, wrapping onto more rows when the
+    // terminal is narrow
+    width := max(m.width-2*optionMargin.width, 0)
+    var rows, row []string
+    rowWidth := 0
+    for i, kb := range m.FullHelp() {
+        if len(kb) == 0 {
+            continue
+        }
+        column := lipgloss.NewStyle().MarginRight(4).Render(
+            conversationStyle.Render(keyGroups[i].title) + "\n" + m.help.FullHelpView([][]key.Binding{kb}),
+        )
+        if len(row) > 0 && width > 0 && rowWidth+lipgloss.Width(column) > width {
+            rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+            row, rowWidth = nil, 0
+        }
+        row = append(row, column)
+        rowWidth += lipgloss.Width(column)
+    }
+    rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+    help := helpStyle.Margin(optionMargin.height, optionMargin.width).
+        Render(strings.Join(rows, "\n\n"))
     return fmt.Sprintf(helpWrapping, help)
 }
-
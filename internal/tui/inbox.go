@@ -0,0 +1,141 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+)
+
+func updateInbox(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
+    switch msg := msg.(type) {
+    case tea.KeyMsg:
+        switch {
+        case key.Matches(msg, m.keys.Quit):
+            m.Quitting = true
+            return m, tea.Quit
+        case key.Matches(msg, m.keys.Back):
+            m.Chosen = 0
+            return m, nil
+        case key.Matches(msg, m.keys.Accept), key.Matches(msg, m.keys.Decline), key.Matches(msg, m.keys.Block):
+            c, ok := m.inbox.SelectedItem().(contact)
+            if !ok {
+                return m, nil
+            }
+            return answerRequest(msg, m, c)
+        }
+    case tea.WindowSizeMsg:
+        m = m.resize(msg.Width, msg.Height)
+    }
+
+    var cmd tea.Cmd
+    m.inbox, cmd = m.inbox.Update(msg)
+
+    return m, cmd
+}
+
+// accept, decline or block the sender of the selected message request
+func answerRequest(msg tea.KeyMsg, m Model, c contact) (Model, tea.Cmd) {
+    if m.pending != "" {
+        return m, nil
+    }
+    id, err := uuid.Parse(c.id)
+    if err != nil {
+        m.inboxMsg = utils.ErrorStyle.Render(err.Error())
+        return m, nil
+    }
+    m.inboxMsg = ""
+    accept := key.Matches(msg, m.keys.Accept)
+    block := key.Matches(msg, m.keys.Block)
+    return startPending(m, "Answering "+c.name+"...", answer(id, c.name, accept, block))
+}
+
+func answerResult(msg answerResultMsg, m Model) Model {
+    m.pending = ""
+    if msg.err != nil {
+        if expired, ok := sessionExpired(m, msg.err); ok {
+            return expired
+        }
+        m.inboxMsg = utils.ErrorStyle.Render(msg.err.Error())
+        return m
+    }
+    // keep accepted and blocked senders in the contact book
+    if msg.contact.ID != uuid.Nil {
+        if m.cfg.Contacts == nil {
+            m.cfg.Contacts = make(map[string]requests.Contact)
+        }
+        c := requests.MergeContact(m.cfg.Contacts, msg.contact)
+        if msg.block {
+            c.Blocked = true
+            m.cfg.Contacts[msg.id] = c
+        }
+        if err := requests.WriteContacts(m.cfg.Contacts); err != nil {
+            m.inboxMsg = utils.ErrorStyle.Render(err.Error())
+            return m
+        }
+    }
+    for i, item := range m.inbox.Items() {
+        if c, ok := item.(contact); ok && c.id == msg.id {
+            m.inbox.RemoveItem(i)
+            break
+        }
+    }
+    m.contacts.SetItems(contactItems(m.cfg))
+    m.inboxMsg = utils.SuccessStyle.Render(msg.status)
+    return m
+}
+
+// openInbox fetches the pending message requests from the server
+func openInbox(m Model) (Model, tea.Cmd) {
+    m.inbox.SetItems([]list.Item{})
+    m.inboxMsg = ""
+    return startPending(m, "Fetching message requests...", fetchInbox)
+}
+
+func inboxResult(msg inboxResultMsg, m Model) Model {
+    m.pending = ""
+    if msg.err != nil {
+        if expired, ok := sessionExpired(m, msg.err); ok {
+            return expired
+        }
+        m.inbox.SetItems([]list.Item{})
+        m.inboxMsg = utils.ErrorStyle.Render("Unable to fetch message requests: " + msg.err.Error())
+        return m
+    }
+    items := []list.Item{}
+    for _, p := range msg.pending {
+        c := requests.Contact{ID: p.SenderID, Email: p.Email, Name: p.Name}
+        items = append(items, contact{
+            id: p.SenderID.String(),
+            name: c.DisplayName(),
+            desc: fmt.Sprintf("%d message(s) since %s", p.Messages, p.FirstSentAt.Format("02-01-2006 15:04")),
+        })
+    }
+    m.inbox.SetItems(items)
+    m.inboxMsg = ""
+    return m
+}
+
+func inboxView(m Model) string {
+    var pending string
+    if m.pending != "" {
+        pending = ""
+    } else if len(m.inbox.Items()) == 0 {
+        pending = "No message requests"
+    } else {
+        pending = lipgloss.NewStyle().Margin(contactMargin.height, contactMargin.width).
+            Render(m.inbox.View())
+    }
+    hints := strings.Join([]string{
+        hint(m.keys.Accept, "accept"),
+        hint(m.keys.Decline, "decline"),
+        hint(m.keys.Block, "block"),
+    }, ", ")
+    return fmt.Sprintf(inboxWrapping, pending, hints, pendingStatus(m, m.inboxMsg))
+}
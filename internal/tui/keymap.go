@@ -0,0 +1,403 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
+)
+
+// Keymap remaps key bindings, from the group.action name of a binding to its
+// keys. An empty list of keys disables the action.
+type Keymap map[string][]string
+
+// groups of key bindings, which are the sections of the keymap config
+const (
+    groupGeneral       = "general"
+    groupList          = "list"
+    groupConversation  = "conversation"
+    groupSelection     = "selection"
+    groupTextarea      = "textarea"
+    groupViewport      = "viewport"
+    groupForm          = "form"
+)
+
+// titles of the groups on the help screen, in the order they are shown
+var keyGroups = []struct {
+    name, title  string
+}{
+    {groupGeneral, "General"},
+    {groupConversation, "Conversation"},
+    {groupSelection, "Selected message"},
+    {groupViewport, "Scrolling"},
+    {groupTextarea, "Message editor"},
+    {groupList, "Menus and contacts"},
+    {groupForm, "Forms"},
+}
+
+// screens on which a key may only be bound to one action
+const (
+    screenMenu          = "menu"
+    screenContacts      = "contacts"
+    screenInbox         = "inbox"
+    screenConversation  = "conversation"
+    screenSelection     = "message selection"
+    screenForm          = "form"
+    screenHelp          = "help"
+)
+
+var (
+    listScreens  = []string{screenMenu, screenContacts, screenInbox}
+    allScreens   = []string{screenMenu, screenContacts, screenInbox, screenConversation, screenSelection,
+        screenForm, screenHelp}
+    // screens handling keys themselves, rather than as a list
+    textScreens  = []string{screenConversation, screenSelection, screenForm, screenHelp}
+)
+
+// key binding presets, chosen with keymap.preset
+var keymapPresets = map[string]Keymap{
+    "default": {},
+    // vim scrolls with ctrl+u and ctrl+d, so the emacs editing keys they
+    // clash with are left to the arrow keys
+    "vim": {
+        "list.next_page": {"right", "pgdown", "ctrl+f"},
+        "list.previous_page": {"left", "pgup", "ctrl+b"},
+        "list.select": {"enter", "l"},
+        "list.back": {"esc", "backspace", "h"},
+        "list.help": {"?"},
+        "viewport.up": {"ctrl+y", "ctrl+up"},
+        "viewport.down": {"ctrl+e", "ctrl+down"},
+        "viewport.half_page_up": {"ctrl+u"},
+        "viewport.half_page_down": {"ctrl+d"},
+        "viewport.page_up": {"ctrl+b", "pgup"},
+        "viewport.page_down": {"ctrl+f", "pgdown"},
+        "textarea.character_forward": {"right"},
+        "textarea.character_backward": {"left"},
+        "textarea.line_end": {"end"},
+        "textarea.delete_before_cursor": {},
+        "textarea.delete_character_forward": {"delete"},
+    },
+}
+
+// keymap in use, set once at start-up
+var keymap Keymap
+
+// SetKeymap sets the key bindings of the TUI, which should first be checked
+// with LoadKeymap.
+func SetKeymap(k Keymap) {
+    keymap = k
+}
+
+// LoadKeymap combines a preset with the keys remapped in the config, given by
+// group and action, and checks that no key is bound to two actions on the
+// same screen.
+func LoadKeymap(preset string, groups map[string]map[string][]string) (Keymap, error) {
+    if preset == "" {
+        preset = "default"
+    }
+    base, ok := keymapPresets[preset]
+    if !ok {
+        return nil, fmt.Errorf("unknown keymap preset %q", preset)
+    }
+    k := Keymap{}
+    for name, keys := range base {
+        k[name] = keys
+    }
+    for group, actions := range groups {
+        for action, keys := range actions {
+            k[group+"."+action] = keys
+        }
+    }
+    kb := newKeyBindings()
+    if err := kb.remap(k); err != nil {
+        return nil, err
+    }
+    if err := kb.conflicts(); err != nil {
+        return nil, err
+    }
+    return k, nil
+}
+
+// keyBindings holds every key binding of the TUI
+type keyBindings struct {
+    keys      *listKeyMap
+    textarea  textarea.KeyMap
+    viewport  viewport.KeyMap
+}
+
+func newKeyBindings() *keyBindings {
+    return &keyBindings{
+        keys: newListKeyMap(),
+        textarea: newTextareaKeyMap(),
+        viewport: newViewportKeyMap(),
+    }
+}
+
+// modelKeyBindings are the bindings a model was created with
+func modelKeyBindings(m Model) *keyBindings {
+    return &keyBindings{keys: m.keys, textarea: m.textarea.KeyMap, viewport: m.viewport.KeyMap}
+}
+
+// action is a binding that can be remapped, and the screens it is used on
+type action struct {
+    group, name  string
+    binding      *key.Binding
+    screens      []string
+}
+
+func (a action) String() string {
+    return a.group + "." + a.name
+}
+
+func (kb *keyBindings) actions() []action {
+    k, ta, vp := kb.keys, &kb.textarea, &kb.viewport
+    conversation := []string{screenConversation}
+    return []action{
+        {groupGeneral, "quit", &k.ForceQuit, textScreens},
+        {groupGeneral, "cancel", &k.Cancel, textScreens},
+        {groupGeneral, "jump", &k.Jump, allScreens},
+        {groupGeneral, "switch_pane", &k.SwitchPane, []string{screenContacts, screenConversation, screenSelection}},
+
+        {groupList, "up", &k.optionUp, append(listScreens, screenSelection)},
+        {groupList, "down", &k.optionDown, append(listScreens, screenSelection)},
+        {groupList, "next_page", &k.NextPage, listScreens},
+        {groupList, "previous_page", &k.PrevPage, listScreens},
+        {groupList, "start", &k.GoToStart, listScreens},
+        {groupList, "end", &k.GoToEnd, listScreens},
+        {groupList, "help", &k.toggleHelpMenu, listScreens},
+        {groupList, "select", &k.Enter, []string{screenMenu, screenContacts}},
+        {groupList, "back", &k.Back, listScreens},
+        {groupList, "quit", &k.Quit, listScreens},
+        {groupList, "new_conversation", &k.NewConversation, []string{screenMenu, screenContacts}},
+        {groupList, "accept", &k.Accept, []string{screenInbox}},
+        {groupList, "decline", &k.Decline, []string{screenInbox}},
+        {groupList, "block", &k.Block, []string{screenInbox}},
+
+        {groupConversation, "send", &k.Send, conversation},
+        {groupConversation, "help", &k.ConversationHelp, []string{screenConversation, screenHelp}},
+        {groupConversation, "editor", &k.Editor, conversation},
+        {groupConversation, "select_message", &k.Select, conversation},
+
+        {groupSelection, "reply", &k.Reply, []string{screenSelection}},
+        {groupSelection, "edit", &k.Edit, []string{screenSelection}},
+        {groupSelection, "delete", &k.Delete, []string{screenSelection}},
+        {groupSelection, "react", &k.React, []string{screenSelection}},
+
+        {groupForm, "next_field", &k.NextField, []string{screenForm}},
+        {groupForm, "previous_field", &k.PrevField, []string{screenForm}},
+        {groupForm, "submit", &k.Submit, []string{screenForm}},
+        {groupForm, "create_account", &k.CreateAccount, []string{screenForm}},
+
+        {groupTextarea, "character_forward", &ta.CharacterForward, conversation},
+        {groupTextarea, "character_backward", &ta.CharacterBackward, conversation},
+        {groupTextarea, "word_forward", &ta.WordForward, conversation},
+        {groupTextarea, "word_backward", &ta.WordBackward, conversation},
+        {groupTextarea, "line_next", &ta.LineNext, conversation},
+        {groupTextarea, "line_previous", &ta.LinePrevious, conversation},
+        {groupTextarea, "delete_word_backward", &ta.DeleteWordBackward, conversation},
+        {groupTextarea, "delete_word_forward", &ta.DeleteWordForward, conversation},
+        {groupTextarea, "delete_after_cursor", &ta.DeleteAfterCursor, conversation},
+        {groupTextarea, "delete_before_cursor", &ta.DeleteBeforeCursor, conversation},
+        {groupTextarea, "insert_newline", &ta.InsertNewline, conversation},
+        {groupTextarea, "delete_character_backward", &ta.DeleteCharacterBackward, conversation},
+        {groupTextarea, "delete_character_forward", &ta.DeleteCharacterForward, conversation},
+        {groupTextarea, "line_start", &ta.LineStart, conversation},
+        {groupTextarea, "line_end", &ta.LineEnd, conversation},
+        {groupTextarea, "paste", &ta.Paste, conversation},
+        {groupTextarea, "input_begin", &ta.InputBegin, conversation},
+        {groupTextarea, "input_end", &ta.InputEnd, conversation},
+        {groupTextarea, "capitalize_word_forward", &ta.CapitalizeWordForward, conversation},
+        {groupTextarea, "lowercase_word_forward", &ta.LowercaseWordForward, conversation},
+        {groupTextarea, "uppercase_word_forward", &ta.UppercaseWordForward, conversation},
+        {groupTextarea, "transpose_character_backward", &ta.TransposeCharacterBackward, conversation},
+
+        {groupViewport, "up", &vp.Up, conversation},
+        {groupViewport, "down", &vp.Down, conversation},
+        {groupViewport, "half_page_up", &vp.HalfPageUp, conversation},
+        {groupViewport, "half_page_down", &vp.HalfPageDown, conversation},
+        {groupViewport, "page_up", &vp.PageUp, conversation},
+        {groupViewport, "page_down", &vp.PageDown, conversation},
+    }
+}
+
+// remap rebinds the actions named in the keymap
+func (kb *keyBindings) remap(k Keymap) error {
+    actions := make(map[string]action)
+    for _, a := range kb.actions() {
+        actions[a.String()] = a
+    }
+    for name, keys := range k {
+        a, ok := actions[name]
+        if !ok {
+            return fmt.Errorf("unknown key binding %q", name)
+        }
+        a.binding.SetKeys(keys...)
+        a.binding.SetHelp(strings.Join(keys, " | "), a.binding.Help().Desc)
+        a.binding.SetEnabled(len(keys) > 0)
+    }
+    return nil
+}
+
+// conflicts reports keys bound to more than one action on the same screen
+func (kb *keyBindings) conflicts() error {
+    bound := make(map[string]map[string][]string)
+    for _, a := range kb.actions() {
+        if !a.binding.Enabled() {
+            continue
+        }
+        for _, screen := range a.screens {
+            if bound[screen] == nil {
+                bound[screen] = make(map[string][]string)
+            }
+            for _, k := range a.binding.Keys() {
+                bound[screen][k] = append(bound[screen][k], a.String())
+            }
+        }
+    }
+    var errs []string
+    for screen, keys := range bound {
+        for k, names := range keys {
+            if len(names) > 1 {
+                errs = append(errs, fmt.Sprintf("%s is bound to %s on the %s screen",
+                    k, strings.Join(names, " and "), screen))
+            }
+        }
+    }
+    if len(errs) == 0 {
+        return nil
+    }
+    sort.Strings(errs)
+    return errors.New("conflicting key bindings: " + strings.Join(errs, "; "))
+}
+
+// newTextareaKeyMap is the default set of key bindings for navigating and
+// acting upon the textarea, leaving ctrl+h for the help screen
+func newTextareaKeyMap() textarea.KeyMap {
+    return textarea.KeyMap{
+        CharacterForward: key.NewBinding(
+            key.WithKeys("right", "ctrl+f"),
+            key.WithHelp("right", "character forward"),
+        ),
+        CharacterBackward: key.NewBinding(
+            key.WithKeys("left", "ctrl+b"),
+            key.WithHelp("left", "character backward"),
+        ),
+        WordForward: key.NewBinding(
+            key.WithKeys("ctrl+right", "alt+f"),
+            key.WithHelp("ctrl+right", "word forward"),
+        ),
+        WordBackward: key.NewBinding(
+            key.WithKeys("ctrl+left", "alt+b"),
+            key.WithHelp("ctrl+left", "word backward"),
+        ),
+        LineNext: key.NewBinding(
+            key.WithKeys("down"),
+            key.WithHelp("down", "next line"),
+        ),
+        LinePrevious: key.NewBinding(
+            key.WithKeys("up"),
+            key.WithHelp("up", "previous line"),
+        ),
+        DeleteWordBackward: key.NewBinding(
+            key.WithKeys("alt+backspace", "ctrl+w"),
+            key.WithHelp("alt+backspace", "delete word backward"),
+        ),
+        DeleteWordForward: key.NewBinding(
+            key.WithKeys("alt+delete", "alt+d"),
+            key.WithHelp("alt+delete", "delete word forward"),
+        ),
+        DeleteAfterCursor: key.NewBinding(
+            key.WithKeys("ctrl+k"),
+            key.WithHelp("ctrl+k", "delete after cursor"),
+        ),
+        DeleteBeforeCursor: key.NewBinding(
+            key.WithKeys("ctrl+u"),
+            key.WithHelp("ctrl+u", "delete before cursor"),
+        ),
+        InsertNewline: key.NewBinding(
+            key.WithKeys("ctrl+s"),
+            key.WithHelp("ctrl+s", "insert newline"),
+        ),
+        DeleteCharacterBackward: key.NewBinding(
+            key.WithKeys("backspace"),
+            key.WithHelp("backspace", "delete character backward"),
+        ),
+        DeleteCharacterForward: key.NewBinding(
+            key.WithKeys("delete", "ctrl+d"),
+            key.WithHelp("delete", "delete character forward"),
+        ),
+        LineStart: key.NewBinding(
+            key.WithKeys("home", "ctrl+a"),
+            key.WithHelp("home", "line start"),
+        ),
+        LineEnd: key.NewBinding(
+            key.WithKeys("end", "ctrl+e"),
+            key.WithHelp("end", "line end"),
+        ),
+        Paste: key.NewBinding(
+            key.WithKeys("ctrl+v"),
+            key.WithHelp("ctrl+v", "paste"),
+        ),
+        InputBegin: key.NewBinding(
+            key.WithKeys("alt+<", "ctrl+home"),
+            key.WithHelp("alt+<", "input begin"),
+        ),
+        InputEnd: key.NewBinding(
+            key.WithKeys("alt+>", "ctrl+end"),
+            key.WithHelp("alt+>", "input end"),
+        ),
+
+        CapitalizeWordForward: key.NewBinding(
+            key.WithKeys("alt+c"),
+            key.WithHelp("alt+c", "capitalize word forward"),
+        ),
+        LowercaseWordForward: key.NewBinding(
+            key.WithKeys("alt+l"),
+            key.WithHelp("alt+l", "lowercase word forward"),
+        ),
+        UppercaseWordForward: key.NewBinding(
+            key.WithKeys("alt+u"),
+            key.WithHelp("alt+u", "uppercase word forward"),
+        ),
+
+        TransposeCharacterBackward: key.NewBinding(
+            key.WithKeys("ctrl+t"),
+            key.WithHelp("ctrl+t", "transpose character backward"),
+        ),
+    }
+}
+
+// newViewportKeyMap scrolls the conversation, with keys the textarea does not
+// use
+func newViewportKeyMap() viewport.KeyMap {
+    return viewport.KeyMap{
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown"),
+			key.WithHelp("pgdn", "conversation page down"),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup"),
+			key.WithHelp("pgup", "conversation page up"),
+		),
+		HalfPageUp: key.NewBinding(
+			key.WithKeys("shift+up"),
+			key.WithHelp("shift+↑", "conversation ½ page up"),
+		),
+		HalfPageDown: key.NewBinding(
+			key.WithKeys("shift+down"),
+			key.WithHelp("shift+↓", "conversation ½ page down"),
+		),
+		Up: key.NewBinding(
+			key.WithKeys("ctrl+up"),
+			key.WithHelp("ctrl+↑", "conversation up"),
+		),
+		Down: key.NewBinding(
+			key.WithKeys("ctrl+down"),
+			key.WithHelp("ctrl+↓", "conversation down"),
+		),
+	}
+}
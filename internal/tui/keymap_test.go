@@ -0,0 +1,91 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestLoadKeymap(t *testing.T) {
+    // the presets have no conflicts
+    for preset := range keymapPresets {
+        if _, err := LoadKeymap(preset, nil); err != nil {
+            t.Errorf("expected the %s preset to load, got %s", preset, err)
+        }
+    }
+    if _, err := LoadKeymap("emacs", nil); err == nil {
+        t.Error("expected an error for an unknown preset")
+    }
+    if _, err := LoadKeymap("", map[string]map[string][]string{"viewport": {"sideways": {"x"}}}); err == nil {
+        t.Error("expected an error for an unknown action")
+    }
+
+    // keys bound twice on a screen are reported
+    _, err := LoadKeymap("", map[string]map[string][]string{"viewport": {"half_page_up": {"ctrl+u"}}})
+    if err == nil || !strings.Contains(err.Error(), "textarea.delete_before_cursor and viewport.half_page_up") {
+        t.Errorf("expected a conflict on ctrl+u, got %v", err)
+    }
+    // but not when the other action is moved or disabled
+    _, err = LoadKeymap("", map[string]map[string][]string{
+        "viewport": {"half_page_up": {"ctrl+u"}},
+        "textarea": {"delete_before_cursor": {}},
+    })
+    if err != nil {
+        t.Errorf("expected no conflict, got %s", err)
+    }
+    // keys on different screens do not conflict
+    if _, err = LoadKeymap("", map[string]map[string][]string{"list": {"quit": {"esc"}}}); err == nil {
+        t.Error("expected quit and back to conflict in the lists")
+    }
+    if _, err = LoadKeymap("", map[string]map[string][]string{"selection": {"reply": {"n"}}}); err != nil {
+        t.Errorf("expected no conflict between screens, got %s", err)
+    }
+}
+
+func TestKeymap(t *testing.T) {
+    k, err := LoadKeymap("vim", map[string]map[string][]string{
+        "conversation": {"help": {"f1"}},
+        "selection": {"react": {"a", "b"}},
+        "textarea": {"paste": {}},
+        "form": {"submit": {"ctrl+s"}},
+        "list": {"decline": {"x"}},
+    })
+    if err != nil {
+        t.Fatalf("error loading keymap: %s", err)
+    }
+    t.Cleanup(func() { SetKeymap(nil) })
+    SetKeymap(k)
+    m := InitialModel(&ApiConfig{}).resize(80, 40)
+
+    // the model uses the remapped keys
+    f1 := tea.KeyMsg{Type: tea.KeyF1}
+    if !key.Matches(f1, m.keys.ConversationHelp) || key.Matches(tea.KeyMsg{Type: tea.KeyCtrlH}, m.keys.ConversationHelp) {
+        t.Error("expected help on f1 only")
+    }
+    ctrlU := tea.KeyMsg{Type: tea.KeyCtrlU}
+    if !key.Matches(ctrlU, m.viewport.KeyMap.HalfPageUp) || key.Matches(ctrlU, m.textarea.KeyMap.DeleteBeforeCursor) {
+        t.Error("expected the vim preset to scroll with ctrl+u")
+    }
+    if !key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("h")}, m.keys.Back) ||
+        key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("h")}, m.options.KeyMap.ShowFullHelp) {
+        t.Error("expected the lists to go back with h")
+    }
+
+    // the help screen lists the active bindings
+    help := helpView(m)
+    if !strings.Contains(help, "f1") || !strings.Contains(help, "a | b") {
+        t.Errorf("expected the remapped keys on the help screen, got %s", help)
+    }
+    if strings.Contains(help, "paste") {
+        t.Error("expected disabled actions to be left off the help screen")
+    }
+    // and so do the hints on the forms and in the inbox
+    if login := loginView(m); !strings.Contains(login, "ctrl+s to submit") || strings.Contains(login, "enter to submit") {
+        t.Errorf("expected the remapped submit key on the login form, got %s", login)
+    }
+    if inbox := inboxView(m); !strings.Contains(inbox, "x to decline") {
+        t.Errorf("expected the remapped decline key in the inbox, got %s", inbox)
+    }
+}
@@ -0,0 +1,104 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// terminals at least this wide show the contacts and conversation side by side
+const splitWidth = 100
+
+// panes of the split layout
+const (
+    paneContacts = iota
+    paneConversation
+)
+
+// sidebarWidth is the width of the contact list in the split layout
+func sidebarWidth(width int) int {
+    return min(max(width/3, 30), 48)
+}
+
+// inSplit reports whether the contacts and conversation share the screen
+func inSplit(m Model) bool {
+    return m.split && (m.conversation != "" || m.Chosen == 1)
+}
+
+// updateSplit sends keys to the focused pane, switching panes with tab
+func updateSplit(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
+    if msg, ok := msg.(tea.KeyMsg); ok {
+        if key.Matches(msg, m.keys.SwitchPane) && m.conversation != "" {
+            if m.focus == paneContacts {
+                m = focusPane(m, paneConversation)
+            } else {
+                m = focusPane(m, paneContacts)
+            }
+            return m, nil
+        }
+        if m.focus == paneContacts || m.conversation == "" {
+            return splitContacts(msg, m)
+        }
+        return splitConversation(msg, m)
+    }
+    // everything else, such as resizes and the cursor blink, goes to the
+    // conversation if it is open
+    if m.conversation != "" {
+        return splitConversation(msg, m)
+    }
+    return splitContacts(msg, m)
+}
+
+func splitContacts(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
+    conversation := m.conversation
+    next, cmd := updateContacts(msg, m)
+    m = next.(Model)
+    // leaving the contact list closes the conversation beside it
+    if m.Chosen != 1 && m.conversation != "" {
+        m = markRead(m)
+        m.conversation = ""
+    }
+    if m.conversation != "" && m.conversation != conversation {
+        m = focusPane(m, paneConversation)
+    }
+    return m, cmd
+}
+
+func splitConversation(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
+    next, cmd := updateConversation(msg, m)
+    m = next.(Model)
+    if m.conversation == "" {
+        m = focusPane(m, paneContacts)
+    }
+    return m, cmd
+}
+
+func focusPane(m Model, pane int) Model {
+    m.focus = pane
+    if pane == paneConversation {
+        m.textarea.Focus()
+    } else {
+        m.textarea.Blur()
+    }
+    return m
+}
+
+func splitView(m Model) string {
+    side := sidebarWidth(m.width)
+    leftStyle, rightStyle := paneStyle, paneStyle
+    if m.focus == paneContacts || m.conversation == "" {
+        leftStyle = focusedPaneStyle
+    } else {
+        rightStyle = focusedPaneStyle
+    }
+    left := leftStyle.Width(side).Height(m.height - 2).Render(contactsView(m))
+    var right string
+    if m.conversation != "" {
+        right = conversationView(m)
+    } else {
+        right = lipgloss.NewStyle().Margin(convMargin.height, convMargin.width).
+            Render(subtleStyle.Render("Select a conversation, or press n to start one"))
+    }
+    right = rightStyle.Width(m.width - side - 4).Height(m.height - 2).Render(right)
+    return lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+}
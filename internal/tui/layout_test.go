@@ -0,0 +1,82 @@
+package tui
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+)
+
+func TestSplitLayout(t *testing.T) {
+    // opening a conversation marks it read in the contact book
+    wd, _ := os.Getwd()
+    os.Chdir(t.TempDir())
+    t.Cleanup(func() { os.Chdir(wd) })
+
+    alice := uuid.New()
+    cfg := &ApiConfig{
+        Messages: map[string][]utils.RawMessage{
+            alice.String(): {{Sender: utils.ContactType, Message: "hello", Time: time.Now()}},
+        },
+        Contacts: map[string]requests.Contact{
+            alice.String(): {ID: alice, Name: "Alice"},
+        },
+    }
+    m := InitialModel(cfg)
+    m.loggedIn = true
+    m.Chosen = 1
+    update := func(msg tea.Msg) {
+        next, _ := m.Update(msg)
+        m = next.(Model)
+    }
+
+    // narrow terminals keep one screen at a time
+    update(tea.WindowSizeMsg{Width: 80, Height: 30})
+    if m.split || inSplit(m) {
+        t.Fatal("expected a single screen on a narrow terminal")
+    }
+
+    update(tea.WindowSizeMsg{Width: 140, Height: 30})
+    if !inSplit(m) || m.focus != paneContacts {
+        t.Fatal("expected the split layout with the contacts focused")
+    }
+    if w := lipgloss.Width(m.View()); w > 140 {
+        t.Errorf("expected the layout to fit the terminal, got width %d", w)
+    }
+
+    // opening a conversation focuses it beside the contact list
+    update(tea.KeyMsg{Type: tea.KeyEnter})
+    if m.conversation != alice.String() || m.focus != paneConversation {
+        t.Fatalf("expected alice's conversation to be focused, got %q", m.conversation)
+    }
+    view := m.View()
+    if !strings.Contains(view, "Conversations") || !strings.Contains(view, "hello") {
+        t.Error("expected both panes to be shown")
+    }
+
+    // keys go to the focused pane, so q is typed rather than quitting
+    update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+    if m.Quitting || m.textarea.Value() != "q" {
+        t.Fatal("expected q to be typed in the conversation")
+    }
+    update(tea.KeyMsg{Type: tea.KeyTab})
+    if m.focus != paneContacts || m.conversation != alice.String() {
+        t.Fatal("expected tab to focus the contacts and keep the conversation open")
+    }
+
+    // narrowing the terminal falls back to the conversation screen
+    update(tea.WindowSizeMsg{Width: 80, Height: 30})
+    if inSplit(m) || !strings.Contains(m.View(), "hello") {
+        t.Fatal("expected the conversation on its own on a narrow terminal")
+    }
+    update(tea.KeyMsg{Type: tea.KeyEsc})
+    if m.conversation != "" || m.Chosen != 1 {
+        t.Fatal("expected esc to return to the contact list")
+    }
+}
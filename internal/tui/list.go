@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
@@ -44,7 +45,9 @@ func (d optionDelegate) Render(w io.Writer, m list.Model, index int, listItem li
 
 // list information for contacts
 type contact struct {
-    name, desc  string
+    id, name, desc  string
+    latest          time.Time
+    unread          int
 }
 func (c contact) FilterValue() string { return "" }
 type contactDelegate struct{
@@ -57,46 +60,90 @@ func (d contactDelegate) Render(w io.Writer, m list.Model, index int, listItem l
 		return
 	}
 
+    var badge string
+    if i.unread > 0 {
+        badge = " " + badgeStyle.Render(fmt.Sprint(i.unread))
+    }
     var str string
 	if index == m.Index() {
-        str = selectedContactStyleName.Render(i.name) + "\n" + 
+        str = selectedContactStyleName.Render(i.name) + badge + "\n" + 
             selectedContactStyleDesc.Render(i.desc)
 	} else {
-        str = contactStyleName.Render(i.name) + "\n" +
+        str = contactStyleName.Render(i.name) + badge + "\n" +
             contactStyleDesc.Render(i.desc)
     }
 
 	fmt.Fprint(w, str + "\n")
 }
 
-// list key map
+// key bindings of the menus, conversation and forms, see keymap.go for the
+// textarea and viewport
 type listKeyMap struct {
-    optionUp        key.Binding
-    optionDown      key.Binding
-    toggleHelpMenu  key.Binding
-    findOption      key.Binding
-    Enter           key.Binding
-    Back            key.Binding
-    Quit            key.Binding
+    // lists
+    optionUp          key.Binding
+    optionDown        key.Binding
+    NextPage          key.Binding
+    PrevPage          key.Binding
+    GoToStart         key.Binding
+    GoToEnd           key.Binding
+    toggleHelpMenu    key.Binding
+    Enter             key.Binding
+    Back              key.Binding
+    Quit              key.Binding
+    NewConversation   key.Binding
+    Accept            key.Binding
+    Decline           key.Binding
+    Block             key.Binding
+    // every screen
+    ForceQuit         key.Binding
+    Cancel            key.Binding
+    Jump              key.Binding
+    SwitchPane        key.Binding
+    // conversation
+    Send              key.Binding
+    ConversationHelp  key.Binding
+    Editor            key.Binding
+    Select            key.Binding
+    Reply             key.Binding
+    Edit              key.Binding
+    Delete            key.Binding
+    React             key.Binding
+    // forms
+    NextField         key.Binding
+    PrevField         key.Binding
+    Submit            key.Binding
+    CreateAccount     key.Binding
 }
 func newListKeyMap() *listKeyMap {
     return &listKeyMap{
         optionUp: key.NewBinding(
             key.WithKeys("up", "k"),
-            key.WithHelp("up", "previous option"),
+            key.WithHelp("up | k", "previous option"),
         ),
         optionDown: key.NewBinding(
             key.WithKeys("down", "j"),
-            key.WithHelp("down", "next option"),
+            key.WithHelp("down | j", "next option"),
+        ),
+        NextPage: key.NewBinding(
+            key.WithKeys("right", "pgdown"),
+            key.WithHelp("right | pgdown", "next page"),
+        ),
+        PrevPage: key.NewBinding(
+            key.WithKeys("left", "pgup"),
+            key.WithHelp("left | pgup", "previous page"),
+        ),
+        GoToStart: key.NewBinding(
+            key.WithKeys("home", "g"),
+            key.WithHelp("home | g", "go to start"),
+        ),
+        GoToEnd: key.NewBinding(
+            key.WithKeys("end", "G"),
+            key.WithHelp("end | G", "go to end"),
         ),
         toggleHelpMenu: key.NewBinding(
             key.WithKeys("ctrl+h", "h", "?"),
             key.WithHelp("ctrl+h | h | ?", "display help menu"),
         ),
-        findOption: key.NewBinding(
-            key.WithKeys("ctrl+f", "f"),
-            key.WithHelp("ctrl+f | f", "add new option"),
-        ),
         Enter: key.NewBinding(
             key.WithKeys("enter"),
             key.WithHelp("enter", "select option"),
@@ -109,26 +156,102 @@ func newListKeyMap() *listKeyMap {
             key.WithKeys("ctrl+c", "q"),
             key.WithHelp("ctrl+c | q", "quit mescli"),
         ),
+        NewConversation: key.NewBinding(
+            key.WithKeys("n"),
+            key.WithHelp("n", "new conversation"),
+        ),
+        Accept: key.NewBinding(
+            key.WithKeys("enter", "a"),
+            key.WithHelp("enter | a", "accept request"),
+        ),
+        Decline: key.NewBinding(
+            key.WithKeys("d"),
+            key.WithHelp("d", "decline request"),
+        ),
+        Block: key.NewBinding(
+            key.WithKeys("b"),
+            key.WithHelp("b", "decline and block"),
+        ),
+        ForceQuit: key.NewBinding(
+            key.WithKeys("ctrl+c"),
+            key.WithHelp("ctrl+c", "quit mescli"),
+        ),
+        Cancel: key.NewBinding(
+            key.WithKeys("esc"),
+            key.WithHelp("esc", "return to previous screen"),
+        ),
+        Jump: key.NewBinding(
+            key.WithKeys("ctrl+g"),
+            key.WithHelp("ctrl+g", "open notified conversation"),
+        ),
+        SwitchPane: key.NewBinding(
+            key.WithKeys("tab"),
+            key.WithHelp("tab", "switch between contacts and conversation"),
+        ),
+        Send: key.NewBinding(
+            key.WithKeys("enter"),
+            key.WithHelp("enter", "send message"),
+        ),
+        ConversationHelp: key.NewBinding(
+            key.WithKeys("ctrl+h"),
+            key.WithHelp("ctrl+h", "show this help screen"),
+        ),
+        Editor: key.NewBinding(
+            key.WithKeys("ctrl+x"),
+            key.WithHelp("ctrl+x", "compose in $EDITOR"),
+        ),
+        Select: key.NewBinding(
+            key.WithKeys("ctrl+o"),
+            key.WithHelp("ctrl+o", "select a message"),
+        ),
+        Reply: key.NewBinding(
+            key.WithKeys("r"),
+            key.WithHelp("r", "reply"),
+        ),
+        Edit: key.NewBinding(
+            key.WithKeys("e"),
+            key.WithHelp("e", "edit"),
+        ),
+        Delete: key.NewBinding(
+            key.WithKeys("d"),
+            key.WithHelp("d", "delete for everyone"),
+        ),
+        React: key.NewBinding(
+            key.WithKeys("1", "2", "3", "4", "5", "6"),
+            key.WithHelp("1-6", "react"),
+        ),
+        NextField: key.NewBinding(
+            key.WithKeys("tab", "down"),
+            key.WithHelp("tab | down", "next field"),
+        ),
+        PrevField: key.NewBinding(
+            key.WithKeys("shift+tab", "up"),
+            key.WithHelp("shift+tab | up", "previous field"),
+        ),
+        Submit: key.NewBinding(
+            key.WithKeys("enter"),
+            key.WithHelp("enter", "submit"),
+        ),
+        CreateAccount: key.NewBinding(
+            key.WithKeys("ctrl+n"),
+            key.WithHelp("ctrl+n", "create an account"),
+        ),
     }
 }
 
-func newListDelegate(keys *listKeyMap) list.DefaultDelegate {
-    d := list.NewDefaultDelegate()
-
-    d.UpdateFunc = func(msg tea.Msg, m *list.Model) tea.Cmd {
-        return nil
-    }
-
-    help := []key.Binding{keys.Back, keys.Quit, keys.toggleHelpMenu}
-
-    d.ShortHelpFunc = func() []key.Binding {
-        return help
-    }
-
-    d.FullHelpFunc = func() [][]key.Binding {
-        return [][]key.Binding{help, {keys.optionUp, keys.optionDown, keys.findOption}}
-    }
-
-    return d
+// listModelKeys are the bindings the bubbles lists navigate with. The screens
+// handle quitting before the list sees the key.
+func (k *listKeyMap) listModelKeys() list.KeyMap {
+    km := list.DefaultKeyMap()
+    km.CursorUp = k.optionUp
+    km.CursorDown = k.optionDown
+    km.NextPage = k.NextPage
+    km.PrevPage = k.PrevPage
+    km.GoToStart = k.GoToStart
+    km.GoToEnd = k.GoToEnd
+    km.ShowFullHelp = k.toggleHelpMenu
+    km.CloseFullHelp = k.toggleHelpMenu
+    km.Quit = k.Quit
+    km.ForceQuit.SetEnabled(false)
+    return km
 }
-
@@ -3,14 +3,13 @@ package tui
 import (
 	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 
 	"github.com/CraigYanitski/mescli/assets"
 	"github.com/CraigYanitski/mescli/internal/requests"
 	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/spf13/viper"
 )
 
 // login struct
@@ -20,37 +19,26 @@ const (
 )
 
 func updateLogin(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
-    if viper.GetString("access_token") != "" {
-        m.loggedIn = true
-        return m, nil
-    }
-
     cmds := make([]tea.Cmd, len(m.loginInputs))
     switch msg := msg.(type) {
     case tea.KeyMsg:
-        switch msg.Type {
-        case tea.KeyCtrlC, tea.KeyEscape:
+        switch {
+        case key.Matches(msg, m.keys.ForceQuit), key.Matches(msg, m.keys.Cancel):
             m.Quitting = true
             return m, tea.Quit 
-        case tea.KeyTab, tea.KeyDown:
+        case key.Matches(msg, m.keys.NextField):
             m.loginFocus = (m.loginFocus + 1) % len(m.loginInputs)
-        case tea.KeyShiftTab, tea.KeyUp:
+        case key.Matches(msg, m.keys.PrevField):
             m.loginFocus = ((m.loginFocus - 1) % len(m.loginInputs) + len(m.loginInputs)) % len(m.loginInputs)
-        case tea.KeyEnter:
-            err := requests.LoginWithPassword(
-                m.loginInputs[loginEmail].Value(), 
-                m.loginInputs[loginPassword].Value(),
-            )
-            if err != nil {
-                m.loginMsg = fmt.Sprintf(loginMsgWrapping, utils.ErrorStyle.Render("Invalid login"))
+        case key.Matches(msg, m.keys.Submit):
+            if m.pending != "" {
                 return m, nil
             }
-            m.loggedIn = true
-            m.loginFocus = 0
-            for i, _ := range m.loginInputs {
-                m.loginInputs[i].SetValue("")
-            }
-        case tea.KeyCtrlN:
+            return startPending(m, "Logging in...", login(
+                m.loginInputs[loginEmail].Value(), 
+                m.loginInputs[loginPassword].Value(),
+            ))
+        case key.Matches(msg, m.keys.CreateAccount):
             m.created = false
             return m, nil
         }
@@ -65,6 +53,21 @@ func updateLogin(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
     return m, tea.Batch(cmds...)
 }
 
+func loginResult(msg loginResultMsg, m Model) Model {
+    m.pending = ""
+    if msg.err != nil {
+        m.loginMsg = utils.ErrorStyle.Render("Invalid login")
+        return m
+    }
+    m.loggedIn = true
+    m.loginFocus = 0
+    m.loginMsg = ""
+    for i, _ := range m.loginInputs {
+        m.loginInputs[i].SetValue("")
+    }
+    return m
+}
+
 func loginView(m Model) string{
     // obscure password
     pw := m.loginInputs[loginPassword].Value()
@@ -76,61 +79,22 @@ func loginView(m Model) string{
         assets.Logo,
         m.loginInputs[loginEmail].View(), 
         m.loginInputs[loginPassword].View(),
-        m.loginMsg,
+        formStatus(m, m.loginMsg,
+            hint(m.keys.Submit, "submit credentials"),
+            hint(m.keys.CreateAccount, "create a new account"),
+        ),
     )
     // restore password
     m.loginInputs[loginPassword].SetValue(pw)
     return s
 }
 
-func emailValidator(s string) error {
-    ok, err := regexp.MatchString(`([a-zA-Z0-9\._-]+)\@`, s)
-    if err == nil && !ok {
-        err = errors.New("not a valid email")
-    }
-    if err != nil {
-        return err
-    }
-    ok, err = regexp.MatchString(`(\@[a-zA-Z0-9\._-]+)\.`, s)
-    if err == nil && !ok {
-        err = errors.New("not a valid email")
+// return to the login screen if the refresh token is no longer valid
+func sessionExpired(m Model, err error) (Model, bool) {
+    if !errors.Is(err, requests.ErrSessionExpired) {
+        return m, false
     }
-    if err != nil {
-        return err
-    }
-    ok, err = regexp.MatchString(`(\.[a-zA-Z0-9]+)`, s)
-    if err == nil && !ok {
-        err = errors.New("not a valid email")
-    }
-    return err
-}
-
-func passwordValidator(s string) error {
-    ok, err := regexp.MatchString(`[a-z]+`, s)
-    if err == nil && !ok {
-        err = errors.New("password does not contain lowercase characters")
-    }
-    if err != nil {
-        return err
-    }
-    ok, err = regexp.MatchString(`[A-Z]+`, s)
-    if err == nil && !ok {
-        err = errors.New("password does not contain uppercase characters")
-    }
-    if err != nil {
-        return err
-    }
-    ok, err = regexp.MatchString(`[^a-zA-Z0-9]+`, s)
-    if err == nil && !ok {
-        err = errors.New("password does not contain special characters")
-    }
-    if err != nil {
-        return err
-    }
-    ok, err = regexp.MatchString(`.{8,}`, s)
-    if err == nil && !ok {
-        err = errors.New("password not long enough")
-    }
-    return err
+    m.loggedIn = false
+    m.loginMsg = utils.ErrorStyle.Render("Session expired, please login again")
+    return m, true
 }
-
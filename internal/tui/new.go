@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CraigYanitski/mescli/assets"
+	"github.com/CraigYanitski/mescli/internal/client"
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type newConversationMsg struct {
+    contact  requests.Contact
+    err      error
+}
+
+// startConversation resolves a user and performs the X3DH key exchange with
+// them, away from the update loop
+func startConversation(user string) tea.Cmd {
+    return background(func() tea.Msg {
+        c, err := requests.FetchContact(user)
+        if err != nil {
+            return newConversationMsg{err: err}
+        }
+        // keep an established session rather than exchanging keys again
+        if client.HasSession(c.ID) {
+            return newConversationMsg{contact: c}
+        }
+        if _, err = requests.AddContact(c.ID.String()); err != nil {
+            return newConversationMsg{err: fmt.Errorf("key exchange failed: %w", err)}
+        }
+        return newConversationMsg{contact: c}
+    })
+}
+
+// openNew shows the new conversation screen
+func openNew(m Model) Model {
+    m.Chosen = 6
+    m.newMsg = ""
+    m.newInput.SetValue("")
+    m.newInput.Focus()
+    return m
+}
+
+func updateNew(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
+    switch msg := msg.(type) {
+    case tea.KeyMsg:
+        switch {
+        case key.Matches(msg, m.keys.ForceQuit):
+            m.Quitting = true
+            return m, tea.Quit
+        case key.Matches(msg, m.keys.Cancel):
+            m.Chosen = 1
+            m.newInput.Blur()
+            return m, nil
+        case key.Matches(msg, m.keys.Submit):
+            if m.pending != "" {
+                return m, nil
+            }
+            user := strings.TrimSpace(m.newInput.Value())
+            if user == "" {
+                m.newMsg = utils.ErrorStyle.Render("Enter an email or UUID")
+                return m, nil
+            }
+            // refuse blocked contacts before contacting the server
+            if c, ok := requests.FindContact(m.cfg.Contacts, user); ok {
+                if c.Blocked {
+                    m.newMsg = utils.ErrorStyle.Render(c.DisplayName() + " is blocked")
+                    return m, nil
+                }
+                user = c.ID.String()
+            }
+            m.newMsg = ""
+            return startPending(m, "Exchanging keys...", startConversation(user))
+        }
+    }
+    var cmd tea.Cmd
+    m.newInput, cmd = m.newInput.Update(msg)
+    return m, cmd
+}
+
+func newConversationResult(msg newConversationMsg, m Model) Model {
+    m.pending = ""
+    if msg.err != nil {
+        if expired, ok := sessionExpired(m, msg.err); ok {
+            return expired
+        }
+        m.newMsg = utils.ErrorStyle.Render(msg.err.Error())
+        return m
+    }
+    // keep any local details of a known contact
+    if m.cfg.Contacts == nil {
+        m.cfg.Contacts = make(map[string]requests.Contact)
+    }
+    c := requests.MergeContact(m.cfg.Contacts, msg.contact)
+    if err := requests.WriteContacts(m.cfg.Contacts); err != nil {
+        m.newMsg = utils.ErrorStyle.Render(err.Error())
+        return m
+    }
+    m.contacts.SetItems(contactItems(m.cfg))
+    // only open the conversation if the user is still waiting for it
+    if m.Chosen != 6 || m.conversation != "" {
+        return m
+    }
+    m.newInput.Blur()
+    m.Chosen = 1
+    m.conversation = c.ID.String()
+    m = resetActions(m)
+    m = initialiseConversation(m)
+    m = focusPane(m, paneConversation)
+    m = markRead(m)
+    return m
+}
+
+func newView(m Model) string {
+    hints := hint(m.keys.Submit, "exchange keys and open the conversation") + "\n" + hint(m.keys.Cancel, "cancel")
+    return fmt.Sprintf(newWrapping, assets.Logo, m.newInput.View(), pendingStatus(m, m.newMsg), hints)
+}
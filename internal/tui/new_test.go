@@ -0,0 +1,53 @@
+package tui
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+func TestNewConversation(t *testing.T) {
+    // the contact book is written to the working directory
+    wd, _ := os.Getwd()
+    os.Chdir(t.TempDir())
+    t.Cleanup(func() { os.Chdir(wd) })
+
+    blocked := uuid.New()
+    cfg := &ApiConfig{
+        Messages: map[string][]utils.RawMessage{},
+        Contacts: map[string]requests.Contact{
+            blocked.String(): {ID: blocked, Nickname: "spam", Blocked: true},
+        },
+    }
+    m := openNew(InitialModel(cfg))
+    m.loggedIn = true
+
+    // blocked contacts are refused without a request
+    m.newInput.SetValue("spam")
+    next, cmd := updateNew(tea.KeyMsg{Type: tea.KeyEnter}, m)
+    m = next.(Model)
+    if cmd != nil || m.pending != "" || m.newMsg == "" {
+        t.Fatal("expected blocked contact to be refused")
+    }
+
+    // errors are reported on the same screen
+    m = newConversationResult(newConversationMsg{err: errors.New("no user")}, m)
+    if m.Chosen != 6 || m.conversation != "" || m.newMsg == "" {
+        t.Fatal("expected error to be shown on the new conversation screen")
+    }
+
+    // a successful key exchange saves the contact and opens the conversation
+    alice := requests.Contact{ID: uuid.New(), Email: "alice@example.com", Name: "Alice"}
+    m = newConversationResult(newConversationMsg{contact: alice}, m)
+    if m.conversation != alice.ID.String() || m.Chosen != 1 {
+        t.Fatalf("expected conversation with alice to open, got %q", m.conversation)
+    }
+    if c, ok := cfg.Contacts[alice.ID.String()]; !ok || c.Email != alice.Email {
+        t.Fatal("expected alice to be saved in the contact book")
+    }
+}
@@ -0,0 +1,140 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// how long a notification banner stays on screen
+const noticeDuration = 5 * time.Second
+
+// terminal notifications, chosen with the notifications config key
+const (
+    notifyNone    = "none"
+    notifyBell    = "bell"
+    notifyOSC9    = "osc9"
+    notifyOSC777  = "osc777"
+)
+
+// notice is a banner announcing a message in a conversation that is not open
+type notice struct {
+    id            int
+    conversation  string
+    text          string
+}
+
+type noticeExpiredMsg struct {
+    id  int
+}
+
+// newNotice announces new messages arriving outside the open conversation,
+// returning the commands to clear the banner and notify the terminal
+func newNotice(m Model, messages []requests.MessageResponse) (Model, tea.Cmd) {
+    var last *requests.MessageResponse
+    senders := make(map[string]bool)
+    for i, msg := range messages {
+        if msg.SenderID.String() == m.conversation {
+            continue
+        }
+        senders[msg.SenderID.String()] = true
+        last = &messages[i]
+    }
+    if last == nil {
+        return m, nil
+    }
+    id := last.SenderID.String()
+    name := id
+    if c, ok := m.cfg.Contacts[id]; ok {
+        name = c.DisplayName()
+    }
+    text := name + ": " + messagePreview(last.Message, previewLength)
+    if len(senders) > 1 {
+        text += fmt.Sprintf(" (and %d more)", len(senders)-1)
+    }
+    m.noticeCount++
+    noticeID := m.noticeCount
+    m.notice = notice{id: noticeID, conversation: id, text: text}
+    expire := tea.Tick(noticeDuration, func(time.Time) tea.Msg {
+        return noticeExpiredMsg{id: noticeID}
+    })
+    return m, tea.Batch(expire, notify(m.settings.notifications, "mescli", text))
+}
+
+// updateNotice clears expired banners and jumps to the announced conversation
+func updateNotice(msg tea.Msg, m Model) (Model, tea.Cmd, bool) {
+    switch msg := msg.(type) {
+    case noticeExpiredMsg:
+        if msg.id == m.notice.id {
+            m.notice = notice{}
+        }
+        return m, nil, true
+    case tea.KeyMsg:
+        if m.notice.conversation == "" || !m.loggedIn || !key.Matches(msg, m.keys.Jump) {
+            return m, nil, false
+        }
+        if m.conversation != "" {
+            m = markRead(m)
+        }
+        m.conversation = m.notice.conversation
+        m.notice = notice{}
+        m.Chosen = 1
+        m.viewHelp = false
+        m.sendMsg = ""
+        m = resetActions(m)
+        m = initialiseConversation(m)
+        m = focusPane(m, paneConversation)
+        m = markRead(m)
+        return m, nil, true
+    }
+    return m, nil, false
+}
+
+// notify rings the terminal bell or sends a desktop notification
+func notify(kind, title, body string) tea.Cmd {
+    seq := notifySequence(kind, title, body)
+    if seq == "" {
+        return nil
+    }
+    return func() tea.Msg {
+        fmt.Fprint(os.Stdout, seq)
+        return nil
+    }
+}
+
+// notifySequence returns the escape sequence for a kind of notification,
+// stripping control characters from the untrusted message text
+func notifySequence(kind, title, body string) string {
+    title, body = stripControl(title), stripControl(body)
+    switch kind {
+    case notifyBell:
+        return "\a"
+    case notifyOSC9:
+        return "\x1b]9;" + title + ": " + body + "\a"
+    case notifyOSC777:
+        // the fields of OSC 777 are separated by semicolons
+        title = strings.ReplaceAll(title, ";", ",")
+        return "\x1b]777;notify;" + title + ";" + body + "\a"
+    }
+    return ""
+}
+
+func stripControl(s string) string {
+    return strings.Map(func(r rune) rune {
+        if unicode.IsControl(r) {
+            return ' '
+        }
+        return r
+    }, s)
+}
+
+func noticeView(m Model) string {
+    return noticeStyle.Render(m.notice.text) + "  " +
+        subtleStyle.Render(m.keys.Jump.Help().Key+" to open")
+}
@@ -0,0 +1,85 @@
+package tui
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+func TestNotifySequence(t *testing.T) {
+    cases := []struct {
+        kind   string
+        title  string
+        body   string
+        want   string
+    }{
+        {notifyNone, "mescli", "hi", ""},
+        {"", "mescli", "hi", ""},
+        {notifyBell, "mescli", "hi", "\a"},
+        {notifyOSC9, "mescli", "Bob: hi", "\x1b]9;mescli: Bob: hi\a"},
+        {notifyOSC777, "mescli", "Bob: hi", "\x1b]777;notify;mescli;Bob: hi\a"},
+        {notifyOSC777, "a;b", "hi", "\x1b]777;notify;a,b;hi\a"},
+        // message text cannot end the sequence early
+        {notifyOSC9, "mescli", "hi\a\x1b]9;spoof", "\x1b]9;mescli: hi  ]9;spoof\a"},
+    }
+    for _, c := range cases {
+        if got := notifySequence(c.kind, c.title, c.body); got != c.want {
+            t.Errorf("%s notification of %q: expected %q, got %q", c.kind, c.body, c.want, got)
+        }
+    }
+}
+
+func TestNotice(t *testing.T) {
+    // marking conversations read writes the contact book
+    wd, _ := os.Getwd()
+    os.Chdir(t.TempDir())
+    t.Cleanup(func() { os.Chdir(wd) })
+
+    alice, bob := uuid.New(), uuid.New()
+    cfg := &ApiConfig{
+        Messages: map[string][]utils.RawMessage{},
+        Contacts: map[string]requests.Contact{
+            bob.String(): {ID: bob, Nickname: "bobby"},
+        },
+    }
+    m := InitialModel(cfg)
+    m.loggedIn = true
+    m.conversation = alice.String()
+
+    // messages in the open conversation are not announced
+    m, cmd := newNotice(m, []requests.MessageResponse{{SenderID: alice, Message: "hi"}})
+    if cmd != nil || m.notice.text != "" {
+        t.Fatal("expected no notice for the open conversation")
+    }
+
+    m, cmd = newNotice(m, []requests.MessageResponse{
+        {SenderID: alice, Message: "hi"},
+        {SenderID: bob, Message: "are you there?\nhello?"},
+    })
+    if cmd == nil || m.notice.conversation != bob.String() || m.notice.text != "bobby: are you there?" {
+        t.Fatalf("unexpected notice: %+v", m.notice)
+    }
+    if view := m.View(); !strings.Contains(view, "bobby: are you there?") {
+        t.Error("expected the notice to be shown on screen")
+    }
+
+    // an older banner expiring leaves the current one
+    m, _, _ = updateNotice(noticeExpiredMsg{id: m.notice.id - 1}, m)
+    if m.notice.text == "" {
+        t.Fatal("expected the notice to stay")
+    }
+
+    // the jump key opens the announced conversation
+    m, _, ok := updateNotice(tea.KeyMsg{Type: tea.KeyCtrlG}, m)
+    if !ok || m.conversation != bob.String() || m.notice.text != "" {
+        t.Fatalf("expected to jump to bob, got %q", m.conversation)
+    }
+    if cfg.Contacts[alice.String()].LastRead.IsZero() || cfg.Contacts[bob.String()].LastRead.IsZero() {
+        t.Error("expected both conversations to be marked read")
+    }
+}
@@ -0,0 +1,68 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// time between checks for new messages
+const pollInterval = 10 * time.Second
+
+type pollMsg struct{}
+
+type messagesMsg struct {
+    messages  []requests.MessageResponse
+    err       error
+}
+
+func pollMessages() tea.Cmd {
+    return tea.Tick(pollInterval, func(time.Time) tea.Msg {
+        return pollMsg{}
+    })
+}
+
+var fetchMessages = background(func() tea.Msg {
+    messages, err := requests.FetchMessages()
+    return messagesMsg{messages: messages, err: err}
+})
+
+// updateMessages polls for new messages whatever screen is shown, storing
+// them and refreshing the contact list and open conversation
+func updateMessages(msg tea.Msg, m Model) (Model, tea.Cmd, bool) {
+    switch msg := msg.(type) {
+    case pollMsg:
+        // skip while a send may be advancing the session ratchets
+        if !m.loggedIn || m.pending != "" {
+            return m, pollMessages(), true
+        }
+        return m, tea.Batch(fetchMessages, presenceCmd(m)), true
+    case messagesMsg:
+        if msg.err != nil {
+            if expired, ok := sessionExpired(m, msg.err); ok {
+                return expired, pollMessages(), true
+            }
+            m.err = msg.err
+            return m, pollMessages(), true
+        }
+        // typing signals are shown but not stored
+        m = applySignals(m, msg.messages)
+        messages, _ := requests.SplitSignals(msg.messages)
+        if len(messages) == 0 {
+            return m, pollMessages(), true
+        }
+        if err := requests.StoreMessages(m.cfg.Messages, messages); err != nil {
+            m.err = err
+        }
+        if m.conversation != "" {
+            m = initialiseConversation(m)
+            m = markRead(m)
+        } else {
+            m.contacts.SetItems(contactItems(m.cfg))
+        }
+        m, cmd := newNotice(m, messages)
+        return m, tea.Batch(pollMessages(), cmd), true
+    }
+    return m, nil, false
+}
@@ -0,0 +1,219 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// reactions offered in selection mode, chosen with the react keys (1 to 6)
+var reactions = []string{"👍", "❤️", "😂", "😮", "😢", "🙏"}
+
+// startSelection selects the latest message that can be acted on
+func startSelection(m Model) Model {
+    m.selected = len(m.cfg.Messages[m.conversation])
+    m = moveSelection(m, -1)
+    if m.selected < 0 {
+        m.sendMsg = utils.ErrorStyle.Render("No messages to select")
+        return m
+    }
+    m.selecting = true
+    m.confirmDelete = ""
+    m.textarea.Blur()
+    return initialiseConversation(m)
+}
+
+// moveSelection moves to the next message with an ID in the direction of
+// step, staying put if there is none
+func moveSelection(m Model, step int) Model {
+    msgs := m.cfg.Messages[m.conversation]
+    for i := m.selected + step; i >= 0 && i < len(msgs); i += step {
+        if msgs[i].ID != "" && !msgs[i].Deleted {
+            m.selected = i
+            return m
+        }
+    }
+    if m.selected >= len(msgs) {
+        m.selected = -1
+    }
+    return m
+}
+
+func stopSelection(m Model) Model {
+    m.selecting = false
+    m.confirmDelete = ""
+    m.textarea.Focus()
+    return initialiseConversation(m)
+}
+
+// resetActions forgets the selection, reply and edit of a conversation
+func resetActions(m Model) Model {
+    m.selecting = false
+    m.replyTo = ""
+    m.editing = ""
+    m.confirmDelete = ""
+    return m
+}
+
+func updateSelection(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
+    keyMsg, ok := msg.(tea.KeyMsg)
+    if !ok {
+        var cmd tea.Cmd
+        if size, ok := msg.(tea.WindowSizeMsg); ok {
+            m = m.resize(size.Width, size.Height)
+            m = initialiseConversation(m)
+        }
+        m.viewport, cmd = m.viewport.Update(msg)
+        return m, cmd
+    }
+    selected := m.cfg.Messages[m.conversation][m.selected]
+    own := selected.Sender == utils.SelfType
+    // anything but a second press of the delete key cancels a deletion
+    confirm := m.confirmDelete
+    m.confirmDelete = ""
+    m.sendMsg = ""
+    switch {
+    case key.Matches(keyMsg, m.keys.ForceQuit):
+        m.Quitting = true
+        return m, tea.Quit
+    case key.Matches(keyMsg, m.keys.Cancel):
+        return stopSelection(m), nil
+    case key.Matches(keyMsg, m.keys.optionUp):
+        m = moveSelection(m, -1)
+        return initialiseConversation(m), nil
+    case key.Matches(keyMsg, m.keys.optionDown):
+        m = moveSelection(m, 1)
+        return initialiseConversation(m), nil
+    case key.Matches(keyMsg, m.keys.Reply):
+        m.replyTo = selected.ID
+        m.editing = ""
+        return stopSelection(m), nil
+    case key.Matches(keyMsg, m.keys.Edit):
+        if !own {
+            m.sendMsg = utils.ErrorStyle.Render("You can only edit your own messages")
+            return m, nil
+        }
+        m.editing = selected.ID
+        m.replyTo = ""
+        m.textarea.SetValue(selected.Message)
+        return stopSelection(m), nil
+    }
+    // the remaining actions are sent, one at a time
+    if m.pending != "" {
+        return m, nil
+    }
+    switch {
+    case key.Matches(keyMsg, m.keys.Delete):
+        if !own {
+            m.sendMsg = utils.ErrorStyle.Render("You can only delete your own messages")
+            return m, nil
+        }
+        if confirm != selected.ID {
+            m.confirmDelete = selected.ID
+            m.sendMsg = utils.ErrorStyle.Render("Press " + m.keys.Delete.Help().Key +
+                " again to delete this message for everyone")
+            return m, nil
+        }
+        p := utils.NewPayload(utils.DeletePayload, "")
+        p.Target = selected.ID
+        m = stopSelection(m)
+        return sendPayload(m, p)
+    case key.Matches(keyMsg, m.keys.React):
+        // the react keys choose the reactions in order
+        i := slices.Index(m.keys.React.Keys(), keyMsg.String())
+        if i >= len(reactions) {
+            return m, nil
+        }
+        emoji := reactions[i]
+        // choosing your current reaction again removes it
+        for _, r := range selected.Reactions {
+            if r.Sender == utils.SelfType && r.Emoji == emoji {
+                emoji = ""
+            }
+        }
+        p := utils.NewPayload(utils.ReactionPayload, emoji)
+        p.Target = selected.ID
+        return sendPayload(m, p)
+    }
+    return m, nil
+}
+
+// sendPayload applies payloads to the open conversation and sends them in
+// order
+func sendPayload(m Model, ps ...utils.Payload) (Model, tea.Cmd) {
+    for _, p := range ps {
+        m.cfg.Messages[m.conversation] = utils.ApplyPayload(
+            m.cfg.Messages[m.conversation],
+            utils.SelfType,
+            p,
+            p.SentAt.Local(),
+        )
+    }
+    m.sendMsg = ""
+    if !requests.WriteMessages(m.cfg.Messages) {
+        m.sendMsg = utils.ErrorStyle.Render("Unable to save messages locally")
+    }
+    m = initialiseConversation(m)
+    return startPending(m, "Sending...", sendMessage(m.conversation, ps...))
+}
+
+// scrollToSelection brings the selected message into view
+func scrollToSelection(m Model) Model {
+    offset := 0
+    for _, msg := range m.messages[m.conversation][:m.selected] {
+        offset += lipgloss.Height(msg)
+    }
+    height := lipgloss.Height(m.messages[m.conversation][m.selected])
+    if offset < m.viewport.YOffset {
+        m.viewport.SetYOffset(offset)
+    } else if offset + height > m.viewport.YOffset + m.viewport.Height {
+        m.viewport.SetYOffset(offset + height - m.viewport.Height)
+    }
+    return m
+}
+
+// composeContext describes the message being replied to or edited
+func composeContext(m Model) string {
+    if m.editing != "" {
+        return subtleStyle.Render("Editing message · " + m.keys.Cancel.Help().Key + " to cancel")
+    }
+    if m.replyTo == "" {
+        return ""
+    }
+    return quote(m, m.replyTo) + subtleStyle.Render(" · " + m.keys.Cancel.Help().Key + " to cancel")
+}
+
+// quote previews the message being replied to above a reply
+func quote(m Model, id string) string {
+    msgs := m.cfg.Messages[m.conversation]
+    i := utils.FindMessage(msgs, id)
+    if i < 0 {
+        return quoteStyle.Render("↳ original message unavailable")
+    }
+    if msgs[i].Deleted {
+        return quoteStyle.Render("↳ " + senderName(m, msgs[i].Sender) + ": message deleted")
+    }
+    return quoteStyle.Render("↳ " + senderName(m, msgs[i].Sender) + ": " +
+        messagePreview(msgs[i].Message, previewLength))
+}
+
+func senderName(m Model, sender utils.SenderType) string {
+    if sender == utils.SelfType {
+        return "You"
+    }
+    return contactName(m)
+}
+
+// reactionLine lists the reactions to a message
+func reactionLine(rs []utils.Reaction) string {
+    emojis := make([]string, len(rs))
+    for i, r := range rs {
+        emojis[i] = r.Emoji
+    }
+    return strings.Join(emojis, " ")
+}
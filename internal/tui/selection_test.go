@@ -0,0 +1,122 @@
+package tui
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/requests"
+	"github.com/CraigYanitski/mescli/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+func TestSelection(t *testing.T) {
+    // the messages are saved to the working directory
+    wd, _ := os.Getwd()
+    os.Chdir(t.TempDir())
+    t.Cleanup(func() { os.Chdir(wd) })
+
+    alice := uuid.NewString()
+    cfg := &ApiConfig{
+        Messages: map[string][]utils.RawMessage{
+            alice: {
+                // messages from older clients have no ID to refer to
+                {Sender: utils.ContactType, Message: "old", Time: time.Now()},
+                {ID: "a", Sender: utils.ContactType, Message: "hello", Time: time.Now()},
+                {ID: "b", Sender: utils.SelfType, Message: "mine", Time: time.Now()},
+            },
+        },
+        Contacts: map[string]requests.Contact{
+            alice: {Name: "Alice"},
+        },
+    }
+    m := InitialModel(cfg).resize(100, 40)
+    m.loggedIn = true
+    m.Chosen = 1
+    m.conversation = alice
+    m = initialiseConversation(m)
+    var cmd tea.Cmd
+    update := func(msg tea.KeyMsg) {
+        var next tea.Model
+        next, cmd = updateConversation(msg, m)
+        m = next.(Model)
+    }
+    runes := func(s string) tea.KeyMsg {
+        return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+    }
+
+    update(tea.KeyMsg{Type: tea.KeyCtrlO})
+    if !m.selecting || m.selected != 2 {
+        t.Fatalf("expected the latest message to be selected, got %d", m.selected)
+    }
+    update(runes("k"))
+    update(runes("k"))
+    if m.selected != 1 {
+        t.Fatalf("expected the selection to stop at the oldest message with an ID, got %d", m.selected)
+    }
+
+    // only your own messages can be edited
+    update(runes("e"))
+    if m.editing != "" || !m.selecting {
+        t.Fatal("expected not to edit alice's message")
+    }
+
+    update(runes("r"))
+    if m.selecting || m.replyTo != "a" {
+        t.Fatal("expected to reply to alice's message")
+    }
+    if view := m.View(); !strings.Contains(view, "↳ Alice: hello") {
+        t.Error("expected the message being replied to to be shown")
+    }
+    // esc cancels the reply before leaving the conversation
+    update(tea.KeyMsg{Type: tea.KeyEsc})
+    if m.replyTo != "" || m.conversation != alice {
+        t.Fatal("expected esc to cancel the reply")
+    }
+
+    update(tea.KeyMsg{Type: tea.KeyCtrlO})
+    update(runes("e"))
+    if m.editing != "b" || m.textarea.Value() != "mine" {
+        t.Fatalf("expected to edit my message, got %q", m.textarea.Value())
+    }
+    m.textarea.SetValue("mine!")
+    update(tea.KeyMsg{Type: tea.KeyEnter})
+    if cmd == nil || m.pending == "" || m.editing != "" {
+        t.Fatal("expected the edit to be sent")
+    }
+    if msg := cfg.Messages[alice][2]; msg.Message != "mine!" || !msg.Edited || len(cfg.Messages[alice]) != 3 {
+        t.Fatalf("expected my message to be edited in place, got %+v", cfg.Messages[alice])
+    }
+    if !strings.Contains(m.View(), "(edited)") {
+        t.Error("expected the edited marker to be shown")
+    }
+    m.pending = ""
+
+    // pressing a reaction again removes it
+    update(tea.KeyMsg{Type: tea.KeyCtrlO})
+    update(runes("1"))
+    if rs := cfg.Messages[alice][2].Reactions; cmd == nil || len(rs) != 1 || rs[0].Emoji != "👍" {
+        t.Fatalf("expected a reaction, got %+v", rs)
+    }
+    m.pending = ""
+    update(runes("1"))
+    if rs := cfg.Messages[alice][2].Reactions; len(rs) != 0 {
+        t.Fatalf("expected the reaction to be removed, got %+v", rs)
+    }
+    m.pending = ""
+
+    // deleting asks for confirmation
+    update(runes("d"))
+    if cfg.Messages[alice][2].Deleted || cmd != nil {
+        t.Fatal("expected the first press to ask for confirmation")
+    }
+    update(runes("d"))
+    if !cfg.Messages[alice][2].Deleted || cmd == nil || m.selecting {
+        t.Fatal("expected the message to be deleted")
+    }
+    if !strings.Contains(m.View(), "message deleted") {
+        t.Error("expected the deleted placeholder to be shown")
+    }
+}
@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"github.com/CraigYanitski/mescli/internal/utils"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -16,49 +17,108 @@ var (
     convMargin = margin{2, 1}
 )
 
-// styles
+// styles, set by the theme
 var (
     // login styles
-    inputStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("164"))
+    inputStyle  lipgloss.Style
 
     // option styles
-    optionStyle          = lipgloss.NewStyle()
-    selectedOptionStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("164"))
-    titleStyle           = lipgloss.NewStyle()
-    paginationStyle      = list.DefaultStyles().PaginationStyle
-    helpStyle            = list.DefaultStyles().HelpStyle
-    subtleStyle          = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
-    dotStyle             = lipgloss.NewStyle().Foreground(lipgloss.Color("236")).Render(" • ")
+    optionStyle          lipgloss.Style
+    selectedOptionStyle  lipgloss.Style
+    titleStyle           lipgloss.Style
+    paginationStyle      lipgloss.Style
+    helpStyle            lipgloss.Style
+    subtleStyle          lipgloss.Style
+    dotStyle             string
 
     // contact styles
-    contactStyleName          = lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
-    contactStyleDesc          = lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("245"))
-    selectedContactStyleName  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("164"))
-    selectedContactStyleDesc  = lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("164"))
+    contactStyleName          lipgloss.Style
+    contactStyleDesc          lipgloss.Style
+    selectedContactStyleName  lipgloss.Style
+    selectedContactStyleDesc  lipgloss.Style
+    badgeStyle                lipgloss.Style
+    noticeStyle               lipgloss.Style
+
+    // split layout styles
+    paneStyle         lipgloss.Style
+    focusedPaneStyle  lipgloss.Style
 
     // chat styles
-    conversationStyle  = lipgloss.NewStyle().Bold(true)
-    outputStyle       = lipgloss.NewStyle()
-    senderStyle       = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("164"))
-    receiveStyle       = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("166"))
-    promptStyle       = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("220"))
+    conversationStyle     lipgloss.Style
+    outputStyle           lipgloss.Style
+    senderStyle           lipgloss.Style
+    receiveStyle          lipgloss.Style
+    promptStyle           lipgloss.Style
+    quoteStyle            lipgloss.Style
+    selectedMessageStyle  lipgloss.Style
 )
 
 // additional output
 var (
     loginWrapping = "\n%s\n\n\n\n\n\n%s\n\n%s\n\n\n%s\n"
-    loginMsgWrapping = "enter to submit credentials\nctrl+n to create a new account\n\n%s"
-    createMsgWrapping = "enter to create a new account\n\n%s"
     updateWrapping = "\n%s\n\n\n\n\n\n%s\n\n%s\n\n%s\n\n%s\n\n\n%s\n"
-    updateMsgWrapping = "enter to submit credentials\nctrl+n to update your account\n\n%s"
-    conversationWrapping = "\n%s\n\n%s\n\n%s"
+    deleteWrapping = "\n%s\n\n\n\n%s\n\n%s\n\n\n%s\n"
+    deleteWarning = "This deletes your account, keys and messages from the server and this machine.\n" +
+        "Enter your password to confirm."
+    newWrapping = "\n%s\n\n\n\nStart a conversation with\n\n%s\n\n\n%s\n\n%s\n"
+    conversationWrapping = "\n%s\n\n%s\n\n%s\n%s"
+    // rendered with the theme
+    optionWrapping   string
+    contactWrapping  string
+    inboxWrapping    string
+    helpWrapping     string
+)
+
+// SetTheme styles the TUI and the CLI output with a theme.
+func SetTheme(t Theme) {
+    theme = t
+
+    inputStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Accent))
+
+    optionStyle = lipgloss.NewStyle()
+    selectedOptionStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(t.Accent))
+    titleStyle = lipgloss.NewStyle()
+    paginationStyle = list.DefaultStyles().PaginationStyle
+    helpStyle = list.DefaultStyles().HelpStyle
+    subtleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Subtle))
+    dotStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Separator)).Render(" • ")
+
+    contactStyleName = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Text))
+    contactStyleDesc = lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color(t.Muted))
+    selectedContactStyleName = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(t.Accent))
+    selectedContactStyleDesc = lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color(t.Accent))
+    badgeStyle = lipgloss.NewStyle().Bold(true).Padding(0, 1).
+        Foreground(lipgloss.Color(t.BadgeText)).Background(lipgloss.Color(t.Accent))
+    noticeStyle = lipgloss.NewStyle().Bold(true).Padding(0, 1).
+        Foreground(lipgloss.Color(t.NoticeText)).Background(lipgloss.Color(t.Notice))
+
+    paneStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).
+        BorderForeground(lipgloss.Color(t.Border))
+    focusedPaneStyle = paneStyle.BorderForeground(lipgloss.Color(t.Accent))
+
+    conversationStyle = lipgloss.NewStyle().Bold(true)
+    outputStyle = lipgloss.NewStyle()
+    senderStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(t.Accent))
+    receiveStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(t.Receive))
+    promptStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(t.Prompt))
+    quoteStyle = lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color(t.Muted))
+    selectedMessageStyle = lipgloss.NewStyle().Border(lipgloss.ThickBorder(), false, false, false, true).
+        BorderForeground(lipgloss.Color(t.Accent)).PaddingLeft(1)
+
     optionWrapping = optionStyle.Margin(optionMargin.height, optionMargin.width).
         Render("\nPlease choose an option\n%s\n")
     contactWrapping = contactStyleName.Margin(contactMargin.height, contactMargin.width).
         Render("\nConversations\n%s\n")
+    inboxWrapping = contactStyleName.Margin(contactMargin.height, contactMargin.width).
+        Render("\nMessage requests\n%s\n\n%s\n\n%s\n")
     helpWrapping = helpStyle.Margin(contactMargin.height, contactMargin.width).
         Render("\nKey Bindings\n%s\n")
-)
+
+    utils.StatusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Status))
+    utils.SuccessStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Success))
+    utils.ErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Error))
+}
+
 type (
     errMsg error
 )
@@ -0,0 +1,189 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"go.yaml.in/yaml/v3"
+	"golang.org/x/term"
+)
+
+// Theme sets the colours and prompts of the TUI and CLI, and the glamour
+// style messages are rendered with. Colours are ANSI numbers or hex codes.
+type Theme struct {
+    // built-in theme a theme file starts from, auto by default
+    Base           string  `yaml:"base,omitempty"`
+    // glamour style name, or path to a custom glamour JSON style
+    Glamour        string  `yaml:"glamour"`
+    // prompts
+    SenderPrompt   string  `yaml:"sender_prompt"`
+    ReceivePrompt  string  `yaml:"receive_prompt"`
+    InputPrompt    string  `yaml:"input_prompt"`
+    // colours
+    Accent         string  `yaml:"accent"`
+    Receive        string  `yaml:"receive"`
+    Prompt         string  `yaml:"prompt"`
+    Text           string  `yaml:"text"`
+    Muted          string  `yaml:"muted"`
+    Subtle         string  `yaml:"subtle"`
+    Separator      string  `yaml:"separator"`
+    Border         string  `yaml:"border"`
+    BadgeText      string  `yaml:"badge_text"`
+    Notice         string  `yaml:"notice"`
+    NoticeText     string  `yaml:"notice_text"`
+    Status         string  `yaml:"status"`
+    Success        string  `yaml:"success"`
+    Error          string  `yaml:"error"`
+}
+
+// built-in themes, chosen with the theme config key
+const (
+    themeAuto          = "auto"
+    themeDark          = "dark"
+    themeLight         = "light"
+    themeHighContrast  = "high-contrast"
+)
+
+var builtinThemes = map[string]Theme{
+    themeDark: {
+        Glamour: styles.TokyoNightStyle,
+        SenderPrompt: "You: ",
+        ReceivePrompt: "> ",
+        InputPrompt: "| ",
+        Accent: "164",
+        Receive: "166",
+        Prompt: "220",
+        Text: "255",
+        Muted: "245",
+        Subtle: "241",
+        Separator: "236",
+        Border: "238",
+        BadgeText: "255",
+        Notice: "220",
+        NoticeText: "0",
+        Status: "3",
+        Success: "2",
+        Error: "1",
+    },
+    themeLight: {
+        Glamour: styles.LightStyle,
+        SenderPrompt: "You: ",
+        ReceivePrompt: "> ",
+        InputPrompt: "| ",
+        Accent: "127",
+        Receive: "166",
+        Prompt: "130",
+        Text: "235",
+        Muted: "240",
+        Subtle: "244",
+        Separator: "250",
+        Border: "250",
+        BadgeText: "255",
+        Notice: "130",
+        NoticeText: "255",
+        Status: "130",
+        Success: "28",
+        Error: "160",
+    },
+    themeHighContrast: {
+        Glamour: styles.DarkStyle,
+        SenderPrompt: "You: ",
+        ReceivePrompt: "> ",
+        InputPrompt: "| ",
+        Accent: "14",
+        Receive: "11",
+        Prompt: "15",
+        Text: "15",
+        Muted: "15",
+        Subtle: "7",
+        Separator: "15",
+        Border: "15",
+        BadgeText: "0",
+        Notice: "11",
+        NoticeText: "0",
+        Status: "11",
+        Success: "10",
+        Error: "9",
+    },
+}
+
+// theme in use
+var theme Theme
+
+func init() {
+    SetTheme(builtinThemes[themeDark])
+}
+
+// LoadTheme returns a built-in theme by name, or reads a YAML theme file. A
+// theme file only needs the settings it changes from its base theme.
+func LoadTheme(name string) (Theme, error) {
+    if name == "" || name == themeAuto {
+        return autoTheme(), nil
+    }
+    if t, ok := builtinThemes[name]; ok {
+        return t, nil
+    }
+    data, err := os.ReadFile(name)
+    if err != nil {
+        return Theme{}, fmt.Errorf("unknown theme %q: %w", name, err)
+    }
+    base := Theme{}
+    if err := yaml.Unmarshal(data, &base); err != nil {
+        return Theme{}, fmt.Errorf("error reading theme %s: %w", name, err)
+    }
+    t := autoTheme()
+    if base.Base != "" && base.Base != themeAuto {
+        var ok bool
+        if t, ok = builtinThemes[base.Base]; !ok {
+            return Theme{}, fmt.Errorf("unknown base theme %q in %s", base.Base, name)
+        }
+    }
+    // the file overrides the base theme
+    if err := yaml.Unmarshal(data, &t); err != nil {
+        return Theme{}, fmt.Errorf("error reading theme %s: %w", name, err)
+    }
+    // custom glamour styles are found next to the theme file
+    if !isGlamourStyle(t.Glamour) {
+        if !filepath.IsAbs(t.Glamour) {
+            t.Glamour = filepath.Join(filepath.Dir(name), t.Glamour)
+        }
+        if _, err := os.Stat(t.Glamour); err != nil {
+            return Theme{}, fmt.Errorf("unknown glamour style in %s: %w", name, err)
+        }
+    }
+    return t, nil
+}
+
+// stdoutIsTerminal is whether the output is a terminal that can be asked
+// for its background
+var stdoutIsTerminal = func() bool {
+    return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// autoTheme suits the terminal background. Output that is not a terminal is
+// dark without asking, so piped commands are never held up by the query or
+// sent its escape sequences.
+func autoTheme() Theme {
+    if !stdoutIsTerminal() || lipgloss.HasDarkBackground() {
+        return builtinThemes[themeDark]
+    }
+    return builtinThemes[themeLight]
+}
+
+func isGlamourStyle(name string) bool {
+    _, ok := styles.DefaultStyles[name]
+    return ok || name == styles.AutoStyle
+}
+
+// glamourStyle is the style used to render messages, which is plain text
+// when NO_COLOR is set
+func glamourStyle() string {
+    if lipgloss.ColorProfile() == termenv.Ascii {
+        return styles.NoTTYStyle
+    }
+    return theme.Glamour
+}
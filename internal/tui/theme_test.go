@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestLoadTheme(t *testing.T) {
+    for name, want := range builtinThemes {
+        got, err := LoadTheme(name)
+        if err != nil || got != want {
+            t.Errorf("expected the built-in %s theme, got %+v (%v)", name, got, err)
+        }
+    }
+    if _, err := LoadTheme("missing"); err == nil {
+        t.Error("expected an error for an unknown theme")
+    }
+    // the background is only asked of a terminal
+    isTerminal := stdoutIsTerminal
+    t.Cleanup(func() { stdoutIsTerminal = isTerminal })
+    stdoutIsTerminal = func() bool { return false }
+    if got, err := LoadTheme(themeAuto); err != nil || got != builtinThemes[themeDark] {
+        t.Errorf("expected the dark theme when output is not a terminal, got %+v (%v)", got, err)
+    }
+
+    dir := t.TempDir()
+    write := func(name, content string) string {
+        path := filepath.Join(dir, name)
+        if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+            t.Fatal(err)
+        }
+        return path
+    }
+
+    // theme files override their base theme, with custom glamour styles
+    // found next to them
+    write("glamour.json", "{}")
+    custom, err := LoadTheme(write("custom.yaml", "base: light\naccent: \"#ff00ff\"\nglamour: glamour.json\n"))
+    if err != nil {
+        t.Fatalf("error loading theme: %s", err)
+    }
+    want := builtinThemes[themeLight]
+    want.Base = themeLight
+    want.Accent = "#ff00ff"
+    want.Glamour = filepath.Join(dir, "glamour.json")
+    if custom != want {
+        t.Errorf("expected %+v, got %+v", want, custom)
+    }
+
+    bad := map[string]string{
+        "base.yaml": "base: sepia\n",
+        "glamour.yaml": "glamour: missing.json\n",
+        "invalid.yaml": "accent: [\n",
+    }
+    for name, content := range bad {
+        if _, err := LoadTheme(write(name, content)); err == nil {
+            t.Errorf("expected an error loading %s", name)
+        }
+    }
+
+    // the theme styles the TUI and CLI
+    t.Cleanup(func() { SetTheme(builtinThemes[themeDark]) })
+    SetTheme(builtinThemes[themeHighContrast])
+    if utils.ErrorStyle.GetForeground() != lipgloss.Color("9") || theme.SenderPrompt != "You: " {
+        t.Error("expected the high-contrast theme to be used")
+    }
+
+    // messages are plain text without colours, such as with NO_COLOR
+    profile := lipgloss.ColorProfile()
+    t.Cleanup(func() { lipgloss.SetColorProfile(profile) })
+    lipgloss.SetColorProfile(termenv.Ascii)
+    if glamourStyle() != "notty" {
+        t.Errorf("expected messages rendered without colours, got %s", glamourStyle())
+    }
+}
@@ -1,15 +1,16 @@
 package tui
 
 import (
-	"fmt"
+	"time"
 	// "log"
 	// "os"
 	// "path"
 
+	"github.com/CraigYanitski/mescli/internal/requests"
 	"github.com/CraigYanitski/mescli/internal/utils"
 	"github.com/charmbracelet/bubbles/help"
-	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -17,6 +18,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
+	"github.com/spf13/viper"
 )
 
 type ApiConfig struct {
@@ -24,12 +26,34 @@ type ApiConfig struct {
     Email  string
     Uuid  uuid.UUID
     Messages  map[string][]utils.RawMessage
+    Contacts  map[string]requests.Contact
+}
+
+// settings are the options read from the config when the TUI starts, since
+// the commands run away from the update loop write to viper
+type settings struct {
+    typingIndicators  bool
+    presence          bool
+    maths             bool
+    editorSend        bool
+    notifications     string
+}
+
+func loadSettings() settings {
+    return settings{
+        typingIndicators:  viper.GetBool("typing_indicators"),
+        presence:          viper.GetBool("presence"),
+        maths:             viper.GetBool("maths"),
+        editorSend:        viper.GetBool("editor_send"),
+        notifications:     viper.GetString("notifications"),
+    }
 }
 
 // model parameters
 type Model struct {
     // config
-    cfg  *ApiConfig
+    cfg       *ApiConfig
+    settings  settings
     // geometry
     height  int
     width   int
@@ -48,12 +72,24 @@ type Model struct {
     updateInputs  []textinput.Model
     updateFocus   int
     updateMsg     string
+    // delete
+    deleteInput  textinput.Model
+    deleteMsg    string
     // options
     options  list.Model
     Chosen   int
     // contacts
     contacts      list.Model
     conversation  string
+    // split layout on wide terminals
+    split  bool
+    focus  int
+    // message requests
+    inbox     list.Model
+    inboxMsg  string
+    // new conversation
+    newInput  textinput.Model
+    newMsg    string
     // conversation
     viewport       viewport.Model
     messages       map[string][]string
@@ -64,6 +100,28 @@ type Model struct {
     receivePrompt  string
     receiveStyle   lipgloss.Style
     help           help.Model
+    sendMsg        string
+    // acting on a past message
+    selecting      bool
+    selected       int
+    replyTo        string
+    editing        string
+    confirmDelete  string
+    // unsent text of the conversations not open
+    drafts         map[string]string
+    // network work in progress
+    spinner  spinner.Model
+    pending  string
+    // notification banner
+    notice       notice
+    noticeCount  int
+    // typing and presence
+    lastInput    time.Time
+    typingTo     string
+    typingSent   time.Time
+    typingCount  int
+    typing       map[string]time.Time
+    presence     map[string]requests.PresenceResponse
     // help
     viewHelp  bool
     // misc
@@ -101,13 +159,13 @@ func InitialModel(cfg *ApiConfig) Model {
     loginInputs[loginEmail].CharLimit = 256
     loginInputs[loginEmail].Width = 50
     loginInputs[loginEmail].Prompt = ""
-    loginInputs[loginEmail].Validate = emailValidator
+    loginInputs[loginEmail].Validate = utils.ValidateEmail
     loginInputs[loginPassword] = textinput.New()
     loginInputs[loginPassword].Placeholder = "password"
     loginInputs[loginPassword].CharLimit = 256
     loginInputs[loginPassword].Width = 50
     loginInputs[loginPassword].Prompt = ""
-    loginInputs[loginPassword].Validate = passwordValidator
+    loginInputs[loginPassword].Validate = utils.ValidatePassword
 
     //update textinput
     updateInputs := make([]textinput.Model, 4)
@@ -122,26 +180,53 @@ func InitialModel(cfg *ApiConfig) Model {
     updateInputs[updateEmail].CharLimit = 256
     updateInputs[updateEmail].Width = 50
     updateInputs[updateEmail].Prompt = ""
-    updateInputs[updateEmail].Validate = emailValidator
+    updateInputs[updateEmail].Validate = utils.ValidateEmail
     updateInputs[updatePassword] = textinput.New()
     updateInputs[updatePassword].Placeholder = "password"
     updateInputs[updatePassword].CharLimit = 256
     updateInputs[updatePassword].Width = 50
     updateInputs[updatePassword].Prompt = ""
-    updateInputs[updatePassword].Validate = passwordValidator
+    updateInputs[updatePassword].Validate = utils.ValidatePassword
     updateInputs[updateRetypePassword] = textinput.New()
     updateInputs[updateRetypePassword].Placeholder = "retype password"
     updateInputs[updateRetypePassword].CharLimit = 256
     updateInputs[updateRetypePassword].Width = 50
     updateInputs[updateRetypePassword].Prompt = ""
 
+    // delete textinput
+    deleteInput := textinput.New()
+    deleteInput.Placeholder = "password"
+    deleteInput.CharLimit = 256
+    deleteInput.Width = 50
+    deleteInput.Prompt = ""
+
+    // new conversation textinput
+    newInput := textinput.New()
+    newInput.Placeholder = "email or UUID"
+    newInput.CharLimit = 256
+    newInput.Width = 50
+    newInput.Prompt = ""
+
+    // spinner shown during network calls
+    sp := spinner.New()
+    sp.Spinner = spinner.Dot
+    sp.Style = promptStyle
+
     // option list
     options := []list.Item{
         option{str: "View conversations", o: 1},
+        option{str: "New conversation", o: 6},
+        option{str: "Message requests", o: 5},
         option{str: "Update account", o: 2},
+        option{str: "Delete account", o: 4},
         option{str: "Run custom tests", o: 3},
     }
+    // key bindings, with those remapped in the config
+    kb := newKeyBindings()
+    kb.remap(keymap)
+
     o := list.New(options, optionDelegate{}, 20, 10)
+    o.KeyMap = kb.keys.listModelKeys()
     o.SetShowTitle(false)
     o.SetShowStatusBar(false)
     o.SetFilteringEnabled(false)
@@ -150,18 +235,11 @@ func InitialModel(cfg *ApiConfig) Model {
     o.SetShowHelp(true)
 
     // contact messages
-    //fmt.Println("TUI:", cfg.Messages)
     messages := make(map[string][]string)
-    messages["Test contact 1"] = []string{}
-    messages["Test contact 2"] = []string{}
-    messages["Test contact 3"] = []string{}
 
     // contact list
-    contacts := []list.Item{}
-    for key, _ := range messages {
-        contacts = append(contacts, contact{name: key, desc: "encrypted"})
-    }
-    c := list.New(contacts, contactDelegate{}, 20, 10)
+    c := list.New(contactItems(cfg), contactDelegate{}, 20, 10)
+    c.KeyMap = kb.keys.listModelKeys()
     c.SetShowTitle(false)
     c.SetShowStatusBar(false)
     c.SetFilteringEnabled(false)
@@ -170,107 +248,25 @@ func InitialModel(cfg *ApiConfig) Model {
     c.Styles.HelpStyle = helpStyle
     c.SetShowHelp(true)
 
+    // message request list
+    inbox := list.New([]list.Item{}, contactDelegate{}, 20, 10)
+    inbox.KeyMap = kb.keys.listModelKeys()
+    inbox.SetShowTitle(false)
+    inbox.SetShowStatusBar(false)
+    inbox.SetFilteringEnabled(false)
+    inbox.Styles.PaginationStyle = paginationStyle
+    inbox.Styles.HelpStyle = helpStyle
+    inbox.SetShowHelp(false)
+
     // conversation textarea
     ta := textarea.New()
-    // DefaultKeyMap is the default set of key bindings for navigating and acting
-    // upon the textarea.
-    var textareaKeyMap = textarea.KeyMap{
-        CharacterForward: key.NewBinding(
-            key.WithKeys("right", "ctrl+f"), 
-            key.WithHelp("right", "character forward"),
-        ),
-        CharacterBackward: key.NewBinding(
-            key.WithKeys("left", "ctrl+b"), 
-            key.WithHelp("left", "character backward"),
-        ),
-        WordForward: key.NewBinding(
-            key.WithKeys("ctrl+right", "alt+f"), 
-            key.WithHelp("ctrl+right", "word forward"),
-        ),
-        WordBackward: key.NewBinding(
-            key.WithKeys("ctrl+left", "alt+b"), 
-            key.WithHelp("ctrl+left", "word backward"),
-        ),
-        LineNext: key.NewBinding(
-            key.WithKeys("down"), 
-            key.WithHelp("down", "next line"),
-        ),
-        LinePrevious: key.NewBinding(
-            key.WithKeys("up"), 
-            key.WithHelp("up", "previous line"),
-        ),
-        DeleteWordBackward: key.NewBinding(
-            key.WithKeys("alt+backspace", "ctrl+w"), 
-            key.WithHelp("alt+backspace", "delete word backward"),
-        ),
-        DeleteWordForward: key.NewBinding(
-            key.WithKeys("alt+delete", "alt+d"), 
-            key.WithHelp("alt+delete", "delete word forward"),
-        ),
-        DeleteAfterCursor: key.NewBinding(
-            key.WithKeys("ctrl+k"), 
-            key.WithHelp("ctrl+k", "delete after cursor"),
-        ),
-        DeleteBeforeCursor: key.NewBinding(
-            key.WithKeys("ctrl+u"), 
-            key.WithHelp("ctrl+u", "delete before cursor"),
-        ),
-        InsertNewline: key.NewBinding(
-            key.WithKeys("ctrl+s"), 
-            key.WithHelp("ctrl+s", "insert newline"),
-        ),
-        DeleteCharacterBackward: key.NewBinding(
-            key.WithKeys("backspace", "ctrl+h"), 
-            key.WithHelp("backspace", "delete character backward"),
-        ),
-        DeleteCharacterForward: key.NewBinding(
-            key.WithKeys("delete", "ctrl+d"), 
-            key.WithHelp("delete", "delete character forward"),
-        ),
-        LineStart: key.NewBinding(
-            key.WithKeys("home", "ctrl+a"), 
-            key.WithHelp("home", "line start"),
-        ),
-        LineEnd: key.NewBinding(
-            key.WithKeys("end", "ctrl+e"), 
-            key.WithHelp("end", "line end"),
-        ),
-        Paste: key.NewBinding(
-            key.WithKeys("ctrl+v"), 
-            key.WithHelp("ctrl+v", "paste"),
-        ),
-        InputBegin: key.NewBinding(
-            key.WithKeys("alt+<", "ctrl+home"), 
-            key.WithHelp("alt+<", "input begin"),
-        ),
-        InputEnd: key.NewBinding(
-            key.WithKeys("alt+>", "ctrl+end"), 
-            key.WithHelp("alt+>", "input end"),
-        ),
-
-        CapitalizeWordForward: key.NewBinding(
-            key.WithKeys("alt+c"), 
-            key.WithHelp("alt+c", "capitalize word forward"),
-        ),
-        LowercaseWordForward: key.NewBinding(
-            key.WithKeys("alt+l"), 
-            key.WithHelp("alt+l", "lowercase word forward"),
-        ),
-        UppercaseWordForward: key.NewBinding(
-            key.WithKeys("alt+u"), 
-            key.WithHelp("alt+u", "uppercase word forward"),
-        ),
-
-        TransposeCharacterBackward: key.NewBinding(
-            key.WithKeys("ctrl+t"), 
-            key.WithHelp("ctrl+t", "transpose character backward"),
-        ),
-    }
-    ta.KeyMap = textareaKeyMap
+    ta.KeyMap = kb.textarea
     ta.Placeholder = "Enter message to send"
     ta.Focus()
-    ta.Prompt = "| "
-    ta.CharLimit = 1024
+    ta.Prompt = theme.InputPrompt
+    // long drafts are sent as several messages
+    ta.CharLimit = 0
+    ta.MaxHeight = 0
     ta.FocusedStyle.Prompt = promptStyle
     ta.SetWidth(100)
     ta.SetHeight(3)
@@ -279,66 +275,38 @@ func InitialModel(cfg *ApiConfig) Model {
     ta.KeyMap.InsertNewline.SetEnabled(true)
     // conversation viewport
     vp := viewport.New(100, 5)
-    viewportKeyMap := viewport.KeyMap{
-		PageDown: key.NewBinding(
-			key.WithKeys("pgdown"),
-			key.WithHelp("pgdn", "conversation page down"),
-		),
-		PageUp: key.NewBinding(
-			key.WithKeys("pgup"),
-			key.WithHelp("pgup", "conversation page up"),
-		),
-		HalfPageUp: key.NewBinding(
-			key.WithKeys("ctrl+u"),
-			key.WithHelp("ctrl+u", "conversation ½ page up"),
-		),
-		HalfPageDown: key.NewBinding(
-			key.WithKeys("ctrl+d"),
-			key.WithHelp("ctrl+d", "conversation ½ page down"),
-		),
-		Up: key.NewBinding(
-			key.WithKeys("ctrl+up"),
-			key.WithHelp("ctrl+↑", "conversation up"),
-		),
-		Down: key.NewBinding(
-			key.WithKeys("ctrl+down"),
-			key.WithHelp("ctrl+↓", "conversation down"),
-		),
-		// Left: key.NewBinding(
-		// 	key.WithKeys("left", "h"),
-		// 	key.WithHelp("←/h", "move left"),
-		// ),
-		// Right: key.NewBinding(
-		// 	key.WithKeys("right", "l"),
-		// 	key.WithHelp("→/l", "move right"),
-		// ),
-	}
-    vp.KeyMap = viewportKeyMap
+    vp.KeyMap = kb.viewport
     welcomeMsg := lipgloss.NewStyle().Bold(true).Render(
         "Welcome to the chat room!\nType a message and press Enter to send.",
     )
     vp.SetContent(welcomeMsg)
     vp.Style.Margin(convMargin.height, convMargin.width)
-    senderPrompt := "You: "
-    receivePrompt := "> "
+    senderPrompt := theme.SenderPrompt
+    receivePrompt := theme.ReceivePrompt
 
     return Model {
         // config
         cfg: cfg,
+        settings: loadSettings(),
         // Model
-        loggedIn:       false,
+        loggedIn:       viper.GetString("access_token") != "" || viper.GetString("refresh_token") != "",
         loginInputs:    loginInputs,
         loginFocus:     0,
-        loginMsg:       fmt.Sprintf(loginMsgWrapping, ""),
+        loginMsg:       "",
         created:        true,
-        createMsg:      fmt.Sprintf(createMsgWrapping, ""),
+        createMsg:      "",
         updated:        true,
         updateInputs:   updateInputs,
         updateFocus:    0,
-        updateMsg:      updateMsgWrapping,
-        keys:           newListKeyMap(),
+        updateMsg:      "",
+        deleteInput:    deleteInput,
+        deleteMsg:      "",
+        keys:           kb.keys,
         options:        o,
         contacts:       c,
+        inbox:          inbox,
+        newInput:       newInput,
+        spinner:        sp,
         textarea:       ta,
         messages:       messages,
         viewport:       vp,
@@ -348,12 +316,16 @@ func InitialModel(cfg *ApiConfig) Model {
         receiveStyle:   receiveStyle,
         receivePrompt:  receivePrompt,
         help:           help.New(),
+        lastInput:      time.Now(),
+        typing:         make(map[string]time.Time),
+        presence:       make(map[string]requests.PresenceResponse),
+        drafts:         make(map[string]string),
         err:            nil,
     }
 }
 
 func (m Model) Init() tea.Cmd {
-    return textarea.Blink
+    return tea.Batch(textarea.Blink, pollMessages(), withdrawPresence(m))
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -361,6 +333,39 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
     if m.Quitting {
         return m, tea.Quit
     }
+    // each conversation keeps its own draft
+    conversation, draft := m.conversation, m.textarea.Value()
+    if m.editing != "" {
+        draft = ""
+    }
+    next, cmd := update(msg, m)
+    if next, ok := next.(Model); ok && next.conversation != conversation {
+        return switchDraft(next, conversation, draft), cmd
+    }
+    return next, cmd
+}
+
+func update(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
+    // network results and new messages arrive on any screen
+    if m, cmd, ok := updateSpinner(msg, m); ok {
+        return m, cmd
+    }
+    if m, cmd, ok := updateResults(msg, m); ok {
+        return m, cmd
+    }
+    if m, cmd, ok := updateMessages(msg, m); ok {
+        return m, cmd
+    }
+    if m, cmd, ok := updateNotice(msg, m); ok {
+        return m, cmd
+    }
+    m, cmd, ok := updateActivity(msg, m)
+    if ok {
+        return m, cmd
+    }
+    if m, cmd, ok := updateEditor(msg, m); ok {
+        return m, cmd
+    }
     // Use the appropriate update function
     if !m.created {
         return updateCreate(msg, m)
@@ -370,6 +375,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
         return updateUpdate(msg, m)
     } else if m.viewHelp {
         return updateHelp(msg, m)
+    } else if inSplit(m) {
+        return updateSplit(msg, m)
     } else if m.conversation != "" {
         return updateConversation(msg, m)
     } else if m.Chosen == 0 {
@@ -378,6 +385,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
         return updateContacts(msg, m)
     } else if m.Chosen == 2 {
         return updateUpdate(msg, m)
+    } else if m.Chosen == 4 {
+        return updateDelete(msg, m)
+    } else if m.Chosen == 5 {
+        return updateInbox(msg, m)
+    } else if m.Chosen == 6 {
+        return updateNew(msg, m)
     } else {
         // m.View()
         return m, tea.Quit
@@ -397,6 +410,8 @@ func (m Model) View() string {
         s = updateView(m)
     } else if m.viewHelp {
         s = helpView(m)
+    } else if inSplit(m) {
+        s = splitView(m)
     } else if m.conversation != "" {
         s = conversationView(m)
     } else if m.Chosen == 0 {
@@ -405,9 +420,18 @@ func (m Model) View() string {
         s = contactsView(m)
     } else if m.Chosen == 2 {
         s = updateView(m)
+    } else if m.Chosen == 4 {
+        s = deleteView(m)
+    } else if m.Chosen == 5 {
+        s = inboxView(m)
+    } else if m.Chosen == 6 {
+        s = newView(m)
     } else {
         s = ""
     }
+    if m.loggedIn && m.notice.text != "" {
+        s = noticeView(m) + "\n" + s
+    }
     return s
 }
 
@@ -416,11 +440,29 @@ func (m Model) resize(width, height int) (Model) {
     m.width = width
     m.options.SetSize(width - 2*optionMargin.width, 
         height - lipgloss.Height(optionWrapping) - 2*optionMargin.height)
-    m.contacts.SetSize(width -2*contactMargin.width, 
-        height - lipgloss.Height(contactWrapping) - 2*contactMargin.height)
-    m.viewport.Width = width
-    m.textarea.SetWidth(width)
-    m.viewport.Height = height - m.textarea.Height() - lipgloss.Height(conversationWrapping)
+    m.inbox.SetSize(width -2*contactMargin.width, 
+        height - lipgloss.Height(inboxWrapping) - 2*contactMargin.height)
+    // fall back to one screen at a time on narrow terminals
+    m.split = width >= splitWidth
+    if !m.split {
+        m.contacts.SetSize(width -2*contactMargin.width, 
+            height - lipgloss.Height(contactWrapping) - 2*contactMargin.height)
+        m.viewport.Width = width
+        m.textarea.SetWidth(width)
+        m.viewport.Height = height - m.textarea.Height() - lipgloss.Height(conversationWrapping)
+        m.textarea.Focus()
+        return m
+    }
+    // each pane is drawn inside a border
+    side := sidebarWidth(width)
+    m.contacts.SetSize(side - 2*contactMargin.width, 
+        height - 2 - lipgloss.Height(contactWrapping) - 2*contactMargin.height)
+    m.viewport.Width = width - side - 4
+    m.textarea.SetWidth(width - side - 4)
+    m.viewport.Height = height - 2 - m.textarea.Height() - lipgloss.Height(conversationWrapping)
+    if m.conversation == "" {
+        m = focusPane(m, paneContacts)
+    }
     return m
 }
 
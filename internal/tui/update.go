@@ -5,8 +5,8 @@ import (
 	"strings"
 
 	"github.com/CraigYanitski/mescli/assets"
-	"github.com/CraigYanitski/mescli/internal/requests"
 	"github.com/CraigYanitski/mescli/internal/utils"
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -22,42 +22,36 @@ func updateUpdate(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
     cmds := make([]tea.Cmd, len(m.updateInputs))
     switch msg := msg.(type) {
     case tea.KeyMsg:
-        switch msg.Type {
-        case tea.KeyCtrlC:
+        switch {
+        case key.Matches(msg, m.keys.ForceQuit):
             m.Quitting = true
             return m, tea.Quit 
-        case tea.KeyEscape:
+        case key.Matches(msg, m.keys.Cancel):
             m.updated = true
             return m, nil
-        case tea.KeyTab, tea.KeyDown:
+        case key.Matches(msg, m.keys.NextField):
             m.updateFocus = (m.updateFocus + 1) % len(m.updateInputs)
-        case tea.KeyShiftTab, tea.KeyUp:
+        case key.Matches(msg, m.keys.PrevField):
             m.updateFocus = ((m.updateFocus - 1) % len(m.updateInputs) + len(m.updateInputs)) % len(m.updateInputs)
-        case tea.KeyEnter:
+        case key.Matches(msg, m.keys.Submit):
+            if m.pending != "" {
+                return m, nil
+            }
             if err := m.updateInputs[updateEmail].Err; err != nil {
-                m.updateMsg = fmt.Sprintf(updateMsgWrapping, utils.ErrorStyle.Render(err.Error()))
+                m.updateMsg = utils.ErrorStyle.Render(err.Error())
                 return m, nil
             } else if err = m.updateInputs[updatePassword].Err; err != nil {
-                m.updateMsg = fmt.Sprintf(updateMsgWrapping, utils.ErrorStyle.Render(err.Error()))
+                m.updateMsg = utils.ErrorStyle.Render(err.Error())
                 return m, nil
             } else if m.updateInputs[updateRetypePassword].Value() != m.updateInputs[updatePassword].Value() {
-                m.updateMsg = fmt.Sprintf(updateMsgWrapping, utils.ErrorStyle.Render("Passwords do not match"))
+                m.updateMsg = utils.ErrorStyle.Render("Passwords do not match")
                 return m, nil
             }
-            err := requests.UpdateAccount(
+            return startPending(m, "Updating account...", updateAccount(
                 m.updateInputs[updateName].Value(),
                 m.updateInputs[updateEmail].Value(),
                 m.updateInputs[updatePassword].Value(),
-            )
-            if err != nil {
-                m.updateMsg = fmt.Sprintf(updateMsgWrapping, utils.ErrorStyle.Render("Update failed"))
-                return m, nil
-            }
-            m.updated = true
-            m.updateFocus = 0
-            for i, _ := range m.updateInputs {
-                m.updateInputs[i].SetValue("")
-            }
+            ))
         }
         for i := range m.updateInputs {
             m.updateInputs[i].Blur()
@@ -70,6 +64,24 @@ func updateUpdate(msg tea.Msg, m Model) (tea.Model, tea.Cmd) {
     return m, tea.Batch(cmds...)
 }
 
+func updateResult(msg updateResultMsg, m Model) Model {
+    m.pending = ""
+    if msg.err != nil {
+        if expired, ok := sessionExpired(m, msg.err); ok {
+            return expired
+        }
+        m.updateMsg = utils.ErrorStyle.Render("Update failed: "+msg.err.Error())
+        return m
+    }
+    m.updated = true
+    m.updateFocus = 0
+    m.updateMsg = ""
+    for i, _ := range m.updateInputs {
+        m.updateInputs[i].SetValue("")
+    }
+    return m
+}
+
 func updateView(m Model) string{
     // obscure password
     pw := m.updateInputs[updatePassword].Value()
@@ -86,7 +98,10 @@ func updateView(m Model) string{
         m.updateInputs[updateEmail].View(), 
         m.updateInputs[updatePassword].View(),
         m.updateInputs[updateRetypePassword].View(),
-        m.updateMsg,
+        formStatus(m, m.updateMsg,
+            hint(m.keys.Submit, "update your account"),
+            hint(m.keys.Cancel, "cancel"),
+        ),
     )
     // restore password
     m.updateInputs[updatePassword].SetValue(pw)
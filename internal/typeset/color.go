@@ -0,0 +1,268 @@
+package typeset
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Profile is the set of colours a terminal can show
+type Profile int
+
+const (
+    Ascii      Profile = iota  // no colours
+    ANSI                       // the 16 basic colours
+    ANSI256                    // the 256-colour palette
+    TrueColor                  // 24-bit colours
+)
+
+var profile = TrueColor
+
+// SetProfile sets the colours used by FormatString and Style.Render, which
+// are downsampled to the nearest the terminal can show.
+func SetProfile(p Profile) {
+    profile = p
+}
+
+// EnvProfile detects the colours a terminal supports from its environment
+// variables, looked up with a function such as os.Getenv.
+func EnvProfile(getenv func(string) string) Profile {
+    if getenv("NO_COLOR") != "" {
+        return Ascii
+    }
+    switch strings.ToLower(getenv("COLORTERM")) {
+    case "truecolor", "24bit":
+        return TrueColor
+    }
+    termName := strings.ToLower(getenv("TERM"))
+    switch {
+    case termName == "" || termName == "dumb":
+        return Ascii
+    case strings.Contains(termName, "truecolor") || strings.Contains(termName, "24bit") ||
+        strings.Contains(termName, "direct") || strings.Contains(termName, "kitty") ||
+        strings.Contains(termName, "ghostty") || strings.Contains(termName, "wezterm"):
+        return TrueColor
+    case strings.Contains(termName, "256color"):
+        return ANSI256
+    }
+    return ANSI
+}
+
+// DetectProfile detects the colours supported by the terminal f writes to.
+// Output that is not a terminal has no colours unless CLICOLOR_FORCE is set.
+func DetectProfile(f *os.File) Profile {
+    force := os.Getenv("CLICOLOR_FORCE")
+    forced := force != "" && force != "0"
+    if !forced && !term.IsTerminal(int(f.Fd())) {
+        return Ascii
+    }
+    p := EnvProfile(os.Getenv)
+    if p == Ascii && forced && os.Getenv("NO_COLOR") == "" {
+        return ANSI
+    }
+    return p
+}
+
+type colorKind int
+
+const (
+    noColor  colorKind = iota
+    defaultColor
+    basicColor
+    indexedColor
+    rgbColor
+)
+
+// Color is a terminal colour: one of the 16 basic colours, an index in the
+// 256-colour palette, or a 24-bit colour
+type Color struct {
+    kind     colorKind
+    index    uint8
+    r, g, b  uint8
+}
+
+// DefaultColor is the terminal's own foreground or background colour.
+var DefaultColor = Color{kind: defaultColor}
+
+// BasicColor is one of the 16 basic colours, with the bright colours from 8.
+func BasicColor(n uint8) Color {
+    return Color{kind: basicColor, index: n % 16}
+}
+
+// IndexedColor is a colour in the 256-colour palette.
+func IndexedColor(n uint8) Color {
+    return Color{kind: indexedColor, index: n}
+}
+
+// RGB is a 24-bit colour.
+func RGB(r, g, b uint8) Color {
+    return Color{kind: rgbColor, r: r, g: g, b: b}
+}
+
+// The xterm values of the basic colours, used to find the nearest
+var basicPalette = [16][3]uint8{
+    {0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+    {0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+    {127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+    {92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+var basicNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// Named colours beyond the basic ones, from CSS
+var namedColors = map[string]Color{
+    "orange":     RGB(0xff, 0xa5, 0x00),
+    "purple":     RGB(0x80, 0x00, 0x80),
+    "pink":       RGB(0xff, 0xc0, 0xcb),
+    "brown":      RGB(0xa5, 0x2a, 0x2a),
+    "navy":       RGB(0x00, 0x00, 0x80),
+    "teal":       RGB(0x00, 0x80, 0x80),
+    "olive":      RGB(0x80, 0x80, 0x00),
+    "maroon":     RGB(0x80, 0x00, 0x00),
+    "lime":       RGB(0x00, 0xff, 0x00),
+    "aqua":       RGB(0x00, 0xff, 0xff),
+    "fuchsia":    RGB(0xff, 0x00, 0xff),
+    "silver":     RGB(0xc0, 0xc0, 0xc0),
+    "gold":       RGB(0xff, 0xd7, 0x00),
+    "indigo":     RGB(0x4b, 0x00, 0x82),
+    "violet":     RGB(0xee, 0x82, 0xee),
+    "coral":      RGB(0xff, 0x7f, 0x50),
+    "salmon":     RGB(0xfa, 0x80, 0x72),
+    "tomato":     RGB(0xff, 0x63, 0x47),
+    "crimson":    RGB(0xdc, 0x14, 0x3c),
+    "turquoise":  RGB(0x40, 0xe0, 0xd0),
+    "skyblue":    RGB(0x87, 0xce, 0xeb),
+    "khaki":      RGB(0xf0, 0xe6, 0x8c),
+    "lavender":   RGB(0xe6, 0xe6, 0xfa),
+    "beige":      RGB(0xf5, 0xf5, 0xdc),
+    "chocolate":  RGB(0xd2, 0x69, 0x1e),
+    "orchid":     RGB(0xda, 0x70, 0xd6),
+    "plum":       RGB(0xdd, 0xa0, 0xdd),
+    "tan":        RGB(0xd2, 0xb4, 0x8c),
+}
+
+// ParseColor reads a colour as a hex code ('#f80' or '#ff8800'),
+// 'rgb(255,136,0)', a 256-colour index, or a name: the basic colours, their
+// 'bright' variants, 'grey', 'default' and the common CSS colours.
+func ParseColor(s string) (Color, error) {
+    name := strings.ToLower(strings.Replace(s, " ", "", -1))
+    switch {
+    case strings.HasPrefix(name, "#"):
+        return parseHex(name[1:])
+    case strings.HasPrefix(name, "rgb(") && strings.HasSuffix(name, ")"):
+        parts := strings.Split(name[4:len(name)-1], ",")
+        if len(parts) != 3 {
+            return Color{}, fmt.Errorf("colour %q needs three components", s)
+        }
+        var rgb [3]uint8
+        for i, part := range parts {
+            v, err := strconv.ParseUint(part, 10, 8)
+            if err != nil {
+                return Color{}, fmt.Errorf("colour %q has a component out of 0-255", s)
+            }
+            rgb[i] = uint8(v)
+        }
+        return RGB(rgb[0], rgb[1], rgb[2]), nil
+    case name == "default":
+        return DefaultColor, nil
+    case name == "grey", name == "gray":
+        return BasicColor(8), nil
+    }
+    if n, err := strconv.ParseUint(name, 10, 8); err == nil {
+        return IndexedColor(uint8(n)), nil
+    }
+    bright := strings.HasPrefix(name, "bright")
+    for i, basic := range basicNames {
+        if strings.TrimPrefix(name, "bright") == basic {
+            if bright {
+                return BasicColor(uint8(i + 8)), nil
+            }
+            return BasicColor(uint8(i)), nil
+        }
+    }
+    if c, ok := namedColors[name]; ok {
+        return c, nil
+    }
+    return Color{}, fmt.Errorf("unknown colour %q", s)
+}
+
+func parseHex(hex string) (Color, error) {
+    if len(hex) == 3 {
+        hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+    }
+    if len(hex) != 6 {
+        return Color{}, fmt.Errorf("hex colour #%s should have 3 or 6 digits", hex)
+    }
+    v, err := strconv.ParseUint(hex, 16, 32)
+    if err != nil {
+        return Color{}, fmt.Errorf("invalid hex colour #%s", hex)
+    }
+    return RGB(uint8(v>>16), uint8(v>>8), uint8(v)), nil
+}
+
+// rgb is the value of a colour in the palette
+func (c Color) rgb() (uint8, uint8, uint8) {
+    switch {
+    case c.kind == rgbColor:
+        return c.r, c.g, c.b
+    case c.index < 16:
+        p := basicPalette[c.index]
+        return p[0], p[1], p[2]
+    case c.index < 232:
+        levels := []uint8{0, 95, 135, 175, 215, 255}
+        n := c.index - 16
+        return levels[n/36], levels[n/6%6], levels[n%6]
+    }
+    grey := 8 + 10*(c.index-232)
+    return grey, grey, grey
+}
+
+func distance(r1, g1, b1, r2, g2, b2 uint8) int {
+    dr, dg, db := int(r1)-int(r2), int(g1)-int(g2), int(b1)-int(b2)
+    return dr*dr + dg*dg + db*db
+}
+
+// nearest finds the closest colour of those in the palette from start to end
+func (c Color) nearest(start, end int) uint8 {
+    r, g, b := c.rgb()
+    best, bestDistance := start, -1
+    for i := start; i < end; i++ {
+        pr, pg, pb := IndexedColor(uint8(i)).rgb()
+        if d := distance(r, g, b, pr, pg, pb); bestDistance < 0 || d < bestDistance {
+            best, bestDistance = i, d
+        }
+    }
+    return uint8(best)
+}
+
+// params are the SGR parameters setting the colour, downsampled to the
+// profile
+func (c Color) params(back bool, p Profile) []string {
+    offset := 0
+    if back {
+        offset = 10
+    }
+    if c.kind == noColor || c.kind == defaultColor {
+        return []string{strconv.Itoa(39 + offset)}
+    }
+    switch {
+    case p == Ascii:
+        return nil
+    case c.kind == basicColor || c.kind == indexedColor && c.index < 16 && p == ANSI:
+        if c.index < 8 {
+            return []string{strconv.Itoa(30 + offset + int(c.index))}
+        }
+        return []string{strconv.Itoa(90 + offset + int(c.index) - 8)}
+    case p == ANSI:
+        return BasicColor(c.nearest(0, 16)).params(back, p)
+    case c.kind == indexedColor:
+        return []string{strconv.Itoa(38 + offset), "5", strconv.Itoa(int(c.index))}
+    case p == ANSI256:
+        // the first 16 colours vary between terminals, so are left out
+        return []string{strconv.Itoa(38 + offset), "5", strconv.Itoa(int(c.nearest(16, 256)))}
+    }
+    return []string{strconv.Itoa(38 + offset), "2", strconv.Itoa(int(c.r)), strconv.Itoa(int(c.g)), strconv.Itoa(int(c.b))}
+}
@@ -0,0 +1,95 @@
+package typeset_test
+
+import (
+	"testing"
+
+	"github.com/CraigYanitski/mescli/internal/typeset"
+)
+
+func TestParseColor(t *testing.T) {
+    tests := []struct {
+        input     string
+        expected  typeset.Color
+        err       bool
+    }{
+        {"#ff8800", typeset.RGB(0xff, 0x88, 0x00), false},
+        {"#F80", typeset.RGB(0xff, 0x88, 0x00), false},
+        {"rgb(12, 34, 56)", typeset.RGB(12, 34, 56), false},
+        {"208", typeset.IndexedColor(208), false},
+        {"red", typeset.BasicColor(1), false},
+        {"Bright Blue", typeset.BasicColor(12), false},
+        {"grey", typeset.BasicColor(8), false},
+        {"orange", typeset.RGB(0xff, 0xa5, 0x00), false},
+        {"default", typeset.DefaultColor, false},
+        {"#ff88", typeset.Color{}, true},
+        {"#gggggg", typeset.Color{}, true},
+        {"rgb(256, 0, 0)", typeset.Color{}, true},
+        {"rgb(1, 2)", typeset.Color{}, true},
+        {"256", typeset.Color{}, true},
+        {"blurple", typeset.Color{}, true},
+    }
+    for _, test := range tests {
+        c, err := typeset.ParseColor(test.input)
+        if (err != nil) != test.err {
+            t.Errorf("ParseColor(%q): unexpected error %v", test.input, err)
+            continue
+        }
+        if c != test.expected {
+            t.Errorf("ParseColor(%q) = %+v, expected %+v", test.input, c, test.expected)
+        }
+    }
+}
+
+func TestColorProfiles(t *testing.T) {
+    t.Cleanup(func() { typeset.SetProfile(typeset.TrueColor) })
+    tests := []struct {
+        profile   typeset.Profile
+        format    []string
+        expected  string
+    }{
+        {typeset.TrueColor, []string{"#ff8800"}, "\033[38;2;255;136;0mx\033[0m"},
+        {typeset.TrueColor, []string{"back rgb(1,2,3)"}, "\033[48;2;1;2;3mx\033[0m"},
+        {typeset.TrueColor, []string{"fore 208", "back 17"}, "\033[38;5;208;48;5;17mx\033[0m"},
+        {typeset.TrueColor, []string{"bright red"}, "\033[91mx\033[0m"},
+        {typeset.TrueColor, []string{"back bright white"}, "\033[107mx\033[0m"},
+        {typeset.ANSI256, []string{"#ff8800"}, "\033[38;5;208mx\033[0m"},
+        {typeset.ANSI256, []string{"#808080"}, "\033[38;5;244mx\033[0m"},
+        {typeset.ANSI, []string{"#ff0000"}, "\033[91mx\033[0m"},
+        {typeset.ANSI, []string{"fore 21"}, "\033[34mx\033[0m"},
+        {typeset.ANSI, []string{"fore 3"}, "\033[33mx\033[0m"},
+        {typeset.Ascii, []string{"bold", "#ff0000"}, "\033[1mx\033[0m"},
+        {typeset.Ascii, []string{"red"}, "x"},
+    }
+    for _, test := range tests {
+        typeset.SetProfile(test.profile)
+        formatted, err := typeset.FormatString("x", test.format)
+        if err != nil {
+            t.Errorf("format %q: %v", test.format, err)
+            continue
+        }
+        if formatted != test.expected {
+            t.Errorf("format %q with profile %d: expected %q, got %q", test.format, test.profile, test.expected, formatted)
+        }
+    }
+}
+
+func TestEnvProfile(t *testing.T) {
+    tests := []struct {
+        env       map[string]string
+        expected  typeset.Profile
+    }{
+        {map[string]string{}, typeset.Ascii},
+        {map[string]string{"TERM": "dumb"}, typeset.Ascii},
+        {map[string]string{"TERM": "xterm"}, typeset.ANSI},
+        {map[string]string{"TERM": "xterm-256color"}, typeset.ANSI256},
+        {map[string]string{"TERM": "xterm-256color", "COLORTERM": "truecolor"}, typeset.TrueColor},
+        {map[string]string{"TERM": "xterm-kitty"}, typeset.TrueColor},
+        {map[string]string{"TERM": "xterm-256color", "NO_COLOR": "1"}, typeset.Ascii},
+    }
+    for _, test := range tests {
+        p := typeset.EnvProfile(func(key string) string { return test.env[key] })
+        if p != test.expected {
+            t.Errorf("environment %v: expected profile %d, got %d", test.env, test.expected, p)
+        }
+    }
+}
@@ -0,0 +1,230 @@
+package typeset
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Attributes in the order their codes are written
+var attributes = []ansiCMD{Bold, Faint, Italics, Underline, Blink, Inverse, Hidden, CrossOut, DoubleUnderline}
+
+// Codes turning off attributes, with the attributes they turn off
+var offCodes = []struct {
+    code   ansiCMD
+    attrs  []ansiCMD
+}{
+    {NotBold, []ansiCMD{Bold, Faint}},
+    {NotItalics, []ansiCMD{Italics}},
+    {NotUnderline, []ansiCMD{Underline, DoubleUnderline}},
+    {NotBlink, []ansiCMD{Blink}},
+    {NotInverse, []ansiCMD{Inverse}},
+    {NotHidden, []ansiCMD{Hidden}},
+    {NotCrossOut, []ansiCMD{CrossOut}},
+}
+
+func bits(codes ...ansiCMD) uint32 {
+    var b uint32
+    for _, code := range codes {
+        b |= 1 << code
+    }
+    return b
+}
+
+// Style is a set of text attributes with foreground and background colours.
+// Nested in another style, it keeps the attributes and colours it does not
+// set or turn off.
+type Style struct {
+    on, off     uint32
+    fore, back  Color
+}
+
+// ParseStyle reads the formats accepted by FormatString into a style.
+func ParseStyle(format []string) (Style, error) {
+    var s Style
+    for _, f := range format {
+        code, err := getCode(f)
+        if err == nil {
+            s = s.With(code)
+            continue
+        }
+        // Otherwise parse a colour, in the foreground by default
+        name := strings.ToLower(strings.Replace(f, " ", "", -1))
+        c, colorErr := ParseColor(strings.TrimPrefix(strings.TrimPrefix(name, "fore"), "back"))
+        if colorErr != nil {
+            return Style{}, err
+        }
+        if strings.HasPrefix(name, "back") {
+            s.back = c
+        } else {
+            s.fore = c
+        }
+    }
+    return s, nil
+}
+
+// With applies SGR codes to the style, as a terminal would.
+func (s Style) With(codes ...ansiCMD) Style {
+    for _, code := range codes {
+        switch {
+        case code == Normal:
+            s = Style{off: bits(attributes...), fore: DefaultColor, back: DefaultColor}
+        case bits(attributes...)&bits(code) != 0:
+            s.on |= bits(code)
+            s.off &^= bits(code)
+        case code >= ForeBlack && code <= ForeWhite:
+            s.fore = BasicColor(uint8(code - ForeBlack))
+        case code == ForeDefault:
+            s.fore = DefaultColor
+        case code >= BackBlack && code <= BackWhite:
+            s.back = BasicColor(uint8(code - BackBlack))
+        case code == BackDefault:
+            s.back = DefaultColor
+        }
+        for _, off := range offCodes {
+            if off.code == code {
+                s.on &^= bits(off.attrs...)
+                s.off |= bits(off.attrs...)
+            }
+        }
+    }
+    return s
+}
+
+// Foreground sets the colour of the text.
+func (s Style) Foreground(c Color) Style {
+    s.fore = c
+    return s
+}
+
+// Background sets the colour behind the text.
+func (s Style) Background(c Color) Style {
+    s.back = c
+    return s
+}
+
+// within is the style shown when nested in outer
+func (s Style) within(outer Style) Style {
+    in := Style{on: outer.on&^s.off | s.on, fore: outer.fore, back: outer.back}
+    if s.fore.kind != noColor {
+        in.fore = s.fore
+    }
+    if s.back.kind != noColor {
+        in.back = s.back
+    }
+    // The terminal's colours are shown the same whether set or not
+    if in.fore.kind == defaultColor {
+        in.fore = Color{}
+    }
+    if in.back.kind == defaultColor {
+        in.back = Color{}
+    }
+    return in
+}
+
+// transition is the SGR parameters changing what is shown from one style to
+// another
+func transition(from, to Style, p Profile) []string {
+    if to == (Style{}) && from != (Style{}) {
+        return []string{strconv.Itoa(int(Normal))}
+    }
+    var params []string
+    removed := from.on &^ to.on
+    added := to.on &^ from.on
+    for _, off := range offCodes {
+        group := bits(off.attrs...)
+        if removed&group != 0 {
+            params = append(params, strconv.Itoa(int(off.code)))
+            // Attributes sharing the code are turned back on
+            added |= to.on & group
+        }
+    }
+    for _, attr := range attributes {
+        if added&bits(attr) != 0 {
+            params = append(params, strconv.Itoa(int(attr)))
+        }
+    }
+    if to.fore != from.fore {
+        params = append(params, to.fore.params(false, p)...)
+    }
+    if to.back != from.back {
+        params = append(params, to.back.params(true, p)...)
+    }
+    return params
+}
+
+// Render styles text with the profile set by SetProfile. Styled text nested
+// within it returns to this style after its reset, rather than to plain text.
+func (s Style) Render(text string) string {
+    prefix, err := formatANSI(transition(Style{}, s.within(Style{}), profile))
+    if err != nil {
+        // Nothing to apply
+        return text
+    }
+    reset, _ := formatANSI([]string{strconv.Itoa(int(Normal))})
+    return prefix + strings.ReplaceAll(text, reset, reset+prefix) + reset
+}
+
+// Builder writes text in nested style spans. Ending a span changes back only
+// what differs from the style around it, rather than resetting.
+type Builder struct {
+    profile  Profile
+    text     strings.Builder
+    // The styles shown in the open spans
+    spans    []Style
+}
+
+// NewBuilder starts styled text with the colours of a profile.
+func NewBuilder(p Profile) *Builder {
+    return &Builder{profile: p}
+}
+
+func (b *Builder) current() Style {
+    if len(b.spans) == 0 {
+        return Style{}
+    }
+    return b.spans[len(b.spans)-1]
+}
+
+func (b *Builder) change(from, to Style) {
+    if sequence, err := formatANSI(transition(from, to, b.profile)); err == nil {
+        b.text.WriteString(sequence)
+    }
+}
+
+// Push opens a span in a style, nested in the open spans.
+func (b *Builder) Push(s Style) {
+    next := s.within(b.current())
+    b.change(b.current(), next)
+    b.spans = append(b.spans, next)
+}
+
+// Pop closes the innermost span, returning to the style around it.
+func (b *Builder) Pop() {
+    if len(b.spans) == 0 {
+        return
+    }
+    from := b.current()
+    b.spans = b.spans[:len(b.spans)-1]
+    b.change(from, b.current())
+}
+
+// WriteString writes text in the style of the open spans.
+func (b *Builder) WriteString(text string) {
+    b.text.WriteString(text)
+}
+
+// Span writes text in its own span.
+func (b *Builder) Span(s Style, text string) {
+    b.Push(s)
+    b.WriteString(text)
+    b.Pop()
+}
+
+// String is the styled text, with any open spans closed.
+func (b *Builder) String() string {
+    text := b.text.String()
+    if sequence, err := formatANSI(transition(b.current(), Style{}, b.profile)); err == nil {
+        text += sequence
+    }
+    return text
+}
@@ -18,6 +18,7 @@ const (
     Hidden           ansiCMD = 8
     CrossOut         ansiCMD = 9
     DoubleUnderline  ansiCMD = 21
+    NotBold          ansiCMD = 22
     NotItalics       ansiCMD = 23
     NotUnderline     ansiCMD = 24
     NotBlink         ansiCMD = 25
@@ -44,25 +45,14 @@ const (
     BackDefault      ansiCMD = 49
 )
 
-func formatANSI(codes []ansiCMD) (string, error) {
-    // Start ANSI sequence
-    ansi := "\033["
-
-    // Append codes if they exist
-    if len(codes) > 0 {
-        ansi += fmt.Sprintf("%d", codes[0])
-        for i := 1; i < len(codes); i++ {
-            ansi += fmt.Sprintf(";%d", codes[i])
-        }
-    } else {
+func formatANSI(params []string) (string, error) {
+    // Return error if there are no codes to apply
+    if len(params) == 0 {
         return "", fmt.Errorf("no ANSI code to apply")
     }
 
-    // Finish ANSI code and return
-    ansi += "m"
-    // ansi := fmt.Sprintf("\033[%vm", codes)
-    
-    return ansi, nil
+    // Join codes into an ANSI sequence
+    return "\033[" + strings.Join(params, ";") + "m", nil
 }
 
 func getCode(format string) (ansiCMD, error) {
@@ -90,6 +80,8 @@ func getCode(format string) (ansiCMD, error) {
         code = CrossOut
     case "doubleunderline":
         code = DoubleUnderline
+    case "notbold", "notfaint":
+        code = NotBold
     case "notitalics":
         code = NotItalics
     case "notunderline":
@@ -148,7 +140,9 @@ func getCode(format string) (ansiCMD, error) {
             "You can also specify the colors 'black', 'red', 'green', 'yellow', 'blue', " +
             "'magenta', 'cyan', and 'white', but you should also specify 'fore' or 'back' " +
             "before the color to set the foreground or background. " +
-            "The default is the foreground", format)
+            "The default is the foreground. " +
+            "Colors can also be hex codes ('#ff8800'), 'rgb(255,136,0)', 256-color " +
+            "indices, 'bright' colors or named colors such as 'orange'", format)
     }
     return code, nil
 }
@@ -159,32 +153,11 @@ func FormatString(line string, format []string) (string, error) {
         return line, nil
     }
 
-    // Go through formats to get ANSI codes
-    var codes []ansiCMD
-    for i := 0; i < len(format); i++ {
-        code, err := getCode(format[i])
-        if err != nil {
-            return "", fmt.Errorf("error getting ANSI CODE: %v", err)
-        }
-        codes = append(codes, code)
-    }
-
-    // Format string prefix
-    prefix, err := formatANSI(codes)
-    if err != nil {
-        return "", fmt.Errorf("error combining ANSI codes: %v", err)
-    }
-
-    // Format reset string
-    suffix, err := formatANSI([]ansiCMD{Normal})
+    // Go through formats to get the style
+    style, err := ParseStyle(format)
     if err != nil {
-        return "", fmt.Errorf("error creating suffix: %v", err)
+        return "", fmt.Errorf("error getting ANSI CODE: %v", err)
     }
 
-    return prefix + line + suffix, nil
+    return style.Render(line), nil
 }
-
-
-
-
-
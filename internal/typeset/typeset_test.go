@@ -69,3 +69,78 @@ Actual:    formattedMessage: %s
 	fmt.Println("========================================")
 	fmt.Printf("%d passed, %d failed\n\n\n", passCount, failCount)
 }
+
+func TestNestedStyles(t *testing.T) {
+    style := func(format ...string) typeset.Style {
+        s, err := typeset.ParseStyle(format)
+        if err != nil {
+            t.Fatalf("error parsing style %q: %v", format, err)
+        }
+        return s
+    }
+    tests := []struct {
+        name      string
+        write     func(b *typeset.Builder)
+        expected  string
+    }{
+        {"span", func(b *typeset.Builder) {
+            b.Span(style("bold"), "bold")
+        }, "\033[1mbold\033[0m"},
+        {"colour restored", func(b *typeset.Builder) {
+            b.Push(style("red"))
+            b.WriteString("red ")
+            b.Span(style("#00ff00"), "green")
+            b.WriteString(" red")
+            b.Pop()
+        }, "\033[31mred \033[38;2;0;255;0mgreen\033[31m red\033[0m"},
+        {"attributes kept", func(b *typeset.Builder) {
+            b.Push(style("bold", "back blue"))
+            b.Span(style("italics", "yellow"), "both")
+            b.WriteString(" bold")
+            b.Pop()
+        }, "\033[1;44m\033[3;33mboth\033[23;39m bold\033[0m"},
+        {"turned off inside", func(b *typeset.Builder) {
+            b.Push(style("bold", "faint", "red"))
+            b.Span(style("not bold"), "red")
+            b.Pop()
+        }, "\033[1;2;31m\033[22mred\033[1;2m\033[0m"},
+        {"faint kept after bold", func(b *typeset.Builder) {
+            b.Push(style("faint"))
+            b.Span(style("bold"), "both")
+            b.WriteString("faint")
+            b.Pop()
+        }, "\033[2m\033[1mboth\033[22;2mfaint\033[0m"},
+        {"reset inside", func(b *typeset.Builder) {
+            b.Push(style("underline"))
+            b.Span(style("reset"), "plain")
+            b.Pop()
+        }, "\033[4m\033[0mplain\033[4m\033[0m"},
+        {"open spans closed", func(b *typeset.Builder) {
+            b.Push(style("blink"))
+            b.Push(style("back 200"))
+            b.WriteString("open")
+        }, "\033[5m\033[48;5;200mopen\033[0m"},
+    }
+    for _, test := range tests {
+        b := typeset.NewBuilder(typeset.TrueColor)
+        test.write(b)
+        if b.String() != test.expected {
+            t.Errorf("%s: expected %q, got %q", test.name, test.expected, b.String())
+        }
+    }
+}
+
+func TestNestedFormatString(t *testing.T) {
+    inner, _ := typeset.FormatString("inner", []string{"red"})
+    outer, err := typeset.FormatString("outer "+inner+" outer", []string{"bold"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := "\033[1mouter \033[31minner\033[0m\033[1m outer\033[0m"
+    if outer != expected {
+        t.Errorf("expected %q, got %q", expected, outer)
+    }
+    if _, err := typeset.FormatString("x", []string{"not a colour"}); err == nil {
+        t.Errorf("expected an error for an unknown format")
+    }
+}
@@ -0,0 +1,189 @@
+package typeset
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// escapeLength is the length of the escape sequence at the start of s, or 0
+func escapeLength(s string) int {
+    if len(s) < 2 || s[0] != '\033' {
+        return 0
+    }
+    switch s[1] {
+    case '[':
+        // Control sequence, ending in a byte from '@' to '~'
+        for i := 2; i < len(s); i++ {
+            if s[i] >= '@' && s[i] <= '~' {
+                return i + 1
+            }
+        }
+        return len(s)
+    case ']', 'P', '_', '^':
+        // String sequence, such as a hyperlink, ending in BEL or ESC \
+        for i := 2; i < len(s); i++ {
+            if s[i] == '\a' {
+                return i + 1
+            }
+            if s[i] == '\033' && i+1 < len(s) && s[i+1] == '\\' {
+                return i + 2
+            }
+        }
+        return len(s)
+    }
+    return 2
+}
+
+// StripANSI removes the escape sequences from a string, leaving its text.
+func StripANSI(s string) string {
+    var b strings.Builder
+    for i := 0; i < len(s); {
+        if n := escapeLength(s[i:]); n > 0 {
+            i += n
+            continue
+        }
+        b.WriteByte(s[i])
+        i++
+    }
+    return b.String()
+}
+
+// Width is the number of terminal columns taken by a line, ignoring escape
+// sequences and counting wide characters twice.
+func Width(s string) int {
+    return runewidth.StringWidth(StripANSI(s))
+}
+
+// Truncate cuts a line to fit a width, ending it with tail if it is cut. The
+// escape sequences after the cut are kept, so styles are still reset.
+func Truncate(s string, width int, tail string) string {
+    if Width(s) <= width {
+        return s
+    }
+    if Width(tail) > width {
+        tail = ""
+    }
+    head, rest, _ := split(s, width-Width(tail))
+    return head + tail + escapes(rest)
+}
+
+// split cuts text after a width, returning the width before the cut
+func split(s string, width int) (string, string, int) {
+    used := 0
+    for i := 0; i < len(s); {
+        if n := escapeLength(s[i:]); n > 0 {
+            i += n
+            continue
+        }
+        r, size := utf8.DecodeRuneInString(s[i:])
+        w := runewidth.RuneWidth(r)
+        if used+w > width {
+            return s[:i], s[i:], used
+        }
+        used += w
+        i += size
+    }
+    return s, "", used
+}
+
+// escapes is only the escape sequences in s
+func escapes(s string) string {
+    var b strings.Builder
+    for i := 0; i < len(s); i++ {
+        if n := escapeLength(s[i:]); n > 0 {
+            b.WriteString(s[i : i+n])
+            i += n - 1
+        }
+    }
+    return b.String()
+}
+
+// Wrap breaks text into lines that fit a width, between words where it can.
+// Escape sequences take no space, and the spaces where lines break are
+// dropped.
+func Wrap(s string, width int) string {
+    if width < 1 {
+        return s
+    }
+    lines := strings.Split(s, "\n")
+    for i, line := range lines {
+        lines[i] = wrapLine(line, width)
+    }
+    return strings.Join(lines, "\n")
+}
+
+// word is a run of spaces or of other characters, with any escape sequences
+// in it
+type word struct {
+    text    string
+    width   int
+    spaces  bool
+}
+
+func words(line string) []word {
+    var ws []word
+    for i := 0; i < len(line); {
+        if n := escapeLength(line[i:]); n > 0 {
+            // Escape sequences join the word they are in
+            if len(ws) == 0 {
+                ws = append(ws, word{})
+            }
+            ws[len(ws)-1].text += line[i : i+n]
+            i += n
+            continue
+        }
+        r, size := utf8.DecodeRuneInString(line[i:])
+        spaces := r == ' '
+        if len(ws) == 0 || ws[len(ws)-1].spaces != spaces {
+            ws = append(ws, word{spaces: spaces})
+        }
+        ws[len(ws)-1].text += line[i : i+size]
+        ws[len(ws)-1].width += runewidth.RuneWidth(r)
+        i += size
+    }
+    return ws
+}
+
+func wrapLine(line string, width int) string {
+    var b strings.Builder
+    used := 0
+    // Spaces are written once the word after them fits
+    var pending word
+    for _, w := range words(line) {
+        if w.spaces {
+            pending.text += w.text
+            pending.width += w.width
+            continue
+        }
+        if used > 0 && used+pending.width+w.width > width {
+            // Break the line, keeping the escape sequences in the spaces
+            b.WriteString(escapes(pending.text) + "\n")
+            used = 0
+        } else {
+            b.WriteString(pending.text)
+            used += pending.width
+        }
+        pending = word{}
+        // Words longer than a line are broken
+        for used+w.width > width {
+            head, rest, headWidth := split(w.text, width-used)
+            if headWidth == 0 {
+                if used == 0 {
+                    // A character wider than the line
+                    break
+                }
+                b.WriteString("\n")
+                used = 0
+                continue
+            }
+            b.WriteString(head + "\n")
+            w.text, w.width, used = rest, w.width-headWidth, 0
+        }
+        b.WriteString(w.text)
+        used += w.width
+    }
+    b.WriteString(escapes(pending.text))
+    return b.String()
+}
@@ -0,0 +1,91 @@
+package typeset_test
+
+import (
+	"testing"
+
+	"github.com/CraigYanitski/mescli/internal/typeset"
+)
+
+func TestStripANSI(t *testing.T) {
+    tests := []struct {
+        input     string
+        expected  string
+    }{
+        {"plain", "plain"},
+        {"\033[1;31mred\033[0m", "red"},
+        {"\033[38;2;1;2;3mtrue\033[39m colour", "true colour"},
+        {"\033]8;;https://example.com\033\\link\033]8;;\a", "link"},
+        {"cursor\033[2Kcleared", "cursorcleared"},
+        {"unfinished\033[1", "unfinished"},
+    }
+    for _, test := range tests {
+        if stripped := typeset.StripANSI(test.input); stripped != test.expected {
+            t.Errorf("StripANSI(%q) = %q, expected %q", test.input, stripped, test.expected)
+        }
+    }
+}
+
+func TestWidth(t *testing.T) {
+    tests := []struct {
+        input     string
+        expected  int
+    }{
+        {"", 0},
+        {"hello", 5},
+        {"\033[1mhello\033[0m", 5},
+        {"日本語", 6},
+        {"é", 1},
+        {"x̂", 1},
+    }
+    for _, test := range tests {
+        if width := typeset.Width(test.input); width != test.expected {
+            t.Errorf("Width(%q) = %d, expected %d", test.input, width, test.expected)
+        }
+    }
+}
+
+func TestTruncate(t *testing.T) {
+    tests := []struct {
+        input     string
+        width     int
+        tail      string
+        expected  string
+    }{
+        {"short", 10, "…", "short"},
+        {"exactly", 7, "…", "exactly"},
+        {"truncated text", 8, "…", "truncat…"},
+        {"truncated text", 8, "", "truncate"},
+        {"日本語テキスト", 7, "…", "日本語…"},
+        {"\033[1mbold text\033[0m", 5, "…", "\033[1mbold…\033[0m"},
+        {"\033[31mred\033[0m and plain", 4, ".", "\033[31mred\033[0m."},
+        {"tail too wide", 2, "...", "ta"},
+    }
+    for _, test := range tests {
+        if truncated := typeset.Truncate(test.input, test.width, test.tail); truncated != test.expected {
+            t.Errorf("Truncate(%q, %d, %q) = %q, expected %q", test.input, test.width, test.tail, truncated, test.expected)
+        }
+    }
+}
+
+func TestWrap(t *testing.T) {
+    tests := []struct {
+        input     string
+        width     int
+        expected  string
+    }{
+        {"fits", 10, "fits"},
+        {"the quick brown fox", 10, "the quick\nbrown fox"},
+        {"the  quick", 5, "the\nquick"},
+        {"keep\n\nparagraphs", 20, "keep\n\nparagraphs"},
+        {"unbreakablewords", 5, "unbre\nakabl\neword\ns"},
+        {"a longerword", 5, "a\nlonge\nrword"},
+        {"日本語 テキスト", 6, "日本語\nテキス\nト"},
+        {"\033[1mbold words\033[0m here", 5, "\033[1mbold\nwords\033[0m\nhere"},
+        {"spaced \033[31m red\033[0m", 6, "spaced\033[31m\nred\033[0m"},
+    }
+    for _, test := range tests {
+        if wrapped := typeset.Wrap(test.input, test.width); wrapped != test.expected {
+            t.Errorf("Wrap(%q, %d) = %q, expected %q", test.input, test.width, wrapped, test.expected)
+        }
+    }
+}
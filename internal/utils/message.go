@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxMessageLength is the most characters sent in a single message.
+const MaxMessageLength = 1024
+
+// SplitMessage splits a message into chunks of at most limit characters,
+// breaking between paragraphs where possible, then lines, then words. Fenced
+// code blocks are never broken, so one longer than the limit is sent whole.
+func SplitMessage(message string, limit int) []string {
+    chunks := []string{}
+    message = strings.TrimSpace(message)
+    for utf8.RuneCountInString(message) > limit {
+        // byte offset of the character limit
+        end, count := len(message), 0
+        for i := range message {
+            if count == limit {
+                end = i
+                break
+            }
+            count++
+        }
+        blocks := fences(message)
+        prefix := message[:end]
+        cut := lastCut(prefix, "\n\n", blocks)
+        if cut <= 0 {
+            cut = lastCut(prefix, "\n", blocks)
+        }
+        if cut <= 0 {
+            cut = lastCut(prefix, " ", blocks)
+        }
+        if cut <= 0 {
+            cut = end
+            for _, b := range blocks {
+                if b[0] < end && end < b[1] {
+                    cut = b[1]
+                }
+            }
+        }
+        chunks = append(chunks, strings.TrimSpace(message[:cut]))
+        message = strings.TrimSpace(message[cut:])
+    }
+    if message != "" {
+        chunks = append(chunks, message)
+    }
+    return chunks
+}
+
+// lastCut finds the last separator in text that is outside the fenced blocks
+func lastCut(text, sep string, blocks [][2]int) int {
+    for cut := strings.LastIndex(text, sep); cut > 0; cut = strings.LastIndex(text[:cut], sep) {
+        inside := false
+        for _, b := range blocks {
+            if b[0] < cut && cut < b[1] {
+                inside = true
+            }
+        }
+        if !inside {
+            return cut
+        }
+    }
+    return -1
+}
+
+// fences finds the byte ranges of the fenced code blocks in Markdown, from
+// the opening fence to the end of the closing one
+func fences(message string) [][2]int {
+    var blocks [][2]int
+    open, marker := -1, ""
+    for start := 0; start < len(message); {
+        stop := strings.IndexByte(message[start:], '\n')
+        if stop < 0 {
+            stop = len(message)
+        } else {
+            stop += start
+        }
+        line := strings.TrimLeft(message[start:stop], " ")
+        switch {
+        case open < 0 && (strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~")):
+            open = start
+            marker = line[:len(line)-len(strings.TrimLeft(line, line[:1]))]
+        case open >= 0 && strings.HasPrefix(line, marker) && strings.TrimSpace(strings.TrimLeft(line, marker[:1])) == "":
+            blocks = append(blocks, [2]int{open, stop})
+            open = -1
+        }
+        start = stop + 1
+    }
+    // an unclosed fence runs to the end of the message
+    if open >= 0 {
+        blocks = append(blocks, [2]int{open, len(message)})
+    }
+    return blocks
+}
+
+// EditorCommand returns the command opening the user's editor on a file.
+func EditorCommand(path string) *exec.Cmd {
+    editor := os.Getenv("VISUAL")
+    if editor == "" {
+        editor = os.Getenv("EDITOR")
+    }
+    if editor == "" {
+        editor = "vi"
+    }
+    // the editor may include arguments, such as "code --wait"
+    args := strings.Fields(editor)
+    return exec.Command(args[0], append(args[1:], path)...)
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessage(t *testing.T) {
+    type testCase struct {
+        name      string
+        message   string
+        limit     int
+        expected  []string
+    }
+
+    tests := []testCase{
+        {"short", "hello", 10, []string{"hello"}},
+        {"empty", "  ", 10, []string{}},
+        {"paragraphs", "first line\nsecond\n\nthird", 20, []string{"first line\nsecond", "third"}},
+        {"lines", "first line\nsecond line", 15, []string{"first line", "second line"}},
+        {"words", "one two three four", 9, []string{"one two", "three", "four"}},
+        {"hard", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+        {"unicode", "ééééé", 2, []string{"éé", "éé", "é"}},
+        {"fence", "intro\n\n```go\nfunc a() {\n}\n```\n\nafter", 20,
+            []string{"intro", "```go\nfunc a() {\n}\n```", "after"}},
+        {"long fence", "```\none two three\nfour five six\n```\nend", 12,
+            []string{"```\none two three\nfour five six\n```", "end"}},
+        {"unclosed fence", "text\n~~~\nab cd ef", 8, []string{"text", "~~~\nab cd ef"}},
+    }
+
+    for _, test := range tests {
+        chunks := SplitMessage(test.message, test.limit)
+        if strings.Join(chunks, "|") != strings.Join(test.expected, "|") || len(chunks) != len(test.expected) {
+            t.Errorf("%s: expected %q, got %q", test.name, test.expected, chunks)
+        }
+        for _, c := range chunks {
+            // only fenced code blocks may be longer than the limit
+            if utf8.RuneCountInString(c) > test.limit && !strings.HasPrefix(c, "```") && !strings.HasPrefix(c, "~~~") {
+                t.Errorf("%s: chunk %q longer than %d characters", test.name, c, test.limit)
+            }
+        }
+    }
+}
@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// kinds of payload carried inside the encryption
+const (
+    TextPayload      = "text"
+    EditPayload      = "edit"
+    DeletePayload    = "delete"
+    ReactionPayload  = "reaction"
+    TypingPayload    = "typing"
+)
+
+// bodies of typing payloads
+const (
+    TypingStarted  = "started"
+    TypingStopped  = "stopped"
+)
+
+// PayloadVersion is the version of the payload envelope written by this
+// client. Payloads from newer clients are read as far as they are understood.
+const PayloadVersion = 1
+
+// Payload is the envelope inside the encryption of every message. Text
+// messages carry an ID so that later edits, deletions, reactions and replies
+// can refer to them.
+type Payload struct {
+    Version      int                `json:"version"`
+    Type         string             `json:"type"`
+    ID           string             `json:"id"`
+    SentAt       time.Time          `json:"sent_at"`
+    Body         string             `json:"body,omitempty"`
+    Target       string             `json:"target,omitempty"`
+    ReplyTo      string             `json:"reply_to,omitempty"`
+    Attachments  []Attachment       `json:"attachments,omitempty"`
+    Metadata     map[string]string  `json:"metadata,omitempty"`
+}
+
+// Attachment is a file sent inside a payload.
+type Attachment struct {
+    Name  string  `json:"name"`
+    Type  string  `json:"type"`
+    Data  []byte  `json:"data"`
+}
+
+type Reaction struct {
+    Sender  SenderType  `json:"sender"`
+    Emoji   string      `json:"emoji"`
+}
+
+// NewPayload returns a payload of the given kind with a new ID, sent now.
+func NewPayload(kind, body string) Payload {
+    return Payload{
+        Version: PayloadVersion,
+        Type: kind,
+        ID: uuid.NewString(),
+        SentAt: time.Now().UTC().Truncate(time.Millisecond),
+        Body: body,
+    }
+}
+
+func EncodePayload(p Payload) (string, error) {
+    data, err := json.Marshal(p)
+    if err != nil {
+        return "", err
+    }
+    return string(data), nil
+}
+
+// DecodePayload reads a decrypted message, treating anything that is not a
+// payload as the bare text sent by older clients.
+func DecodePayload(plaintext string) Payload {
+    p := Payload{}
+    if err := json.Unmarshal([]byte(plaintext), &p); err != nil || p.Type == "" || p.Version < 0 {
+        return Payload{Type: TextPayload, Body: plaintext}
+    }
+    // empty lists are the same as none
+    if len(p.Attachments) == 0 {
+        p.Attachments = nil
+    }
+    if len(p.Metadata) == 0 {
+        p.Metadata = nil
+    }
+    return p
+}
+
+// Summary describes a payload in a single line of text.
+func (p Payload) Summary() string {
+    switch p.Type {
+    case EditPayload:
+        return "(edited) " + p.Body
+    case DeletePayload:
+        return "(deleted a message)"
+    case ReactionPayload:
+        if p.Body == "" {
+            return "(removed a reaction)"
+        }
+        return "(reacted " + p.Body + ")"
+    case TypingPayload:
+        return "(typing " + p.Body + ")"
+    case TextPayload:
+        return p.Body
+    }
+    return "(unsupported message)"
+}
+
+// ApplyPayload adds a payload from the sender to a conversation. Edits and
+// deletions only apply to the sender's own messages. Payloads referring to
+// unknown messages, carrying typing signals or of types this client does not
+// know are dropped.
+func ApplyPayload(conversation []RawMessage, sender SenderType, p Payload, t time.Time) []RawMessage {
+    switch p.Type {
+    case TypingPayload:
+        // typing signals are never stored
+        return conversation
+    case EditPayload, DeletePayload:
+        i := FindMessage(conversation, p.Target)
+        if i < 0 || conversation[i].Sender != sender || conversation[i].Deleted {
+            return conversation
+        }
+        if p.Type == EditPayload {
+            conversation[i].Message = p.Body
+            conversation[i].Edited = true
+        } else {
+            conversation[i].Message = ""
+            conversation[i].ReplyTo = ""
+            conversation[i].Reactions = nil
+            conversation[i].Deleted = true
+        }
+        return conversation
+    case ReactionPayload:
+        i := FindMessage(conversation, p.Target)
+        if i < 0 || conversation[i].Deleted {
+            return conversation
+        }
+        // each person has at most one reaction on a message
+        reactions := []Reaction{}
+        for _, r := range conversation[i].Reactions {
+            if r.Sender != sender {
+                reactions = append(reactions, r)
+            }
+        }
+        if p.Body != "" {
+            reactions = append(reactions, Reaction{Sender: sender, Emoji: p.Body})
+        }
+        conversation[i].Reactions = reactions
+        return conversation
+    }
+    // a message is only stored once
+    if p.Type != TextPayload || FindMessage(conversation, p.ID) >= 0 {
+        return conversation
+    }
+    return append(conversation, RawMessage{
+        ID: p.ID,
+        Sender: sender,
+        Message: p.Body,
+        ReplyTo: p.ReplyTo,
+        Time: t,
+    })
+}
+
+// FindMessage returns the index of the message with the ID, or -1.
+func FindMessage(conversation []RawMessage, id string) int {
+    if id == "" {
+        return -1
+    }
+    for i, m := range conversation {
+        if m.ID == id {
+            return i
+        }
+    }
+    return -1
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestDecodePayload(t *testing.T) {
+    p := NewPayload(TextPayload, "hello")
+    p.ReplyTo = "abc"
+    encoded, err := EncodePayload(p)
+    if err != nil {
+        t.Fatalf("error encoding payload: %s", err)
+    }
+    if got := DecodePayload(encoded); !reflect.DeepEqual(got, p) {
+        t.Errorf("expected %+v, got %+v", p, got)
+    }
+
+    // older clients send the text on its own
+    for _, text := range []string{"hi there", `{"not":"a payload"}`, "{", "", `{"type":"text","version":-1}`} {
+        if got := DecodePayload(text); got.Type != TextPayload || got.Body != text || got.ID != "" {
+            t.Errorf("expected %q to decode as text, got %+v", text, got)
+        }
+    }
+
+    // newer versions are read as far as they are understood
+    got := DecodePayload(`{"version":7,"type":"text","id":"x","body":"hi","sent_at":"2025-01-02T03:04:05Z",` +
+        `"attachments":[{"name":"a.txt","type":"text/plain","data":"aGk="}],"metadata":{"k":"v"},"new":[1,2]}`)
+    want := Payload{
+        Version: 7,
+        Type: TextPayload,
+        ID: "x",
+        SentAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+        Body: "hi",
+        Attachments: []Attachment{{Name: "a.txt", Type: "text/plain", Data: []byte("hi")}},
+        Metadata: map[string]string{"k": "v"},
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("expected %+v, got %+v", want, got)
+    }
+    if got := DecodePayload(`{"version":1,"type":"poll","id":"y"}`); got.Summary() != "(unsupported message)" {
+        t.Errorf("unexpected summary of an unknown type %q", got.Summary())
+    }
+}
+
+func FuzzDecodePayload(f *testing.F) {
+    p := NewPayload(TextPayload, "hello")
+    p.Attachments = []Attachment{{Name: "a", Type: "text/plain", Data: []byte("a")}}
+    p.Metadata = map[string]string{"k": "v"}
+    encoded, _ := EncodePayload(p)
+    for _, seed := range []string{encoded, "bare text", "", "{}", `{"type":"edit","target":"x"}`, `{"version":2}`} {
+        f.Add(seed)
+    }
+    f.Fuzz(func(t *testing.T, plaintext string) {
+        p := DecodePayload(plaintext)
+        if p.Type == "" {
+            t.Fatalf("decoded %q without a type", plaintext)
+        }
+        // decoding what was encoded gives the same payload
+        encoded, err := EncodePayload(p)
+        if err != nil {
+            t.Fatalf("error encoding %+v: %s", p, err)
+        }
+        again := DecodePayload(encoded)
+        if !again.SentAt.Equal(p.SentAt) {
+            t.Fatalf("sent time changed from %s to %s", p.SentAt, again.SentAt)
+        }
+        again.SentAt = p.SentAt
+        // encoding replaces invalid UTF-8 in bare text
+        if utf8.ValidString(plaintext) && !reflect.DeepEqual(again, p) {
+            t.Fatalf("expected %+v, got %+v", p, again)
+        }
+    })
+}
+
+func TestApplyPayload(t *testing.T) {
+    now := time.Now()
+    conv := []RawMessage{}
+    mine := NewPayload(TextPayload, "hello")
+    theirs := NewPayload(TextPayload, "hi")
+    theirs.ReplyTo = mine.ID
+    conv = ApplyPayload(conv, SelfType, mine, now)
+    conv = ApplyPayload(conv, ContactType, theirs, now)
+    // the same message arriving twice is stored once
+    conv = ApplyPayload(conv, ContactType, theirs, now)
+    if len(conv) != 2 || conv[1].ReplyTo != mine.ID {
+        t.Fatalf("expected two messages with a reply, got %+v", conv)
+    }
+
+    payload := func(kind, target, body string) Payload {
+        p := NewPayload(kind, body)
+        p.Target = target
+        return p
+    }
+
+    // only the sender can edit or delete a message
+    conv = ApplyPayload(conv, ContactType, payload(EditPayload, mine.ID, "hacked"), now)
+    conv = ApplyPayload(conv, ContactType, payload(DeletePayload, mine.ID, ""), now)
+    if conv[0].Message != "hello" || conv[0].Edited || conv[0].Deleted {
+        t.Fatalf("expected the contact not to change my message, got %+v", conv[0])
+    }
+    conv = ApplyPayload(conv, SelfType, payload(EditPayload, mine.ID, "hello!"), now)
+    if conv[0].Message != "hello!" || !conv[0].Edited {
+        t.Fatalf("expected my message to be edited, got %+v", conv[0])
+    }
+
+    // each sender has one reaction, and an empty reaction removes it
+    conv = ApplyPayload(conv, SelfType, payload(ReactionPayload, theirs.ID, "👍"), now)
+    conv = ApplyPayload(conv, ContactType, payload(ReactionPayload, theirs.ID, "😂"), now)
+    conv = ApplyPayload(conv, SelfType, payload(ReactionPayload, theirs.ID, "❤️"), now)
+    if rs := conv[1].Reactions; len(rs) != 2 || rs[0].Emoji != "😂" || rs[1].Emoji != "❤️" {
+        t.Fatalf("unexpected reactions %+v", rs)
+    }
+    conv = ApplyPayload(conv, ContactType, payload(ReactionPayload, theirs.ID, ""), now)
+    if rs := conv[1].Reactions; len(rs) != 1 || rs[0].Sender != SelfType {
+        t.Fatalf("expected the contact's reaction to be removed, got %+v", rs)
+    }
+
+    // deleted messages lose their content and cannot be changed again
+    conv = ApplyPayload(conv, ContactType, payload(DeletePayload, theirs.ID, ""), now)
+    conv = ApplyPayload(conv, ContactType, payload(EditPayload, theirs.ID, "back"), now)
+    conv = ApplyPayload(conv, SelfType, payload(ReactionPayload, theirs.ID, "👍"), now)
+    if m := conv[1]; !m.Deleted || m.Message != "" || m.ReplyTo != "" || m.Reactions != nil {
+        t.Fatalf("expected the contact's message to be deleted, got %+v", m)
+    }
+
+    // payloads for unknown messages, or of unknown types, are dropped
+    if got := ApplyPayload(conv, ContactType, NewPayload("poll", "?"), now); len(got) != 2 {
+        t.Fatalf("expected the unknown payload to be dropped, got %+v", got)
+    }
+    if got := ApplyPayload(conv, SelfType, payload(EditPayload, "missing", "x"), now); len(got) != 2 {
+        t.Fatalf("expected the edit to be dropped, got %+v", got)
+    }
+}
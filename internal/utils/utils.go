@@ -18,9 +18,14 @@ const (
 )
 
 type RawMessage struct {
-    Sender   SenderType  `json:"sender"`
-    Message  string      `json:"message"`
-    Time     time.Time   `json:"time"`
+    ID         string      `json:"id,omitempty"`
+    Sender     SenderType  `json:"sender"`
+    Message    string      `json:"message"`
+    Time       time.Time   `json:"time"`
+    ReplyTo    string      `json:"reply_to,omitempty"`
+    Edited     bool        `json:"edited,omitempty"`
+    Deleted    bool        `json:"deleted,omitempty"`
+    Reactions  []Reaction  `json:"reactions,omitempty"`
 }
 
 // test encryption functionality
@@ -45,7 +50,10 @@ func RunTests() {
     log.Println("have prekey packet")
 
     // Perform extended triple Diffie-Hellman exchange
-    aliceMP := alice.InitiateX3DH(bobPKP, uuid.UUID{}, true)
+    aliceMP, err := alice.InitiateX3DH(bobPKP, uuid.UUID{}, true)
+    if err != nil {
+        log.Fatal(err)
+    }
     fmt.Printf("\nX3DH initialised\n")
     err = bob.CompleteX3DH(aliceMP, uuid.UUID{}, true)
     if err != nil {
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+)
+
+func ValidateEmail(s string) error {
+    ok, err := regexp.MatchString(`([a-zA-Z0-9\._-]+)\@`, s)
+    if err == nil && !ok {
+        err = errors.New("not a valid email")
+    }
+    if err != nil {
+        return err
+    }
+    ok, err = regexp.MatchString(`(\@[a-zA-Z0-9\._-]+)\.`, s)
+    if err == nil && !ok {
+        err = errors.New("not a valid email")
+    }
+    if err != nil {
+        return err
+    }
+    ok, err = regexp.MatchString(`(\.[a-zA-Z0-9]+)`, s)
+    if err == nil && !ok {
+        err = errors.New("not a valid email")
+    }
+    return err
+}
+
+func ValidatePassword(s string) error {
+    ok, err := regexp.MatchString(`[a-z]+`, s)
+    if err == nil && !ok {
+        err = errors.New("password does not contain lowercase characters")
+    }
+    if err != nil {
+        return err
+    }
+    ok, err = regexp.MatchString(`[A-Z]+`, s)
+    if err == nil && !ok {
+        err = errors.New("password does not contain uppercase characters")
+    }
+    if err != nil {
+        return err
+    }
+    ok, err = regexp.MatchString(`[^a-zA-Z0-9]+`, s)
+    if err == nil && !ok {
+        err = errors.New("password does not contain special characters")
+    }
+    if err != nil {
+        return err
+    }
+    ok, err = regexp.MatchString(`.{8,}`, s)
+    if err == nil && !ok {
+        err = errors.New("password not long enough")
+    }
+    return err
+}
+
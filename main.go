@@ -15,6 +15,8 @@ import (
 type apiConfig struct {
     dbQueries  *database.Queries
     secret     string
+    lookups    *rateLimiter
+    presence   *presenceStore
 }
 
 func main() {
@@ -39,6 +41,8 @@ func main() {
     apiCfg := apiConfig{
         dbQueries: dbQueries,
         secret: secret,
+        lookups: newRateLimiter(lookupLimit, lookupWindow),
+        presence: newPresenceStore(),
     }
 
     // create server multiplexer
@@ -49,6 +53,8 @@ func main() {
     mux.HandleFunc("POST /api/users", http.HandlerFunc(apiCfg.handleCreateUser))
     mux.Handle("GET /api/users", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleGetUserByEmail)))
     mux.Handle("PUT /api/users", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleUpdateUser)))
+    mux.Handle("DELETE /api/users", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleDeleteUser)))
+    mux.Handle("POST /api/users/discover", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleDiscoverUsers)))
     mux.Handle("GET /api/users/{userID}", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleGetUser)))
     mux.Handle("GET /api/users/crypto/{userID}", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleGetUserKeyPacket)))
     mux.Handle("GET /api/users/identity/{userID}", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleGetUserIdentityKey)))
@@ -59,6 +65,20 @@ func main() {
     // messages
     mux.Handle("POST /api/messages", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleCreateMessage)))
     mux.Handle("GET /api/messages", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.HandleGetMessages)))
+    // blocks
+    mux.Handle("POST /api/blocks", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleCreateBlock)))
+    mux.Handle("GET /api/blocks", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleGetBlocks)))
+    mux.Handle("DELETE /api/blocks/{userID}", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleDeleteBlock)))
+    // message requests
+    mux.Handle("GET /api/requests", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleGetMessageRequests)))
+    mux.Handle("POST /api/requests/{userID}", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleAcceptMessageRequest)))
+    mux.Handle("DELETE /api/requests/{userID}", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleDeclineMessageRequest)))
+    // settings
+    mux.Handle("GET /api/settings", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleGetSettings)))
+    mux.Handle("PUT /api/settings", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleUpdateSettings)))
+    // presence
+    mux.Handle("PUT /api/presence", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleUpdatePresence)))
+    mux.Handle("GET /api/presence/{userID}", apiCfg.authenticationMiddleware(http.HandlerFunc(apiCfg.handleGetPresence)))
 
     // define server and listen for requests
     const port = "8080"
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/auth"
+	"github.com/CraigYanitski/mescli/internal/database"
+	"github.com/google/uuid"
+)
+
+type MessageRequest struct {
+    SenderID     uuid.UUID  `json:"sender_id"`
+    Email        string     `json:"email"`
+    Name         string     `json:"name"`
+    Messages     int64      `json:"messages"`
+    FirstSentAt  time.Time  `json:"first_sent_at"`
+}
+type Settings struct {
+    MessageRequests  bool  `json:"message_requests"`
+}
+
+func (cfg *apiConfig) handleGetMessageRequests(w http.ResponseWriter, r *http.Request) {
+    // check authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "unauthorised", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+
+    messageRequests, err := cfg.dbQueries.GetMessageRequests(r.Context(), id)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error getting message requests from database", err)
+        return
+    }
+
+    requestList := []MessageRequest{}
+    for _, messageRequest := range messageRequests {
+        requestList = append(requestList, MessageRequest(messageRequest))
+    }
+
+    respondWithJSON(w, http.StatusOK, requestList)
+}
+
+func (cfg *apiConfig) handleAcceptMessageRequest(w http.ResponseWriter, r *http.Request) {
+    // check authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "unauthorised", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+
+    // get sender ID from request
+    senderID, err := uuid.Parse(r.PathValue("userID"))
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "unable to parse user ID", err)
+        return
+    }
+
+    // accept the sender and release their held messages
+    err = cfg.dbQueries.CreateAcceptedContact(
+        r.Context(), 
+        database.CreateAcceptedContactParams{UserID: id, ContactID: senderID},
+    )
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error adding accepted contact", err)
+        return
+    }
+    err = cfg.dbQueries.AcceptMessages(
+        r.Context(), 
+        database.AcceptMessagesParams{UserID: id, SenderID: senderID},
+    )
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error accepting messages", err)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handleDeclineMessageRequest(w http.ResponseWriter, r *http.Request) {
+    // check authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "unauthorised", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+
+    // get sender ID from request
+    senderID, err := uuid.Parse(r.PathValue("userID"))
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "unable to parse user ID", err)
+        return
+    }
+
+    // drop the held messages without accepting the sender
+    err = cfg.dbQueries.DeletePendingMessages(
+        r.Context(), 
+        database.DeletePendingMessagesParams{UserID: id, SenderID: senderID},
+    )
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error deleting pending messages", err)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+    // check authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "unauthorised", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+
+    user, err := cfg.dbQueries.GetUser(r.Context(), id)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "unable to get user", err)
+        return
+    }
+
+    respondWithJSON(w, http.StatusOK, Settings{MessageRequests: user.MessageRequests})
+}
+
+func (cfg *apiConfig) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+    // check authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "unauthorised", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+
+    // unmarshal PUT JSON
+    decoder := json.NewDecoder(r.Body)
+    s := &Settings{}
+    err = decoder.Decode(s)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error decoding request", err)
+        return
+    }
+
+    user, err := cfg.dbQueries.UpdateMessageRequests(
+        r.Context(), 
+        database.UpdateMessageRequestsParams{ID: id, MessageRequests: s.MessageRequests},
+    )
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error updating users database", err)
+        return
+    }
+
+    respondWithJSON(w, http.StatusOK, Settings{MessageRequests: user.MessageRequests})
+}
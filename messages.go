@@ -12,9 +12,11 @@ import (
 )
 
 type InitMessage struct {
-    UserID    uuid.UUID  `json:"user_id"`
+    UserID              uuid.UUID  `json:"user_id"`
     //SenderID  uuid.UUID  `json:"sender_id"`
-    Message   string     `json:"message"`
+    Message             string     `json:"message"`
+    SenderIdentityKey   string     `json:"sender_identity_key"`
+    SenderEphemeralKey  string     `json:"sender_ephemeral_key"`
 }
 type Message struct {
     ID                  uuid.UUID       `json:"id"`
@@ -25,6 +27,7 @@ type Message struct {
     SenderIdentityKey   sql.NullString  `json:"sender_identity_key"`
     SenderEphemeralKey  sql.NullString  `json:"sender_ephemeral_key"`
     Message             string          `json:"message"`
+    Pending             bool            `json:"pending"`
 }
 
 func (cfg *apiConfig) handleCreateMessage(w http.ResponseWriter, r *http.Request) {
@@ -42,7 +45,7 @@ func (cfg *apiConfig) handleCreateMessage(w http.ResponseWriter, r *http.Request
 
     // unmarshal POST JSON
     decoder := json.NewDecoder(r.Body)
-    m := &Message{}
+    m := &InitMessage{}
     err = decoder.Decode(m)
     if err != nil {
         respondWithError(w, http.StatusInternalServerError, "error decoding request", err)
@@ -53,10 +56,59 @@ func (cfg *apiConfig) handleCreateMessage(w http.ResponseWriter, r *http.Request
         return
     }
 
+    // refuse messages from blocked senders
+    blocked, err := cfg.dbQueries.IsBlocked(r.Context(), database.IsBlockedParams{UserID: m.UserID, BlockedID: id})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking blocks database", err)
+        return
+    } else if blocked {
+        respondWithError(w, http.StatusForbidden, "blocked by recipient", nil)
+        return
+    }
+
+    // hold first contact from unknown senders if the recipient wants message requests
+    recipient, err := cfg.dbQueries.GetUser(r.Context(), m.UserID)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "unable to find recipient", err)
+        return
+    }
+    pending := false
+    if recipient.MessageRequests {
+        accepted, err := cfg.dbQueries.IsAcceptedContact(
+            r.Context(), 
+            database.IsAcceptedContactParams{UserID: m.UserID, ContactID: id},
+        )
+        if err != nil {
+            respondWithError(w, http.StatusInternalServerError, "error checking accepted contacts", err)
+            return
+        }
+        pending = !accepted
+    }
+
+    // messaging someone accepts them as a contact
+    err = cfg.dbQueries.CreateAcceptedContact(
+        r.Context(), 
+        database.CreateAcceptedContactParams{UserID: id, ContactID: m.UserID},
+    )
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error adding accepted contact", err)
+        return
+    }
+
     params := database.CreateMessageParams{
         UserID: m.UserID,
         SenderID: id,
         Message: m.Message,
+        // only the first message of a session carries the key exchange
+        SenderIdentityKey: sql.NullString{
+            String: m.SenderIdentityKey, 
+            Valid: m.SenderIdentityKey != "" && m.SenderEphemeralKey != "",
+        },
+        SenderEphemeralKey: sql.NullString{
+            String: m.SenderEphemeralKey, 
+            Valid: m.SenderIdentityKey != "" && m.SenderEphemeralKey != "",
+        },
+        Pending: pending,
     }
     createdMessage, err := cfg.dbQueries.CreateMessage(r.Context(), params)
     if err != nil {
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/CraigYanitski/mescli/internal/auth"
+	"github.com/CraigYanitski/mescli/internal/database"
+	"github.com/google/uuid"
+)
+
+// presence not refreshed for this long is reported as offline
+const presenceExpiry = 2 * time.Minute
+
+// offline presence is kept this long for the last seen time, then forgotten
+const presenceRetention = 24 * time.Hour
+
+// presence statuses
+const (
+    presenceOnline   = "online"
+    presenceAway     = "away"
+    presenceOffline  = "offline"
+)
+
+type InitPresence struct {
+    Status  string  `json:"status"`
+}
+type Presence struct {
+    UserID    uuid.UUID  `json:"user_id"`
+    Status    string     `json:"status"`
+    LastSeen  time.Time  `json:"last_seen"`
+}
+
+// presenceStore holds the presence users publish, in memory only
+type presenceStore struct {
+    mu        sync.Mutex
+    statuses  map[uuid.UUID]Presence
+    now       func() time.Time
+    // when the store was last swept for forgotten presence
+    swept     time.Time
+}
+
+func newPresenceStore() *presenceStore {
+    return &presenceStore{
+        statuses: make(map[uuid.UUID]Presence),
+        now: time.Now,
+    }
+}
+
+func (s *presenceStore) publish(id uuid.UUID, status string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.evict()
+    s.statuses[id] = Presence{UserID: id, Status: status, LastSeen: s.now()}
+}
+
+// get returns the presence of a user, if they publish it
+func (s *presenceStore) get(id uuid.UUID) (Presence, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.evict()
+    p, ok := s.statuses[id]
+    if ok && s.now().Sub(p.LastSeen) >= presenceRetention {
+        delete(s.statuses, id)
+        return Presence{}, false
+    }
+    if ok && s.now().Sub(p.LastSeen) >= presenceExpiry {
+        p.Status = presenceOffline
+    }
+    return p, ok
+}
+
+// evict forgets presence past its retention, so the store does not grow for
+// the life of the server. The whole store is swept at most once per expiry.
+func (s *presenceStore) evict() {
+    now := s.now()
+    if now.Sub(s.swept) < presenceExpiry {
+        return
+    }
+    s.swept = now
+    for id, p := range s.statuses {
+        if now.Sub(p.LastSeen) >= presenceRetention {
+            delete(s.statuses, id)
+        }
+    }
+}
+
+func (cfg *apiConfig) handleUpdatePresence(w http.ResponseWriter, r *http.Request) {
+    // check authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "unauthorised", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+
+    // unmarshal PUT JSON
+    decoder := json.NewDecoder(r.Body)
+    p := &InitPresence{}
+    err = decoder.Decode(p)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error decoding request", err)
+        return
+    }
+    switch p.Status {
+    case presenceOnline, presenceAway, presenceOffline:
+    default:
+        respondWithError(w, http.StatusBadRequest, "unknown presence status", nil)
+        return
+    }
+
+    cfg.presence.publish(id, p.Status)
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handleGetPresence(w http.ResponseWriter, r *http.Request) {
+    // check authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "unauthorised", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+
+    // get user ID from request
+    userID, err := uuid.Parse(r.PathValue("userID"))
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "unable to parse user ID", err)
+        return
+    }
+
+    // presence is only shown to people the user has messaged or accepted,
+    // and is otherwise indistinguishable from not publishing it
+    p, ok := cfg.presence.get(userID)
+    if !ok {
+        respondWithError(w, http.StatusNotFound, "no presence for user", nil)
+        return
+    }
+    accepted, err := cfg.dbQueries.IsAcceptedContact(
+        r.Context(),
+        database.IsAcceptedContactParams{UserID: userID, ContactID: id},
+    )
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking accepted contacts", err)
+        return
+    }
+    blocked, err := cfg.dbQueries.IsBlocked(
+        r.Context(),
+        database.IsBlockedParams{UserID: userID, BlockedID: id},
+    )
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking blocks", err)
+        return
+    }
+    if !accepted || blocked {
+        respondWithError(w, http.StatusNotFound, "no presence for user", nil)
+        return
+    }
+
+    respondWithJSON(w, http.StatusOK, p)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPresenceStore(t *testing.T) {
+    now := time.Now()
+    store := newPresenceStore()
+    store.now = func() time.Time { return now }
+    alice := uuid.New()
+
+    if _, ok := store.get(alice); ok {
+        t.Fatal("presence found for a user who never published it")
+    }
+    store.publish(alice, presenceAway)
+    if p, ok := store.get(alice); !ok || p.Status != presenceAway || !p.LastSeen.Equal(now) {
+        t.Fatalf("unexpected presence %+v", p)
+    }
+
+    // presence that is not refreshed goes offline, keeping the last seen time
+    seen := now
+    now = now.Add(presenceExpiry)
+    if p, _ := store.get(alice); p.Status != presenceOffline || !p.LastSeen.Equal(seen) {
+        t.Fatalf("expected stale presence to be offline, got %+v", p)
+    }
+
+    // and is forgotten after the retention, even if it is never read again
+    bob := uuid.New()
+    store.publish(bob, presenceOnline)
+    now = seen.Add(presenceRetention)
+    store.publish(uuid.New(), presenceOnline)
+    if _, ok := store.statuses[alice]; ok {
+        t.Fatal("expected alice's presence to be evicted")
+    }
+    if _, ok := store.statuses[bob]; !ok {
+        t.Fatal("expected bob's presence to be kept")
+    }
+    now = now.Add(presenceExpiry)
+    if _, ok := store.get(bob); ok {
+        t.Fatal("expected bob's presence to be forgotten")
+    }
+}
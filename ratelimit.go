@@ -0,0 +1,55 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// lookup limits per account, shared by discovery and lookup by email
+const (
+    lookupLimit   = 1000
+    lookupWindow  = 24 * time.Hour
+)
+
+type lookupUsage struct {
+    start  time.Time
+    used   int
+}
+
+// rateLimiter counts lookups per account in fixed windows, in memory
+type rateLimiter struct {
+    mu      sync.Mutex
+    limit   int
+    window  time.Duration
+    usage   map[uuid.UUID]*lookupUsage
+    now     func() time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+    return &rateLimiter{
+        limit: limit,
+        window: window,
+        usage: make(map[uuid.UUID]*lookupUsage),
+        now: time.Now,
+    }
+}
+
+// allow records n lookups for an account if they fit in its current window,
+// otherwise it returns the time until the window resets
+func (l *rateLimiter) allow(id uuid.UUID, n int) (bool, time.Duration) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    now := l.now()
+    u, ok := l.usage[id]
+    if !ok || now.Sub(u.start) >= l.window {
+        u = &lookupUsage{start: now}
+        l.usage[id] = u
+    }
+    if u.used + n > l.limit {
+        return false, u.start.Add(l.window).Sub(now)
+    }
+    u.used += n
+    return true, 0
+}
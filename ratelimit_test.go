@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRateLimiter(t *testing.T) {
+    now := time.Now()
+    limiter := newRateLimiter(10, time.Hour)
+    limiter.now = func() time.Time { return now }
+    alice, bob := uuid.New(), uuid.New()
+
+    if ok, _ := limiter.allow(alice, 8); !ok {
+        t.Fatal("lookups under the limit refused")
+    }
+    if ok, wait := limiter.allow(alice, 3); ok || wait != time.Hour {
+        t.Fatalf("lookups over the limit allowed (wait %s)", wait)
+    }
+    if ok, _ := limiter.allow(bob, 10); !ok {
+        t.Fatal("limit shared between accounts")
+    }
+    if ok, _ := limiter.allow(alice, 2); !ok {
+        t.Fatal("refused lookups counted against the limit")
+    }
+
+    now = now.Add(time.Hour)
+    if ok, _ := limiter.allow(alice, 10); !ok {
+        t.Fatal("limit not reset after the window")
+    }
+}
@@ -27,6 +27,7 @@ func (cfg *apiConfig) handleRefresh(w http.ResponseWriter, r *http.Request) {
     newToken, err := auth.MakeJWT(refreshToken.ID, cfg.secret, time.Hour)
     if err != nil {
         respondWithError(w, http.StatusUnauthorized, "unauthorised", err)
+        return
     }
 
     respondWithJSON(w, http.StatusOK, Token{newToken})
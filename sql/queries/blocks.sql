@@ -0,0 +1,27 @@
+-- name: CreateBlock :one
+INSERT INTO blocks (
+    user_id,
+    blocked_id,
+    created_at
+) VALUES(
+    $1,
+    $2,
+    NOW()
+) ON CONFLICT (user_id, blocked_id) DO UPDATE 
+SET created_at = blocks.created_at
+RETURNING * ;
+
+-- name: DeleteBlock :exec
+DELETE FROM blocks 
+WHERE user_id = $1 AND blocked_id = $2 ;
+
+-- name: GetBlocks :many
+SELECT * FROM blocks 
+WHERE user_id = $1 
+ORDER BY created_at ;
+
+-- name: IsBlocked :one
+SELECT EXISTS(
+    SELECT 1 FROM blocks 
+    WHERE user_id = $1 AND blocked_id = $2
+) ;
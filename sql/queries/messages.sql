@@ -7,7 +7,8 @@ INSERT INTO messages (
     sender_id,
     sender_identity_key,
     sender_ephemeral_key,
-    message
+    message,
+    pending
 ) VALUES(
     gen_random_uuid(),
     NOW(),
@@ -16,15 +17,24 @@ INSERT INTO messages (
     $2,
     $4,
     $5,
-    $3
+    $3,
+    $6
 ) RETURNING * ;
 
 -- name: GetMessages :many
 SELECT * FROM messages 
-WHERE user_id = $1 
+WHERE user_id = $1 AND NOT pending 
 ORDER BY created_at ;
 
 -- name: DeleteMessage :one
 DELETE FROM messages 
 WHERE id = $1 
 RETURNING * ;
+
+-- name: DeletePendingMessages :exec
+DELETE FROM messages 
+WHERE user_id = $1 AND sender_id = $2 AND pending ;
+
+-- name: DeleteMessagesFromSender :exec
+DELETE FROM messages 
+WHERE user_id = $1 AND sender_id = $2 ;
@@ -0,0 +1,34 @@
+-- name: CreateAcceptedContact :exec
+INSERT INTO accepted_contacts (
+    user_id,
+    contact_id,
+    created_at
+) VALUES(
+    $1,
+    $2,
+    NOW()
+) ON CONFLICT (user_id, contact_id) DO NOTHING ;
+
+-- name: IsAcceptedContact :one
+SELECT EXISTS(
+    SELECT 1 FROM accepted_contacts 
+    WHERE user_id = $1 AND contact_id = $2
+) ;
+
+-- name: GetMessageRequests :many
+SELECT 
+    messages.sender_id, 
+    users.email, 
+    users.name, 
+    COUNT(*) AS messages, 
+    MIN(messages.created_at)::timestamp AS first_sent_at
+FROM messages 
+JOIN users ON users.id = messages.sender_id 
+WHERE messages.user_id = $1 AND messages.pending 
+GROUP BY messages.sender_id, users.email, users.name 
+ORDER BY first_sent_at ;
+
+-- name: AcceptMessages :exec
+UPDATE messages 
+SET pending = FALSE, updated_at = NOW() 
+WHERE user_id = $1 AND sender_id = $2 ;
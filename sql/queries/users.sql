@@ -6,7 +6,8 @@ INSERT INTO users (
     email, 
     name,
     hashed_password, 
-    initialised
+    initialised,
+    email_hash
 ) VALUES (
     gen_random_uuid(), 
     NOW(), 
@@ -14,7 +15,8 @@ INSERT INTO users (
     $1,
     $2,
     $3,
-    false
+    false,
+    $4
 ) RETURNING * ;
 
 -- name: GetUser :one
@@ -31,7 +33,32 @@ SET updated_at = NOW(),
     email = $2,
     name = $3,
     hashed_password = $4,
-    initialised = $5
+    initialised = $5,
+    email_hash = $6
 WHERE id = $1
 RETURNING * ;
 
+
+-- name: DeleteUser :exec
+DELETE FROM users 
+WHERE id = $1 ;
+
+-- name: UpdateMessageRequests :one
+UPDATE users 
+SET updated_at = NOW(),
+    message_requests = $2
+WHERE id = $1
+RETURNING * ;
+
+-- name: DiscoverUsers :many
+SELECT 
+    LEFT(users.email_hash, 16)::text AS hash_prefix, 
+    users.id, 
+    crypto_keys.identity_key
+FROM users 
+JOIN crypto_keys ON crypto_keys.user_id = users.id 
+WHERE LEFT(users.email_hash, 16) = ANY(@prefixes::text[]) 
+AND NOT EXISTS (
+    SELECT 1 FROM blocks 
+    WHERE blocks.user_id = users.id AND blocks.blocked_id = @requester_id
+) ;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE messages
+DROP CONSTRAINT messages_sender_id_fkey,
+ADD CONSTRAINT messages_sender_id_fkey 
+    FOREIGN KEY (sender_id) REFERENCES users ON DELETE CASCADE ;
+
+-- +goose Down
+ALTER TABLE messages
+DROP CONSTRAINT messages_sender_id_fkey,
+ADD CONSTRAINT messages_sender_id_fkey 
+    FOREIGN KEY (sender_id) REFERENCES users ;
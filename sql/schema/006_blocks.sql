@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE blocks (
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    blocked_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, blocked_id)
+) ;
+
+-- +goose Down
+DROP TABLE blocks ;
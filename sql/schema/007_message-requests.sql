@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN message_requests BOOLEAN NOT NULL DEFAULT FALSE ;
+
+ALTER TABLE messages
+ADD COLUMN pending BOOLEAN NOT NULL DEFAULT FALSE ;
+
+CREATE TABLE accepted_contacts (
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    contact_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, contact_id)
+) ;
+
+-- +goose Down
+DROP TABLE accepted_contacts ;
+
+ALTER TABLE messages
+DROP COLUMN pending ;
+
+ALTER TABLE users
+DROP COLUMN message_requests ;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN email_hash TEXT NOT NULL DEFAULT '' ;
+
+-- must match cryptography.HashIdentifier
+UPDATE users
+SET email_hash = encode(sha256(convert_to(lower(btrim(email)), 'UTF8')), 'hex') ;
+
+CREATE INDEX users_email_hash_prefix_idx ON users (LEFT(email_hash, 16)) ;
+
+-- +goose Down
+DROP INDEX users_email_hash_prefix_idx ;
+
+ALTER TABLE users
+DROP COLUMN email_hash ;
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -30,6 +31,8 @@ type User struct {
     Name            string     `json:"name"`
     HashedPassword  string     `json:"hashed_password,omitempty"`
     Initialised     bool       `json:"initialised"`
+    MessageRequests bool       `json:"-"`
+    EmailHash       string     `json:"-"`
 }
 type ValidUser struct {
     User
@@ -119,6 +122,7 @@ func (cfg *apiConfig) handleCreateUser(w http.ResponseWriter, r *http.Request) {
         Email: u.Email,
         Name: u.Name,
         HashedPassword: hash,
+        EmailHash: crypt.HashIdentifier(u.Email),
         //IdentityKey: u.IdentityKey,//hex.EncodeToString(idkBytes),
         //SignedPrekey: u.SignedPrekey,//hex.EncodeToString(spkBytes),
         //SignedKey: u.SignedKey,//hex.EncodeToString(skBytes),
@@ -171,26 +175,66 @@ func (cfg *apiConfig) handleGetUser(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    // the email and name are only shown to the user and to people they have
+    // messaged or accepted, like their presence
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+    user, err = cfg.hideDetails(r.Context(), id, user)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking accepted contacts", err)
+        return
+    }
+
     // return user
+    user.HashedPassword = ""
     respondWithJSON(w, http.StatusOK, User(user))
 }
 
+// hideDetails blanks the email and name of a user unless the requester is the
+// user or someone they have messaged or accepted
+func (cfg *apiConfig) hideDetails(ctx context.Context, requester uuid.UUID, user database.User) (database.User, error) {
+    if requester == user.ID {
+        return user, nil
+    }
+    accepted, err := cfg.dbQueries.IsAcceptedContact(
+        ctx,
+        database.IsAcceptedContactParams{UserID: user.ID, ContactID: requester},
+    )
+    if err != nil {
+        return user, err
+    }
+    if !accepted {
+        user.Email = ""
+        user.Name = ""
+    }
+    return user, nil
+}
+
 func (cfg *apiConfig) handleGetUserByEmail(w http.ResponseWriter, r *http.Request) {
-    // check user authentication (will wrap in middleware)
-    //token, err := auth.GetBearerToken(r.Header)
-    //if err != nil {
-    //    respondWithError(w, http.StatusUnauthorized, "", err)
-    //    return
-    //}
-    //_, err = auth.ValidateJWT(token, cfg.secret)
-    //if err != nil {
-    //    respondWithError(w, http.StatusUnauthorized, token, err)
-    //}
+    // check user authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
 
     // ensure request contains email
     u := &User{}
     decoder := json.NewDecoder(r.Body)
-    err := decoder.Decode(u)
+    err = decoder.Decode(u)
     if err != nil {
         respondWithError(w, http.StatusInternalServerError, "unable to unmarshal user", err)
         return
@@ -200,14 +244,26 @@ func (cfg *apiConfig) handleGetUserByEmail(w http.ResponseWriter, r *http.Reques
         return
     }
 
+    // lookups by email count against the same limit as discovery
+    if !cfg.allowLookups(w, id, 1) {
+        return
+    }
+
     // get user
     user, err := cfg.dbQueries.GetUserByEmail(r.Context(), u.Email)
     if err != nil {
-        respondWithError(w, http.StatusInternalServerError, "unable to get user by email", err)
+        respondWithError(w, http.StatusNotFound, "unable to get user by email", err)
+        return
+    }
+    // strangers are only given the UUID, like in handleGetUser
+    user, err = cfg.hideDetails(r.Context(), id, user)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking accepted contacts", err)
         return
     }
 
     // return user JSON
+    user.HashedPassword = ""
     respondWithJSON(w, http.StatusOK, User(user))
 }
 
@@ -247,6 +303,26 @@ func (cfg *apiConfig) handleGetUserKeyPacket(w http.ResponseWriter, r *http.Requ
     //    }
     //}
 
+    // users may not start a session with someone who blocked them
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+    blocked, err := cfg.dbQueries.IsBlocked(r.Context(), database.IsBlockedParams{UserID: userID, BlockedID: id})
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error checking blocks database", err)
+        return
+    } else if blocked {
+        respondWithError(w, http.StatusForbidden, "blocked by user", nil)
+        return
+    }
+
     // make request for key packet
     userKeyPacket, err := cfg.dbQueries.GetUserKeyPacket(r.Context(), userID)
     if err != nil {
@@ -404,6 +480,7 @@ func (cfg *apiConfig) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
         Email: u.Email,
         Name: u.Name,
         HashedPassword: hash,
+        EmailHash: crypt.HashIdentifier(u.Email),
         // IdentityKey: u.IdentityKey,//hex.EncodeToString(idkBytes),
         // SignedPrekey: u.SignedPrekey,//hex.EncodeToString(spkBytes),
         // SignedKey: u.SignedKey,//hex.EncodeToString(skBytes),
@@ -431,3 +508,49 @@ func (cfg *apiConfig) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
     respondWithJSON(w, http.StatusCreated, User(updatedUser))
 }
 
+
+func (cfg *apiConfig) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+    // check user authentication
+    token, err := auth.GetBearerToken(r.Header)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, "", err)
+        return
+    }
+    id, err := auth.ValidateJWT(token, cfg.secret)
+    if err != nil {
+        respondWithError(w, http.StatusUnauthorized, token, err)
+        return
+    }
+
+    // unmarshal the DELETE JSON and verify the password is set
+    decoder := json.NewDecoder(r.Body)
+    u := &InitUser{}
+    err = decoder.Decode(u)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "unable to unmarshal request", err)
+        return
+    } else if u.Password == "" {
+        respondWithError(w, http.StatusBadRequest, "error: password required to delete user", nil)
+        return
+    }
+
+    // re-confirm the password before deleting anything
+    foundUser, err := cfg.dbQueries.GetUser(r.Context(), id)
+    if err != nil {
+        respondWithError(w, http.StatusNotFound, "unable to get user", err)
+        return
+    }
+    if !crypt.CheckPasswordHash(u.Password, foundUser.HashedPassword) {
+        respondWithError(w, http.StatusForbidden, "password incorrect", nil)
+        return
+    }
+
+    // keys, refresh tokens and queued messages cascade with the user
+    err = cfg.dbQueries.DeleteUser(r.Context(), id)
+    if err != nil {
+        respondWithError(w, http.StatusInternalServerError, "error deleting user from database", err)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}